@@ -3,3 +3,7 @@ module tfs-cli
 go 1.21
 
 require github.com/yuin/goldmark v1.7.4
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require golang.org/x/net v0.24.0
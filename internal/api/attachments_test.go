@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tfs-cli/internal/errs"
+)
+
+func TestUploadAttachmentStreamsBodyOnce(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Method != http.MethodPost {
+			t.Fatalf("unexpected method: %s", r.Method)
+		}
+		if r.URL.Path != "/RND/_apis/wit/attachments" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("fileName"); got != "build.log" {
+			t.Fatalf("unexpected file name: %q", got)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/octet-stream" {
+			t.Fatalf("unexpected content type: %q", got)
+		}
+		if r.ContentLength != 11 {
+			t.Fatalf("unexpected content length: %d", r.ContentLength)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "hello world" {
+			t.Fatalf("unexpected body: %q", body)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	_, err = client.UploadAttachment(context.Background(), "build.log", strings.NewReader("hello world"), 11)
+	if err == nil {
+		t.Fatalf("expected error for failed upload")
+	}
+	if requests != 1 {
+		t.Fatalf("streamed upload was sent %d times, want 1", requests)
+	}
+}
+
+func TestUploadAttachmentReturnsReference(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"6f1d","url":"https://tfs.example/_apis/wit/attachments/6f1d"}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	ref, err := client.UploadAttachment(context.Background(), "shot.png", strings.NewReader("png"), 3)
+	if err != nil {
+		t.Fatalf("UploadAttachment returned error: %v", err)
+	}
+	if ref.ID != "6f1d" || ref.URL != "https://tfs.example/_apis/wit/attachments/6f1d" {
+		t.Fatalf("unexpected reference: %#v", ref)
+	}
+}
+
+func TestDownloadAttachmentUsesConfiguredBaseURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/DefaultCollection/_apis/wit/attachments/6f1d" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("download"); got != "true" {
+			t.Fatalf("download: got %q, want true", got)
+		}
+		fmt.Fprint(w, "file content")
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL+"/DefaultCollection", "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	var buf bytes.Buffer
+	written, err := client.DownloadAttachment(context.Background(), "6f1d", "build.log", &buf)
+	if err != nil {
+		t.Fatalf("DownloadAttachment returned error: %v", err)
+	}
+	if written != int64(len("file content")) || buf.String() != "file content" {
+		t.Fatalf("unexpected download: %d %q", written, buf.String())
+	}
+}
+
+func TestDownloadAttachmentRetriesServerErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/missing"):
+			w.WriteHeader(http.StatusNotFound)
+		case requests == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, "file content")
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := client.DownloadAttachment(context.Background(), "6f1d", "build.log", &buf); err != nil {
+		t.Fatalf("DownloadAttachment returned error: %v", err)
+	}
+	if requests != 2 || buf.String() != "file content" {
+		t.Fatalf("unexpected download after %d requests: %q", requests, buf.String())
+	}
+	_, err = client.DownloadAttachment(context.Background(), "missing", "", &buf)
+	if appErr, ok := err.(errs.AppError); !ok || appErr.Code != "http_error" || !IsStatus(err, http.StatusNotFound) {
+		t.Fatalf("expected a 404 http_error, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tfs-cli/internal/errs"
+)
+
+func TestBatchWorkItemsMapsSubResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/_apis/wit/$batch" {
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		var requests []workItemBatchSubRequest
+		if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if len(requests) != 3 {
+			t.Fatalf("got %d sub-requests, want 3", len(requests))
+		}
+		if requests[0].Method != http.MethodPatch || requests[0].URI != "/RND/_apis/wit/workitems/7?api-version="+defaultAPIVersion {
+			t.Fatalf("unexpected update sub-request: %#v", requests[0])
+		}
+		if requests[2].URI != "/RND/_apis/wit/workitems/$Product%20Backlog%20Item?api-version="+defaultAPIVersion {
+			t.Fatalf("unexpected create sub-request: %#v", requests[2])
+		}
+		if requests[0].Headers["Content-Type"] != "application/json-patch+json" {
+			t.Fatalf("unexpected headers: %#v", requests[0].Headers)
+		}
+		fmt.Fprint(w, `{"count":3,"value":[`+
+			`{"code":200,"body":"{\"id\":7,\"rev\":4,\"fields\":{}}"},`+
+			`{"code":400,"body":"{\"message\":\"TF401320: Rule Error\"}"},`+
+			`{"code":200,"body":{"id":12,"rev":1,"fields":{}}}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	patch := []map[string]interface{}{{"op": "add", "path": "/fields/System.State", "value": "Active"}}
+	results, err := client.BatchWorkItems(context.Background(), []WorkItemBatchOperation{
+		{ID: 7, Patch: patch},
+		{ID: 8, Patch: patch},
+		{Type: "Product Backlog Item", Patch: patch},
+	})
+	if err != nil {
+		t.Fatalf("BatchWorkItems returned error: %v", err)
+	}
+	if results[0].Err != nil || results[0].WorkItem.Rev != 4 {
+		t.Fatalf("unexpected first result: %#v", results[0])
+	}
+	appErr, ok := results[1].Err.(errs.AppError)
+	if !ok || results[1].ID != 8 || results[1].Status != 400 {
+		t.Fatalf("unexpected failed result: %#v", results[1])
+	}
+	if details, _ := appErr.Details.(string); !strings.Contains(details, "TF401320") {
+		t.Fatalf("server message not preserved: %#v", appErr)
+	}
+	if results[2].Err != nil || results[2].ID != 12 {
+		t.Fatalf("unexpected create result: %#v", results[2])
+	}
+}
+
+func TestBatchWorkItemsSplitsLargeRequests(t *testing.T) {
+	sizes := []int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requests []workItemBatchSubRequest
+		if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		sizes = append(sizes, len(requests))
+		if len(sizes) == 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		values := make([]string, len(requests))
+		for i := range values {
+			values[i] = fmt.Sprintf(`{"code":200,"body":"{\"id\":%d}"}`, i+1)
+		}
+		fmt.Fprintf(w, `{"count":%d,"value":[%s]}`, len(values), strings.Join(values, ","))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	ops := make([]WorkItemBatchOperation, MaxWorkItemBatchSize+5)
+	for i := range ops {
+		ops[i] = WorkItemBatchOperation{ID: i + 1}
+	}
+	results, err := client.BatchWorkItems(context.Background(), ops)
+	if err != nil {
+		t.Fatalf("BatchWorkItems returned error: %v", err)
+	}
+	if len(sizes) != 2 || sizes[0] != MaxWorkItemBatchSize || sizes[1] != 5 {
+		t.Fatalf("unexpected request sizes: %v", sizes)
+	}
+	if results[0].Err != nil {
+		t.Fatalf("unexpected error in first chunk: %v", results[0].Err)
+	}
+	last := results[len(results)-1]
+	if last.Err == nil || last.ID != MaxWorkItemBatchSize+5 {
+		t.Fatalf("failed chunk not reported per item: %#v", last)
+	}
+}
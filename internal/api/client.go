@@ -10,6 +10,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -26,6 +27,9 @@ const (
 	wikiAPIVersion             = "6.0-preview.1"
 	workItemCommentsAPIVersion = "5.0-preview.2"
 	workItemCommentsPageSize   = 200
+	workItemUpdatesPageSize    = 200
+	// MaxWorkItemBatchSize is the number of operations $batch accepts per request.
+	MaxWorkItemBatchSize = 200
 )
 
 type Client struct {
@@ -69,13 +73,21 @@ func (c *Client) WithProject(project string) *Client {
 	return &clone
 }
 
-func (c *Client) Wiql(ctx context.Context, query string, top int) (WiqlResponse, error) {
+// Wiql runs a WIQL query. A non-zero asOf runs it against the work items as
+// they were at that time, unless the query has its own ASOF clause.
+func (c *Client) Wiql(ctx context.Context, query string, top int, asOf time.Time) (WiqlResponse, error) {
 	path := fmt.Sprintf("%s/_apis/wit/wiql", c.project)
 	params := url.Values{}
 	params.Set("api-version", defaultAPIVersion)
 	if top > 0 {
 		params.Set("$top", strconv.Itoa(top))
 	}
+	if !asOf.IsZero() {
+		if HasAsOfClause(query) {
+			return WiqlResponse{}, errs.New("invalid_args", "the query has its own ASOF clause", nil)
+		}
+		query = strings.TrimRight(strings.TrimSpace(query), ";") + " ASOF '" + formatAsOf(asOf) + "'"
+	}
 	body, err := json.Marshal(WiqlRequest{Query: query})
 	if err != nil {
 		return WiqlResponse{}, err
@@ -91,6 +103,326 @@ func (c *Client) Wiql(ctx context.Context, query string, top int) (WiqlResponse,
 	return resp, nil
 }
 
+// RunQuery executes a stored query by its ID.
+func (c *Client) RunQuery(ctx context.Context, id string, top int) (WiqlResponse, error) {
+	path := fmt.Sprintf("%s/_apis/wit/wiql/%s", c.project, url.PathEscape(id))
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	if top > 0 {
+		params.Set("$top", strconv.Itoa(top))
+	}
+	respBody, err := c.do(ctx, http.MethodGet, path, params, nil, "")
+	if err != nil {
+		return WiqlResponse{}, err
+	}
+	var resp WiqlResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return WiqlResponse{}, err
+	}
+	return resp, nil
+}
+
+// ListQueries returns the root query folders ("My Queries", "Shared Queries")
+// with their children down to depth levels (the API allows at most 2).
+func (c *Client) ListQueries(ctx context.Context, depth int) ([]QueryHierarchyItem, error) {
+	path := fmt.Sprintf("%s/_apis/wit/queries", c.project)
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	if depth > 0 {
+		params.Set("$depth", strconv.Itoa(depth))
+	}
+	respBody, err := c.do(ctx, http.MethodGet, path, params, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	var resp QueryHierarchyItemsResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
+// GetQuery returns a query or folder by ID or path, including its WIQL.
+func (c *Client) GetQuery(ctx context.Context, idOrPath string, depth int) (QueryHierarchyItem, error) {
+	path := fmt.Sprintf("%s/_apis/wit/queries/%s", c.project, escapeQueryPath(idOrPath))
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	params.Set("$expand", "wiql")
+	if depth > 0 {
+		params.Set("$depth", strconv.Itoa(depth))
+	}
+	respBody, err := c.do(ctx, http.MethodGet, path, params, nil, "")
+	if err != nil {
+		return QueryHierarchyItem{}, err
+	}
+	var item QueryHierarchyItem
+	if err := json.Unmarshal(respBody, &item); err != nil {
+		return QueryHierarchyItem{}, err
+	}
+	return item, nil
+}
+
+// CreateQuery creates a query or folder inside the folder at parentPath.
+func (c *Client) CreateQuery(ctx context.Context, parentPath string, item QueryHierarchyItem) (QueryHierarchyItem, error) {
+	path := fmt.Sprintf("%s/_apis/wit/queries/%s", c.project, escapeQueryPath(parentPath))
+	return c.sendQuery(ctx, http.MethodPost, path, item)
+}
+
+// UpdateQuery changes the query or folder identified by ID or path.
+func (c *Client) UpdateQuery(ctx context.Context, idOrPath string, item QueryHierarchyItem) (QueryHierarchyItem, error) {
+	path := fmt.Sprintf("%s/_apis/wit/queries/%s", c.project, escapeQueryPath(idOrPath))
+	return c.sendQuery(ctx, http.MethodPatch, path, item)
+}
+
+func (c *Client) sendQuery(ctx context.Context, method, path string, item QueryHierarchyItem) (QueryHierarchyItem, error) {
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	body, err := json.Marshal(item)
+	if err != nil {
+		return QueryHierarchyItem{}, err
+	}
+	respBody, err := c.do(ctx, method, path, params, body, "application/json")
+	if err != nil {
+		return QueryHierarchyItem{}, err
+	}
+	var saved QueryHierarchyItem
+	if err := json.Unmarshal(respBody, &saved); err != nil {
+		return QueryHierarchyItem{}, err
+	}
+	return saved, nil
+}
+
+// DeleteQuery deletes a query, or a folder with everything in it.
+func (c *Client) DeleteQuery(ctx context.Context, idOrPath string) error {
+	path := fmt.Sprintf("%s/_apis/wit/queries/%s", c.project, escapeQueryPath(idOrPath))
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	_, err := c.do(ctx, http.MethodDelete, path, params, nil, "")
+	return err
+}
+
+// GetTeamSettings returns the settings of team, or of the project's default
+// team when team is empty.
+func (c *Client) GetTeamSettings(ctx context.Context, team string) (TeamSettings, error) {
+	var settings TeamSettings
+	err := c.getTeamResource(ctx, team, "teamsettings", nil, &settings)
+	return settings, err
+}
+
+// ListTeamIterations returns the iterations selected by team. timeframe
+// "current" returns only the current sprint; "" returns all of them.
+func (c *Client) ListTeamIterations(ctx context.Context, team, timeframe string) ([]TeamSettingsIteration, error) {
+	params := url.Values{}
+	if timeframe != "" {
+		params.Set("$timeframe", timeframe)
+	}
+	var resp TeamSettingsIterationsResponse
+	if err := c.getTeamResource(ctx, team, "teamsettings/iterations", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
+// GetIterationWorkItems returns the sprint backlog of an iteration.
+func (c *Client) GetIterationWorkItems(ctx context.Context, team, iterationID string) (IterationWorkItems, error) {
+	var items IterationWorkItems
+	err := c.getTeamResource(ctx, team, "teamsettings/iterations/"+url.PathEscape(iterationID)+"/workitems", nil, &items)
+	return items, err
+}
+
+// GetIterationCapacities returns the capacity of every team member in an
+// iteration. Newer servers wrap the list in teamMembers; both shapes are
+// accepted.
+func (c *Client) GetIterationCapacities(ctx context.Context, team, iterationID string) ([]TeamMemberCapacity, error) {
+	var resp struct {
+		Value       []TeamMemberCapacity `json:"value"`
+		TeamMembers []TeamMemberCapacity `json:"teamMembers"`
+	}
+	if err := c.getTeamResource(ctx, team, "teamsettings/iterations/"+url.PathEscape(iterationID)+"/capacities", nil, &resp); err != nil {
+		return nil, err
+	}
+	if resp.TeamMembers != nil {
+		return resp.TeamMembers, nil
+	}
+	return resp.Value, nil
+}
+
+// GetIterationDaysOff returns the days the whole team is off in an iteration.
+func (c *Client) GetIterationDaysOff(ctx context.Context, team, iterationID string) (TeamDaysOff, error) {
+	var daysOff TeamDaysOff
+	err := c.getTeamResource(ctx, team, "teamsettings/iterations/"+url.PathEscape(iterationID)+"/teamdaysoff", nil, &daysOff)
+	return daysOff, err
+}
+
+// GetBacklogConfiguration returns the backlog levels of team and the fields
+// that play a role on them, such as the Order field backlogs are ranked by.
+func (c *Client) GetBacklogConfiguration(ctx context.Context, team string) (BacklogConfiguration, error) {
+	var config BacklogConfiguration
+	err := c.getTeamResource(ctx, team, "backlogconfiguration", nil, &config)
+	return config, err
+}
+
+// ListBacklogs returns the backlog levels of team: portfolio, requirement
+// and task backlogs.
+func (c *Client) ListBacklogs(ctx context.Context, team string) ([]BacklogLevel, error) {
+	var resp BacklogLevelsResponse
+	if err := c.getTeamResource(ctx, team, "backlogs", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
+// GetBacklogWorkItems returns the items on a backlog level. Each item is
+// the target of a link; its source is the parent on the same backlog, if any.
+func (c *Client) GetBacklogWorkItems(ctx context.Context, team, backlogID string) ([]WorkItemLink, error) {
+	if strings.TrimSpace(backlogID) == "" {
+		return nil, errs.New("invalid_args", "backlog id is required", nil)
+	}
+	var resp BacklogLevelWorkItems
+	if err := c.getTeamResource(ctx, team, "backlogs/"+url.PathEscape(backlogID)+"/workItems", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.WorkItems, nil
+}
+
+// ReorderWorkItems moves the items of op between PreviousID and NextID on
+// the team's backlog and returns their new order values.
+func (c *Client) ReorderWorkItems(ctx context.Context, team string, op ReorderOperation) ([]ReorderResult, error) {
+	if len(op.IDs) == 0 {
+		return nil, errs.New("invalid_args", "at least one work item id is required", nil)
+	}
+	payload, err := json.Marshal(op)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	respBody, err := c.do(ctx, http.MethodPatch, c.teamPath(team)+"/_apis/work/workitemsorder", params, payload, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	var resp ReorderResultsResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
+// ListBoards returns the Kanban boards of team, one per backlog level.
+func (c *Client) ListBoards(ctx context.Context, team string) ([]BoardReference, error) {
+	var resp BoardReferencesResponse
+	if err := c.getTeamResource(ctx, team, "boards", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
+// GetBoard returns a board by name or id with its columns, swimlanes and
+// the team-specific fields that hold an item's place on it.
+func (c *Client) GetBoard(ctx context.Context, team, board string) (Board, error) {
+	if strings.TrimSpace(board) == "" {
+		return Board{}, errs.New("invalid_args", "board is required", nil)
+	}
+	var resp Board
+	err := c.getTeamResource(ctx, team, "boards/"+url.PathEscape(strings.TrimSpace(board)), nil, &resp)
+	return resp, err
+}
+
+func (c *Client) getTeamResource(ctx context.Context, team, resource string, params url.Values, into interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("api-version", defaultAPIVersion)
+	respBody, err := c.do(ctx, http.MethodGet, c.teamPath(team)+"/_apis/work/"+resource, params, nil, "")
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(respBody, into)
+}
+
+// teamPath is the project path with the team segment the work APIs take;
+// without a team they use the project's default team.
+func (c *Client) teamPath(team string) string {
+	if strings.TrimSpace(team) == "" {
+		return c.project
+	}
+	return c.project + "/" + url.PathEscape(strings.TrimSpace(team))
+}
+
+// Structure groups of the classification nodes API.
+const (
+	AreaNodes      = "areas"
+	IterationNodes = "iterations"
+)
+
+// GetClassificationNode returns the area or iteration at path, relative to
+// the group's root ("" is the root), with children down to depth levels.
+func (c *Client) GetClassificationNode(ctx context.Context, group, path string, depth int) (ClassificationNode, error) {
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	if depth > 0 {
+		params.Set("$depth", strconv.Itoa(depth))
+	}
+	respBody, err := c.do(ctx, http.MethodGet, c.classificationNodePath(group, path), params, nil, "")
+	if err != nil {
+		return ClassificationNode{}, err
+	}
+	var node ClassificationNode
+	if err := json.Unmarshal(respBody, &node); err != nil {
+		return ClassificationNode{}, err
+	}
+	return node, nil
+}
+
+// CreateClassificationNode creates node under parentPath. A node with only
+// ID set moves that existing node under parentPath instead.
+func (c *Client) CreateClassificationNode(ctx context.Context, group, parentPath string, node ClassificationNode) (ClassificationNode, error) {
+	return c.sendClassificationNode(ctx, http.MethodPost, c.classificationNodePath(group, parentPath), node)
+}
+
+// UpdateClassificationNode renames the node at path or changes its dates.
+func (c *Client) UpdateClassificationNode(ctx context.Context, group, path string, node ClassificationNode) (ClassificationNode, error) {
+	return c.sendClassificationNode(ctx, http.MethodPatch, c.classificationNodePath(group, path), node)
+}
+
+func (c *Client) sendClassificationNode(ctx context.Context, method, path string, node ClassificationNode) (ClassificationNode, error) {
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	body, err := json.Marshal(node)
+	if err != nil {
+		return ClassificationNode{}, err
+	}
+	respBody, err := c.do(ctx, method, path, params, body, "application/json")
+	if err != nil {
+		return ClassificationNode{}, err
+	}
+	var saved ClassificationNode
+	if err := json.Unmarshal(respBody, &saved); err != nil {
+		return ClassificationNode{}, err
+	}
+	return saved, nil
+}
+
+// DeleteClassificationNode deletes the node at path and its children. Work
+// items using them are moved to the node with ID reclassifyID.
+func (c *Client) DeleteClassificationNode(ctx context.Context, group, path string, reclassifyID int) error {
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	if reclassifyID > 0 {
+		params.Set("$reclassifyId", strconv.Itoa(reclassifyID))
+	}
+	_, err := c.do(ctx, http.MethodDelete, c.classificationNodePath(group, path), params, nil, "")
+	return err
+}
+
+func (c *Client) classificationNodePath(group, path string) string {
+	nodePath := fmt.Sprintf("%s/_apis/wit/classificationnodes/%s", c.project, group)
+	if strings.Trim(path, "/") != "" {
+		nodePath += "/" + escapeQueryPath(path)
+	}
+	return nodePath
+}
+
 func (c *Client) GetWorkItem(ctx context.Context, id int, fields []string, expand string) (WorkItem, error) {
 	path := fmt.Sprintf("%s/_apis/wit/workitems/%d", c.project, id)
 	params := url.Values{}
@@ -176,6 +508,109 @@ func (c *Client) GetWorkItemComments(ctx context.Context, id, maxComments int) (
 	}
 }
 
+func (c *Client) GetWorkItemUpdates(ctx context.Context, id, maxUpdates int) ([]WorkItemUpdate, error) {
+	if id <= 0 {
+		return nil, errs.New("invalid_args", "work item id must be positive", id)
+	}
+	if maxUpdates < 0 {
+		return nil, errs.New("invalid_args", "maximum updates must not be negative", maxUpdates)
+	}
+
+	updates := []WorkItemUpdate{}
+	for {
+		pageSize := workItemUpdatesPageSize
+		if maxUpdates > 0 && maxUpdates-len(updates) < pageSize {
+			pageSize = maxUpdates - len(updates)
+		}
+		if pageSize <= 0 {
+			return updates, nil
+		}
+
+		path := fmt.Sprintf("%s/_apis/wit/workItems/%d/updates", c.project, id)
+		params := url.Values{}
+		params.Set("api-version", defaultAPIVersion)
+		params.Set("$top", strconv.Itoa(pageSize))
+		params.Set("$skip", strconv.Itoa(len(updates)))
+
+		respBody, err := c.do(ctx, http.MethodGet, path, params, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		var resp WorkItemUpdatesResponse
+		if err := json.Unmarshal(respBody, &resp); err != nil {
+			return nil, err
+		}
+		if len(resp.Value) == 0 {
+			return updates, nil
+		}
+		updates = append(updates, resp.Value...)
+		if len(resp.Value) < pageSize {
+			return updates, nil
+		}
+	}
+}
+
+// GetWorkItemRevision returns work item id as it was at revision rev.
+func (c *Client) GetWorkItemRevision(ctx context.Context, id, rev int) (WorkItem, error) {
+	if id <= 0 || rev <= 0 {
+		return WorkItem{}, errs.New("invalid_args", "work item id and revision must be positive", map[string]int{"id": id, "rev": rev})
+	}
+	path := fmt.Sprintf("%s/_apis/wit/workItems/%d/revisions/%d", c.project, id, rev)
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	respBody, err := c.do(ctx, http.MethodGet, path, params, nil, "")
+	if err != nil {
+		return WorkItem{}, err
+	}
+	var wi WorkItem
+	if err := json.Unmarshal(respBody, &wi); err != nil {
+		return WorkItem{}, err
+	}
+	return wi, nil
+}
+
+func (c *Client) GetWorkItemRevisions(ctx context.Context, id, maxRevisions int) ([]WorkItem, error) {
+	if id <= 0 {
+		return nil, errs.New("invalid_args", "work item id must be positive", id)
+	}
+	if maxRevisions < 0 {
+		return nil, errs.New("invalid_args", "maximum revisions must not be negative", maxRevisions)
+	}
+
+	revisions := []WorkItem{}
+	for {
+		pageSize := workItemUpdatesPageSize
+		if maxRevisions > 0 && maxRevisions-len(revisions) < pageSize {
+			pageSize = maxRevisions - len(revisions)
+		}
+		if pageSize <= 0 {
+			return revisions, nil
+		}
+
+		path := fmt.Sprintf("%s/_apis/wit/workItems/%d/revisions", c.project, id)
+		params := url.Values{}
+		params.Set("api-version", defaultAPIVersion)
+		params.Set("$top", strconv.Itoa(pageSize))
+		params.Set("$skip", strconv.Itoa(len(revisions)))
+
+		respBody, err := c.do(ctx, http.MethodGet, path, params, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		var resp WorkItemRevisionsResponse
+		if err := json.Unmarshal(respBody, &resp); err != nil {
+			return nil, err
+		}
+		if len(resp.Value) == 0 {
+			return revisions, nil
+		}
+		revisions = append(revisions, resp.Value...)
+		if len(resp.Value) < pageSize {
+			return revisions, nil
+		}
+	}
+}
+
 func (c *Client) GetWikiPageByID(ctx context.Context, wikiIdentifier string, pageID int) (WikiPage, error) {
 	if strings.TrimSpace(wikiIdentifier) == "" {
 		return WikiPage{}, errs.New("invalid_args", "wiki identifier is required", nil)
@@ -220,11 +655,26 @@ func (c *Client) getWikiPage(ctx context.Context, path string, params url.Values
 	return page, nil
 }
 
-func (c *Client) GetWorkItemsBatch(ctx context.Context, ids []int, fields []string) ([]WorkItem, error) {
+// GetWorkItemsBatch returns the given fields of up to MaxWorkItemBatchSize
+// work items, as they are now or, with a non-zero asOf, as they were then.
+func (c *Client) GetWorkItemsBatch(ctx context.Context, ids []int, fields []string, asOf time.Time) ([]WorkItem, error) {
+	request := WorkItemsBatchRequest{IDs: ids, Fields: fields}
+	if !asOf.IsZero() {
+		request.AsOf = formatAsOf(asOf)
+	}
+	return c.getWorkItemsBatch(ctx, request)
+}
+
+// GetWorkItemsBatchExpanded fetches all fields plus the given expansion
+// (relations, links, all); the API does not allow combining it with fields.
+func (c *Client) GetWorkItemsBatchExpanded(ctx context.Context, ids []int, expand string) ([]WorkItem, error) {
+	return c.getWorkItemsBatch(ctx, WorkItemsBatchRequest{IDs: ids, Expand: expand})
+}
+
+func (c *Client) getWorkItemsBatch(ctx context.Context, payload WorkItemsBatchRequest) ([]WorkItem, error) {
 	path := fmt.Sprintf("%s/_apis/wit/workitemsbatch", c.project)
 	params := url.Values{}
 	params.Set("api-version", defaultAPIVersion)
-	payload := WorkItemsBatchRequest{IDs: ids, Fields: fields}
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
@@ -282,6 +732,108 @@ func (c *Client) CreateWorkItem(ctx context.Context, wiType string, patch []map[
 	return wi, nil
 }
 
+type workItemBatchSubRequest struct {
+	Method  string                   `json:"method"`
+	URI     string                   `json:"uri"`
+	Headers map[string]string        `json:"headers"`
+	Body    []map[string]interface{} `json:"body"`
+}
+
+type workItemBatchSubResponse struct {
+	Code int             `json:"code"`
+	Body json.RawMessage `json:"body"`
+}
+
+type workItemBatchResponse struct {
+	Count int                        `json:"count"`
+	Value []workItemBatchSubResponse `json:"value"`
+}
+
+// BatchWorkItems sends creates and updates through the $batch endpoint,
+// MaxWorkItemBatchSize operations per request. The returned slice has one
+// result per operation, in order; a request that fails as a whole marks every
+// operation it carried as failed, so the error return is only used for
+// operations that cannot be encoded.
+func (c *Client) BatchWorkItems(ctx context.Context, ops []WorkItemBatchOperation) ([]WorkItemBatchResult, error) {
+	requests := make([]workItemBatchSubRequest, 0, len(ops))
+	for index, op := range ops {
+		var uri string
+		switch {
+		case op.ID > 0:
+			uri = fmt.Sprintf("/%s/_apis/wit/workitems/%d?api-version=%s", url.PathEscape(c.project), op.ID, defaultAPIVersion)
+		case op.Type != "":
+			uri = fmt.Sprintf("/%s/_apis/wit/workitems/$%s?api-version=%s", url.PathEscape(c.project), url.PathEscape(op.Type), defaultAPIVersion)
+		default:
+			return nil, errs.New("invalid_args", fmt.Sprintf("batch operation %d has neither an ID nor a type", index), nil)
+		}
+		requests = append(requests, workItemBatchSubRequest{
+			Method:  http.MethodPatch,
+			URI:     uri,
+			Headers: map[string]string{"Content-Type": "application/json-patch+json"},
+			Body:    op.Patch,
+		})
+	}
+
+	results := make([]WorkItemBatchResult, len(ops))
+	for start := 0; start < len(requests); start += MaxWorkItemBatchSize {
+		end := start + MaxWorkItemBatchSize
+		if end > len(requests) {
+			end = len(requests)
+		}
+		responses, err := c.postWorkItemBatch(ctx, requests[start:end])
+		for i := start; i < end; i++ {
+			results[i].ID = ops[i].ID
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			results[i] = workItemBatchResult(ops[i].ID, responses[i-start])
+		}
+	}
+	return results, nil
+}
+
+func (c *Client) postWorkItemBatch(ctx context.Context, requests []workItemBatchSubRequest) ([]workItemBatchSubResponse, error) {
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	body, err := json.Marshal(requests)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := c.do(ctx, http.MethodPost, "_apis/wit/$batch", params, body, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	var resp workItemBatchResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, errs.New("http_error", "could not parse batch response", err.Error())
+	}
+	if len(resp.Value) != len(requests) {
+		return nil, errs.New("http_error", fmt.Sprintf("batch response has %d results for %d operations", len(resp.Value), len(requests)), nil)
+	}
+	return resp.Value, nil
+}
+
+func workItemBatchResult(id int, resp workItemBatchSubResponse) WorkItemBatchResult {
+	result := WorkItemBatchResult{ID: id, Status: resp.Code}
+	// Sub-response bodies are usually JSON documents encoded as strings.
+	body := []byte(resp.Body)
+	var encoded string
+	if json.Unmarshal(resp.Body, &encoded) == nil {
+		body = []byte(encoded)
+	}
+	if resp.Code < 200 || resp.Code > 299 {
+		result.Err = statusError(resp.Code, body)
+		return result
+	}
+	if err := json.Unmarshal(body, &result.WorkItem); err != nil {
+		result.Err = errs.New("http_error", "could not parse batch item response", err.Error())
+		return result
+	}
+	result.ID = result.WorkItem.ID
+	return result
+}
+
 func (c *Client) DeleteWorkItem(ctx context.Context, id int, destroy bool) (map[string]interface{}, error) {
 	path := fmt.Sprintf("%s/_apis/wit/workitems/%d", c.project, id)
 	params := url.Values{}
@@ -303,6 +855,77 @@ func (c *Client) DeleteWorkItem(ctx context.Context, id int, destroy bool) (map[
 	return payload, nil
 }
 
+func (c *Client) UploadAttachment(ctx context.Context, fileName string, content io.Reader, size int64) (AttachmentReference, error) {
+	if strings.TrimSpace(fileName) == "" {
+		return AttachmentReference{}, errs.New("invalid_args", "attachment file name is required", nil)
+	}
+	if content == nil {
+		return AttachmentReference{}, errs.New("invalid_args", "attachment content is required", nil)
+	}
+	path := fmt.Sprintf("%s/_apis/wit/attachments", c.project)
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	params.Set("fileName", fileName)
+	respBody, err := c.doStream(ctx, http.MethodPost, joinURL(c.baseURL, path), params, content, size, "application/octet-stream")
+	if err != nil {
+		return AttachmentReference{}, err
+	}
+	var ref AttachmentReference
+	if err := json.Unmarshal(respBody, &ref); err != nil {
+		return AttachmentReference{}, err
+	}
+	return ref, nil
+}
+
+func (c *Client) DownloadAttachment(ctx context.Context, attachmentID, fileName string, w io.Writer) (int64, error) {
+	if strings.TrimSpace(attachmentID) == "" {
+		return 0, errs.New("invalid_args", "attachment id is required", nil)
+	}
+	fullURL := joinURL(c.baseURL, "_apis/wit/attachments/"+url.PathEscape(attachmentID))
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	params.Set("download", "true")
+	if fileName != "" {
+		params.Set("fileName", fileName)
+	}
+	fullURL = fullURL + "?" + params.Encode()
+
+	resp, err := c.sendWithRetry(ctx, c.streamClient(), func() (*http.Request, error) {
+		req, err := c.newRequest(ctx, http.MethodGet, fullURL, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/octet-stream")
+		return req, nil
+	}, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if c.verbose {
+		c.logResponse(resp, nil)
+	}
+	return io.Copy(w, resp.Body)
+}
+
+func (c *Client) GetRepository(ctx context.Context, repository string) (GitRepository, error) {
+	if strings.TrimSpace(repository) == "" {
+		return GitRepository{}, errs.New("invalid_args", "repository is required", nil)
+	}
+	path := fmt.Sprintf("%s/_apis/git/repositories/%s", c.project, url.PathEscape(repository))
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	respBody, err := c.do(ctx, http.MethodGet, path, params, nil, "")
+	if err != nil {
+		return GitRepository{}, err
+	}
+	var repo GitRepository
+	if err := json.Unmarshal(respBody, &repo); err != nil {
+		return GitRepository{}, err
+	}
+	return repo, nil
+}
+
 func (c *Client) CreatePullRequest(ctx context.Context, repository string, req CreatePullRequestRequest) (GitPullRequest, error) {
 	if strings.TrimSpace(repository) == "" {
 		return GitPullRequest{}, errs.New("invalid_args", "repository is required", nil)
@@ -530,6 +1153,84 @@ func (c *Client) ProfileMe(ctx context.Context) (Profile, error) {
 	return profile, nil
 }
 
+// GetWorkItemTypeFields returns the fields of a work item type with their
+// allowed values.
+func (c *Client) GetWorkItemTypeFields(ctx context.Context, typeName string) ([]WorkItemTypeField, error) {
+	if strings.TrimSpace(typeName) == "" {
+		return nil, errs.New("invalid_args", "work item type is required", nil)
+	}
+	path := fmt.Sprintf("%s/_apis/wit/workitemtypes/%s/fields", c.project, url.PathEscape(typeName))
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	params.Set("$expand", "allowedValues")
+	respBody, err := c.do(ctx, http.MethodGet, path, params, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	var resp WorkItemTypeFieldsResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
+// GetWorkItemTypeStates returns the states of a work item type in workflow
+// order.
+func (c *Client) GetWorkItemTypeStates(ctx context.Context, typeName string) ([]WorkItemTypeState, error) {
+	if strings.TrimSpace(typeName) == "" {
+		return nil, errs.New("invalid_args", "work item type is required", nil)
+	}
+	path := fmt.Sprintf("%s/_apis/wit/workitemtypes/%s/states", c.project, url.PathEscape(typeName))
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	respBody, err := c.do(ctx, http.MethodGet, path, params, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	var resp WorkItemTypeStatesResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
+// ListFields returns the definitions of every field in the project.
+func (c *Client) ListFields(ctx context.Context) ([]FieldDefinition, error) {
+	path := fmt.Sprintf("%s/_apis/wit/fields", c.project)
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	respBody, err := c.do(ctx, http.MethodGet, path, params, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	var resp FieldDefinitionsResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
+// SearchIdentities finds users and groups by display name, account or email.
+func (c *Client) SearchIdentities(ctx context.Context, filter string) ([]Identity, error) {
+	if strings.TrimSpace(filter) == "" {
+		return nil, errs.New("invalid_args", "identity search text is required", nil)
+	}
+	params := url.Values{}
+	params.Set("api-version", defaultAPIVersion)
+	params.Set("searchFilter", "General")
+	params.Set("filterValue", filter)
+	params.Set("queryMembership", "None")
+	respBody, err := c.do(ctx, http.MethodGet, "_apis/identities", params, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	var resp IdentitiesResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
 func (c *Client) ListWorkItemTypes(ctx context.Context) ([]WorkItemType, error) {
 	path := fmt.Sprintf("%s/_apis/wit/workitemtypes", c.project)
 	params := url.Values{}
@@ -622,51 +1323,109 @@ func (c *Client) doFullURLWithHeaders(ctx context.Context, method, fullURL strin
 	if params != nil && len(params) > 0 {
 		fullURL = fullURL + "?" + params.Encode()
 	}
-	var lastErr error
+	resp, err := c.sendWithRetry(ctx, c.client, func() (*http.Request, error) {
+		return c.newRequest(ctx, method, fullURL, body, contentType)
+	}, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.verbose {
+		c.logResponse(resp, respBody)
+	}
+	return resp.Header, respBody, nil
+}
+
+// sendWithRetry sends the request built by newRequest, retrying throttled
+// and server errors with backoff. A successful response is returned with its
+// body unread; any other status becomes an error. body is only logged.
+func (c *Client) sendWithRetry(ctx context.Context, httpClient *http.Client, newRequest func() (*http.Request, error), body []byte) (*http.Response, error) {
 	backoff := initialBackoff
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		req, err := c.newRequest(ctx, method, fullURL, body, contentType)
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		if c.verbose {
 			c.logRequest(req, body)
 		}
-		resp, err := c.client.Do(req)
+		resp, err := httpClient.Do(req)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			return resp, nil
 		}
 		respBody, readErr := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
 		if readErr != nil {
-			return nil, nil, readErr
+			return nil, readErr
 		}
 		if c.verbose {
 			c.logResponse(resp, respBody)
 		}
-		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
-			return resp.Header, respBody, nil
+		if !shouldRetry(resp.StatusCode) || attempt >= maxRetries {
+			return nil, statusError(resp.StatusCode, respBody)
 		}
-
-		if shouldRetry(resp.StatusCode) && attempt < maxRetries {
-			wait := retryAfter(resp.Header.Get("Retry-After"))
-			if wait == 0 {
-				wait = backoff
-				backoff *= 2
-				if backoff > maxBackoff {
-					backoff = maxBackoff
-				}
+		wait := retryAfter(resp.Header.Get("Retry-After"))
+		if wait == 0 {
+			wait = backoff
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
 			}
-			lastErr = errs.New("http_retry", fmt.Sprintf("retryable status %d", resp.StatusCode), string(respBody))
-			time.Sleep(wait)
-			continue
 		}
-		return nil, nil, errs.New("http_error", fmt.Sprintf("request failed with status %d", resp.StatusCode), string(respBody))
+		if err := waitRetry(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// doStream sends a request whose body is read from content exactly once. It
+// never retries: a streamed body that was partially sent cannot be replayed.
+func (c *Client) doStream(ctx context.Context, method, fullURL string, params url.Values, content io.Reader, size int64, contentType string) ([]byte, error) {
+	if len(params) > 0 {
+		fullURL = fullURL + "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, content)
+	if err != nil {
+		return nil, err
+	}
+	c.setRequestHeaders(req, contentType)
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	if c.verbose {
+		c.logRequest(req, []byte(fmt.Sprintf("<streamed %d bytes>", size)))
+	}
+	resp, err := c.streamClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, readErr := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if readErr != nil {
+		return nil, readErr
 	}
-	if lastErr != nil {
-		return nil, nil, lastErr
+	if c.verbose {
+		c.logResponse(resp, respBody)
 	}
-	return nil, nil, errs.New("http_error", "request failed", nil)
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return respBody, nil
+	}
+	return nil, statusError(resp.StatusCode, respBody)
+}
+
+// streamClient drops the overall request timeout so large attachments are
+// bounded only by the caller's context.
+func (c *Client) streamClient() *http.Client {
+	clone := *c.client
+	clone.Timeout = 0
+	return &clone
 }
 
 func (c *Client) newRequest(ctx context.Context, method, fullURL string, body []byte, contentType string) (*http.Request, error) {
@@ -678,12 +1437,16 @@ func (c *Client) newRequest(ctx context.Context, method, fullURL string, body []
 	if err != nil {
 		return nil, err
 	}
+	c.setRequestHeaders(req, contentType)
+	return req, nil
+}
+
+func (c *Client) setRequestHeaders(req *http.Request, contentType string) {
 	req.Header.Set("Accept", "application/json")
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
 	}
 	req.Header.Set("Authorization", "Basic "+basicAuthToken(c.pat))
-	return req, nil
 }
 
 func basicAuthToken(pat string) string {
@@ -691,6 +1454,28 @@ func basicAuthToken(pat string) string {
 	return encoded
 }
 
+// statusError reports a failed response. 409 and 412, which the server
+// returns when a JSON-patch test operation such as the one on /rev fails,
+// become "conflict" so callers can tell a concurrent change from other errors.
+func statusError(status int, body []byte) error {
+	message := statusMessage(status)
+	if status == http.StatusConflict || status == http.StatusPreconditionFailed {
+		return errs.New("conflict", message, string(body))
+	}
+	return errs.New("http_error", message, string(body))
+}
+
+func statusMessage(status int) string {
+	return fmt.Sprintf("request failed with status %d", status)
+}
+
+// IsStatus reports whether err is the error returned for a response with
+// the given HTTP status.
+func IsStatus(err error, status int) bool {
+	appErr, ok := err.(errs.AppError)
+	return ok && appErr.Message == statusMessage(status)
+}
+
 func shouldRetry(status int) bool {
 	return status == http.StatusTooManyRequests || status >= 500
 }
@@ -705,6 +1490,19 @@ func retryAfter(value string) time.Duration {
 	return 0
 }
 
+// waitRetry waits before a retry, returning early with the context's error
+// when it is cancelled.
+func waitRetry(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func (c *Client) logRequest(req *http.Request, body []byte) {
 	if c.log == nil {
 		return
@@ -746,6 +1544,30 @@ func truncateBody(body []byte) string {
 	return string(body[:limit]) + "..."
 }
 
+// wiqlAsOfClause finds an ASOF clause already present in a query.
+var wiqlAsOfClause = regexp.MustCompile(`(?i)\basof\s+'`)
+
+// HasAsOfClause reports whether a WIQL query sets its own ASOF time, which
+// an asOf argument to Wiql cannot override.
+func HasAsOfClause(query string) bool {
+	return wiqlAsOfClause.MatchString(query)
+}
+
+// formatAsOf formats an asOf time the way the server takes it, in UTC.
+func formatAsOf(asOf time.Time) string {
+	return asOf.UTC().Format(time.RFC3339)
+}
+
+// escapeQueryPath escapes each segment of a query path such as
+// "Shared Queries/Team/Active bugs" while keeping the separators.
+func escapeQueryPath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
 func joinURL(base, path string) string {
 	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
 }
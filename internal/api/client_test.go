@@ -2,11 +2,17 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"tfs-cli/internal/errs"
 )
 
 func TestGetWorkItemCommentsPaginatesByRevision(t *testing.T) {
@@ -80,3 +86,253 @@ func TestGetWorkItemCommentsSupportsCommentsPropertyAndLimit(t *testing.T) {
 		t.Fatalf("unexpected comments: %#v", comments)
 	}
 }
+
+func TestGetWorkItemUpdatesPaginatesBySkip(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/RND/_apis/wit/workItems/42/updates" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("$top"); got != strconv.Itoa(workItemUpdatesPageSize) {
+			t.Fatalf("unexpected page size: %s", got)
+		}
+		switch r.URL.Query().Get("$skip") {
+		case "0":
+			page := make([]string, 0, workItemUpdatesPageSize)
+			for rev := 1; rev <= workItemUpdatesPageSize; rev++ {
+				page = append(page, fmt.Sprintf(`{"rev":%d}`, rev))
+			}
+			fmt.Fprintf(w, `{"count":%d,"value":[%s]}`, len(page), strings.Join(page, ","))
+		case strconv.Itoa(workItemUpdatesPageSize):
+			fmt.Fprintf(w, `{"count":1,"value":[{"rev":%d,"fields":{"System.State":{"oldValue":"New","newValue":"Active"}}}]}`, workItemUpdatesPageSize+1)
+		default:
+			t.Fatalf("unexpected skip: %s", r.URL.Query().Get("$skip"))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	updates, err := client.GetWorkItemUpdates(context.Background(), 42, 0)
+	if err != nil {
+		t.Fatalf("GetWorkItemUpdates returned error: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("got %d requests, want 2", requests)
+	}
+	last := updates[len(updates)-1]
+	if len(updates) != workItemUpdatesPageSize+1 || last.Rev != workItemUpdatesPageSize+1 {
+		t.Fatalf("unexpected updates: %d, last %#v", len(updates), last)
+	}
+	if got := last.Fields["System.State"].NewValue; got != "Active" {
+		t.Fatalf("unexpected new value: %v", got)
+	}
+}
+
+func TestUpdateWorkItemReportsFailedRevisionTestAsConflict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/RND/_apis/wit/workitems/42" {
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusPreconditionFailed)
+		fmt.Fprint(w, `{"message":"test operation failed"}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	_, err = client.UpdateWorkItem(context.Background(), 42, []map[string]interface{}{{"op": "test", "path": "/rev", "value": 3}})
+	appErr, ok := err.(errs.AppError)
+	if !ok || appErr.Code != "conflict" {
+		t.Fatalf("expected conflict error, got %#v", err)
+	}
+}
+
+func TestGetWorkItemRevision(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/RND/_apis/wit/workItems/42/revisions/3" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"id":42,"rev":3,"fields":{"System.Title":"Old"}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	wi, err := client.GetWorkItemRevision(context.Background(), 42, 3)
+	if err != nil {
+		t.Fatalf("GetWorkItemRevision returned error: %v", err)
+	}
+	if wi.Rev != 3 || wi.Fields["System.Title"] != "Old" {
+		t.Fatalf("unexpected revision: %#v", wi)
+	}
+}
+
+func TestClassificationNodeRequests(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath()+" "+r.URL.Query().Get("$reclassifyId")+" "+string(body))
+		switch r.Method {
+		case http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"VS402485: The node does not exist"}`)
+		case http.MethodPost:
+			fmt.Fprint(w, `{"id":12,"name":"Sprint 1","path":"\\RND\\Iteration\\Release 5\\Sprint 1"}`)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	_, err = client.GetClassificationNode(context.Background(), IterationNodes, "Release 9", 0)
+	if !IsStatus(err, http.StatusNotFound) {
+		t.Fatalf("expected a 404 error, got %v", err)
+	}
+	if _, err := client.CreateClassificationNode(context.Background(), IterationNodes, "Release 5", ClassificationNode{ID: 12}); err != nil {
+		t.Fatalf("CreateClassificationNode returned error: %v", err)
+	}
+	if err := client.DeleteClassificationNode(context.Background(), AreaNodes, "Team A/Old", 7); err != nil {
+		t.Fatalf("DeleteClassificationNode returned error: %v", err)
+	}
+	want := []string{
+		"GET /RND/_apis/wit/classificationnodes/iterations/Release%209  ",
+		`POST /RND/_apis/wit/classificationnodes/iterations/Release%205  {"id":12}`,
+		"DELETE /RND/_apis/wit/classificationnodes/areas/Team%20A/Old 7 ",
+	}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected requests:\n%s", strings.Join(requests, "\n"))
+	}
+}
+
+func TestGetIterationCapacitiesUsesTeamPath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/RND/Team%20A/_apis/work/teamsettings/iterations/it-1/capacities" {
+			t.Fatalf("unexpected path: %s", r.URL.EscapedPath())
+		}
+		fmt.Fprint(w, `{"teamMembers":[{"teamMember":{"displayName":"Ann","uniqueName":"ann@example.com"},"activities":[{"name":"Development","capacityPerDay":6}],"daysOff":[]}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	capacities, err := client.GetIterationCapacities(context.Background(), "Team A", "it-1")
+	if err != nil {
+		t.Fatalf("GetIterationCapacities returned error: %v", err)
+	}
+	if len(capacities) != 1 || capacities[0].TeamMember.UniqueName != "ann@example.com" || capacities[0].Activities[0].CapacityPerDay != 6 {
+		t.Fatalf("unexpected capacities: %#v", capacities)
+	}
+}
+
+func TestReorderWorkItemsSendsNeighbours(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.EscapedPath() != "/RND/Team%20A/_apis/work/workitemsorder" {
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL.EscapedPath())
+		}
+		var op ReorderOperation
+		if err := json.NewDecoder(r.Body).Decode(&op); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if len(op.IDs) != 2 || op.PreviousID != 4 || op.NextID != 5 || op.ParentID != 0 {
+			t.Fatalf("unexpected operation: %#v", op)
+		}
+		fmt.Fprint(w, `{"count":2,"value":[{"id":1,"order":1000102770},{"id":2,"order":1000110675}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	results, err := client.ReorderWorkItems(context.Background(), "Team A", ReorderOperation{IDs: []int{1, 2}, PreviousID: 4, NextID: 5})
+	if err != nil {
+		t.Fatalf("ReorderWorkItems returned error: %v", err)
+	}
+	if len(results) != 2 || results[1].Order != 1000110675 {
+		t.Fatalf("unexpected results: %#v", results)
+	}
+}
+
+func TestWiqlAndBatchSendAsOf(t *testing.T) {
+	asOf := time.Date(2025, 1, 6, 9, 30, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/RND/_apis/wit/wiql":
+			var req WiqlRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if req.Query != "SELECT [System.Id] FROM WorkItems ASOF '2025-01-06T09:30:00Z'" {
+				t.Fatalf("unexpected query: %s", req.Query)
+			}
+			fmt.Fprint(w, `{"workItems":[{"id":7}],"columns":[{"referenceName":"System.Id"},{"referenceName":"System.State"}]}`)
+		case "/RND/_apis/wit/workitemsbatch":
+			var req WorkItemsBatchRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if req.AsOf != "2025-01-06T09:30:00Z" {
+				t.Fatalf("unexpected asOf: %q", req.AsOf)
+			}
+			fmt.Fprint(w, `{"count":1,"value":[{"id":7,"rev":2,"fields":{"System.State":"New"}}]}`)
+		default:
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	resp, err := client.Wiql(context.Background(), "SELECT [System.Id] FROM WorkItems;", 0, asOf)
+	if err != nil {
+		t.Fatalf("Wiql returned error: %v", err)
+	}
+	if len(resp.Columns) != 2 || resp.Columns[1].ReferenceName != "System.State" {
+		t.Fatalf("unexpected columns: %#v", resp.Columns)
+	}
+	items, err := client.GetWorkItemsBatch(context.Background(), []int{7}, []string{"System.State"}, asOf)
+	if err != nil || len(items) != 1 || items[0].Rev != 2 {
+		t.Fatalf("unexpected batch result: %#v %v", items, err)
+	}
+	_, err = client.Wiql(context.Background(), "SELECT [System.Id] FROM WorkItems asof '2024-12-01'", 0, asOf)
+	if appErr, ok := err.(errs.AppError); !ok || appErr.Code != "invalid_args" {
+		t.Fatalf("expected invalid_args for a query with its own ASOF clause, got %v", err)
+	}
+}
+
+func TestRetryWaitStopsWhenContextIsCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = client.ListWorkItemTypes(ctx)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("retry wait ignored the context: took %s", elapsed)
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetQueryEscapesPathSegments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/RND/_apis/wit/queries/Shared%20Queries/Team%20A/Active%20bugs%3F" {
+			t.Fatalf("unexpected path: %s", r.URL.EscapedPath())
+		}
+		if r.URL.Query().Get("$expand") != "wiql" {
+			t.Fatalf("expected wiql expansion: %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"id":"q-1","name":"Active bugs?","path":"Shared Queries/Team A/Active bugs?","queryType":"tree","wiql":"SELECT"}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	query, err := client.GetQuery(context.Background(), "Shared Queries/Team A/Active bugs?", 0)
+	if err != nil {
+		t.Fatalf("GetQuery returned error: %v", err)
+	}
+	if query.ID != "q-1" || query.QueryType != "tree" || query.Wiql != "SELECT" {
+		t.Fatalf("unexpected query: %#v", query)
+	}
+}
+
+func TestRunQueryUsesStoredQueryID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/RND/_apis/wit/wiql/q-1" {
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if r.URL.Query().Get("$top") != "10" {
+			t.Fatalf("unexpected top: %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"queryResultType":"workItemLink","workItemRelations":[{"target":{"id":1}},{"rel":"System.LinkTypes.Hierarchy-Forward","source":{"id":1},"target":{"id":2}}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	resp, err := client.RunQuery(context.Background(), "q-1", 10)
+	if err != nil {
+		t.Fatalf("RunQuery returned error: %v", err)
+	}
+	if len(resp.WorkItemLinks) != 2 || resp.WorkItemLinks[1].Source.ID != 1 {
+		t.Fatalf("unexpected response: %#v", resp)
+	}
+}
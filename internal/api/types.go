@@ -2,6 +2,7 @@ package api
 
 type WorkItem struct {
 	ID        int                    `json:"id"`
+	Rev       int                    `json:"rev,omitempty"`
 	Fields    map[string]interface{} `json:"fields"`
 	Relations []interface{}          `json:"relations"`
 	URL       string                 `json:"url"`
@@ -16,6 +17,15 @@ type WorkItemType struct {
 	URL            string              `json:"url"`
 	Fields         []WorkItemTypeField `json:"fields"`
 	FieldInstances []WorkItemTypeField `json:"fieldInstances"`
+	States         []WorkItemTypeState `json:"states,omitempty"`
+	// Transitions maps a state to the states it can move to; the key ""
+	// lists the initial states.
+	Transitions map[string][]WorkItemStateTransition `json:"transitions,omitempty"`
+}
+
+type WorkItemStateTransition struct {
+	To      string   `json:"to"`
+	Actions []string `json:"actions,omitempty"`
 }
 
 type WorkItemTypesResponse struct {
@@ -24,8 +34,46 @@ type WorkItemTypesResponse struct {
 }
 
 type WorkItemTypeField struct {
+	Name           string        `json:"name"`
+	ReferenceName  string        `json:"referenceName"`
+	AlwaysRequired bool          `json:"alwaysRequired,omitempty"`
+	AllowedValues  []interface{} `json:"allowedValues,omitempty"`
+}
+
+type WorkItemTypeFieldsResponse struct {
+	Count int                 `json:"count"`
+	Value []WorkItemTypeField `json:"value"`
+}
+
+// WorkItemTypeState is a state of a work item type. Category is one of
+// Proposed, InProgress, Resolved, Completed or Removed whatever the state is
+// called in the process.
+type WorkItemTypeState struct {
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	Category string `json:"category"`
+}
+
+type WorkItemTypeStatesResponse struct {
+	Count int                 `json:"count"`
+	Value []WorkItemTypeState `json:"value"`
+}
+
+// FieldDefinition describes a field of the collection. Type is one of
+// string, integer, double, dateTime, plainText, html, treePath, history,
+// boolean, identity, picklistString, picklistInteger, picklistDouble or guid.
+type FieldDefinition struct {
 	Name          string `json:"name"`
 	ReferenceName string `json:"referenceName"`
+	Type          string `json:"type"`
+	ReadOnly      bool   `json:"readOnly"`
+	IsIdentity    bool   `json:"isIdentity"`
+	IsPicklist    bool   `json:"isPicklist"`
+}
+
+type FieldDefinitionsResponse struct {
+	Count int               `json:"count"`
+	Value []FieldDefinition `json:"value"`
 }
 
 type WorkItemReference struct {
@@ -48,12 +96,229 @@ type WiqlResponse struct {
 	QueryResultType string              `json:"queryResultType"`
 	WorkItems       []WorkItemReference `json:"workItems"`
 	WorkItemLinks   []WorkItemLink      `json:"workItemRelations"`
+	Columns         []FieldReference    `json:"columns,omitempty"`
+}
+
+type QueryHierarchyItem struct {
+	ID          string               `json:"id,omitempty"`
+	Name        string               `json:"name"`
+	Path        string               `json:"path,omitempty"`
+	IsFolder    bool                 `json:"isFolder,omitempty"`
+	IsPublic    bool                 `json:"isPublic,omitempty"`
+	HasChildren bool                 `json:"hasChildren,omitempty"`
+	QueryType   string               `json:"queryType,omitempty"`
+	Wiql        string               `json:"wiql,omitempty"`
+	Children    []QueryHierarchyItem `json:"children,omitempty"`
+	URL         string               `json:"url,omitempty"`
+}
+
+type QueryHierarchyItemsResponse struct {
+	Count int                  `json:"count"`
+	Value []QueryHierarchyItem `json:"value"`
+}
+
+// ClassificationNode is an area or iteration. Path is the server's path,
+// which includes the structure name ("\\Project\\Iteration\\Sprint 1"), unlike
+// the values of System.AreaPath and System.IterationPath.
+type ClassificationNode struct {
+	ID            int                           `json:"id,omitempty"`
+	Identifier    string                        `json:"identifier,omitempty"`
+	Name          string                        `json:"name,omitempty"`
+	StructureType string                        `json:"structureType,omitempty"`
+	HasChildren   bool                          `json:"hasChildren,omitempty"`
+	Path          string                        `json:"path,omitempty"`
+	Attributes    *ClassificationNodeAttributes `json:"attributes,omitempty"`
+	Children      []ClassificationNode          `json:"children,omitempty"`
+	URL           string                        `json:"url,omitempty"`
+}
+
+// ClassificationNodeAttributes holds the dates of an iteration.
+type ClassificationNodeAttributes struct {
+	StartDate  string `json:"startDate,omitempty"`
+	FinishDate string `json:"finishDate,omitempty"`
+}
+
+// TeamSettings holds the team settings the sprint commands use.
+type TeamSettings struct {
+	WorkingDays  []string `json:"workingDays"`
+	BugsBehavior string   `json:"bugsBehavior,omitempty"`
+}
+
+// TeamSettingsIteration is an iteration selected by a team. TimeFrame is
+// past, current or future.
+type TeamSettingsIteration struct {
+	ID         string                  `json:"id"`
+	Name       string                  `json:"name"`
+	Path       string                  `json:"path"`
+	Attributes TeamIterationAttributes `json:"attributes"`
+	URL        string                  `json:"url,omitempty"`
+}
+
+type TeamIterationAttributes struct {
+	StartDate  string `json:"startDate,omitempty"`
+	FinishDate string `json:"finishDate,omitempty"`
+	TimeFrame  string `json:"timeFrame,omitempty"`
+}
+
+type TeamSettingsIterationsResponse struct {
+	Count int                     `json:"count"`
+	Value []TeamSettingsIteration `json:"value"`
+}
+
+// IterationWorkItems lists the items of a sprint backlog. Top-level items
+// have no source; their children are linked with Hierarchy-Forward.
+type IterationWorkItems struct {
+	WorkItemRelations []WorkItemLink `json:"workItemRelations"`
+}
+
+// TeamMemberCapacity is the planned capacity of one member in an iteration.
+type TeamMemberCapacity struct {
+	TeamMember IdentityRef        `json:"teamMember"`
+	Activities []ActivityCapacity `json:"activities"`
+	DaysOff    []DateRange        `json:"daysOff"`
+}
+
+type ActivityCapacity struct {
+	Name           string  `json:"name"`
+	CapacityPerDay float64 `json:"capacityPerDay"`
+}
+
+// DateRange is an inclusive range of days off.
+type DateRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type TeamDaysOff struct {
+	DaysOff []DateRange `json:"daysOff"`
+}
+
+// BacklogConfiguration describes the backlog levels of a team.
+// BacklogFields.TypeFields maps roles such as Order and Effort to the
+// reference names of the fields that fill them in the process.
+type BacklogConfiguration struct {
+	BacklogFields      BacklogFields  `json:"backlogFields"`
+	BugsBehavior       string         `json:"bugsBehavior,omitempty"`
+	HiddenBacklogs     []string       `json:"hiddenBacklogs,omitempty"`
+	PortfolioBacklogs  []BacklogLevel `json:"portfolioBacklogs"`
+	RequirementBacklog BacklogLevel   `json:"requirementBacklog"`
+	TaskBacklog        BacklogLevel   `json:"taskBacklog"`
+	URL                string         `json:"url,omitempty"`
+}
+
+type BacklogFields struct {
+	TypeFields map[string]string `json:"typeFields"`
+}
+
+// BacklogLevel is one backlog level. Type is portfolio, requirement or
+// task; portfolio levels with a higher rank sit above lower ones.
+type BacklogLevel struct {
+	ID                  string                  `json:"id"`
+	Name                string                  `json:"name"`
+	Rank                int                     `json:"rank"`
+	Type                string                  `json:"type,omitempty"`
+	WorkItemCountLimit  int                     `json:"workItemCountLimit,omitempty"`
+	IsHidden            bool                    `json:"isHidden,omitempty"`
+	Color               string                  `json:"color,omitempty"`
+	WorkItemTypes       []WorkItemTypeReference `json:"workItemTypes"`
+	DefaultWorkItemType *WorkItemTypeReference  `json:"defaultWorkItemType,omitempty"`
+}
+
+type WorkItemTypeReference struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type BacklogLevelsResponse struct {
+	Count int            `json:"count"`
+	Value []BacklogLevel `json:"value"`
+}
+
+type BacklogLevelWorkItems struct {
+	WorkItems []WorkItemLink `json:"workItems"`
+}
+
+// ReorderOperation places IDs between PreviousID and NextID; 0 means the
+// top or the bottom of the backlog. ParentID 0 means no parent.
+type ReorderOperation struct {
+	IDs           []int  `json:"ids"`
+	ParentID      int    `json:"parentId"`
+	PreviousID    int    `json:"previousId"`
+	NextID        int    `json:"nextId"`
+	IterationPath string `json:"iterationPath,omitempty"`
+}
+
+type ReorderResult struct {
+	ID    int     `json:"id"`
+	Order float64 `json:"order"`
+}
+
+type ReorderResultsResponse struct {
+	Count int             `json:"count"`
+	Value []ReorderResult `json:"value"`
+}
+
+type BoardReference struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type BoardReferencesResponse struct {
+	Count int              `json:"count"`
+	Value []BoardReference `json:"value"`
+}
+
+// Board is a team's Kanban board. Fields names the WEF_*_Kanban fields
+// that store the column, the Doing/Done half of a split column and the
+// swimlane of each item on this board.
+type Board struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Revision int           `json:"revision,omitempty"`
+	Columns  []BoardColumn `json:"columns"`
+	Rows     []BoardRow    `json:"rows"`
+	Fields   BoardFields   `json:"fields"`
+	IsValid  bool          `json:"isValid,omitempty"`
+	URL      string        `json:"url,omitempty"`
+}
+
+// BoardColumn is a board column. StateMappings maps each work item type to
+// the state its items have in the column; ColumnType is incoming,
+// inProgress or outgoing.
+type BoardColumn struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	ItemLimit     int               `json:"itemLimit"`
+	StateMappings map[string]string `json:"stateMappings"`
+	IsSplit       bool              `json:"isSplit,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	ColumnType    string            `json:"columnType,omitempty"`
+}
+
+// BoardRow is a swimlane; the default lane has no name.
+type BoardRow struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type BoardFields struct {
+	ColumnField FieldReference `json:"columnField"`
+	DoneField   FieldReference `json:"doneField"`
+	RowField    FieldReference `json:"rowField"`
+}
+
+type FieldReference struct {
+	ReferenceName string `json:"referenceName"`
+	Name          string `json:"name,omitempty"`
+	URL           string `json:"url,omitempty"`
 }
 
 type WorkItemsBatchRequest struct {
 	IDs    []int    `json:"ids"`
 	Fields []string `json:"fields,omitempty"`
 	Expand string   `json:"$expand,omitempty"`
+	AsOf   string   `json:"asOf,omitempty"`
 }
 
 type WorkItemsBatchResponse struct {
@@ -61,6 +326,23 @@ type WorkItemsBatchResponse struct {
 	Value []WorkItem `json:"value"`
 }
 
+// WorkItemBatchOperation is one create or update sent through the $batch
+// endpoint. A zero ID together with Type creates a work item of that type.
+type WorkItemBatchOperation struct {
+	ID    int
+	Type  string
+	Patch []map[string]interface{}
+}
+
+// WorkItemBatchResult is the outcome of the operation at the same index of
+// the request. Err is an errs.AppError when the item failed.
+type WorkItemBatchResult struct {
+	ID       int
+	Status   int
+	WorkItem WorkItem
+	Err      error
+}
+
 type WorkItemComment struct {
 	Revision    int                    `json:"revision"`
 	Text        string                 `json:"text"`
@@ -77,6 +359,43 @@ type WorkItemCommentsResponse struct {
 	Comments          []WorkItemComment `json:"comments"`
 }
 
+type WorkItemFieldUpdate struct {
+	OldValue interface{} `json:"oldValue,omitempty"`
+	NewValue interface{} `json:"newValue,omitempty"`
+}
+
+type WorkItemRelationUpdates struct {
+	Added   []interface{} `json:"added,omitempty"`
+	Removed []interface{} `json:"removed,omitempty"`
+	Updated []interface{} `json:"updated,omitempty"`
+}
+
+type WorkItemUpdate struct {
+	ID          int                            `json:"id"`
+	WorkItemID  int                            `json:"workItemId"`
+	Rev         int                            `json:"rev"`
+	RevisedBy   map[string]interface{}         `json:"revisedBy"`
+	RevisedDate string                         `json:"revisedDate,omitempty"`
+	Fields      map[string]WorkItemFieldUpdate `json:"fields,omitempty"`
+	Relations   *WorkItemRelationUpdates       `json:"relations,omitempty"`
+	URL         string                         `json:"url,omitempty"`
+}
+
+type WorkItemUpdatesResponse struct {
+	Count int              `json:"count"`
+	Value []WorkItemUpdate `json:"value"`
+}
+
+type WorkItemRevisionsResponse struct {
+	Count int        `json:"count"`
+	Value []WorkItem `json:"value"`
+}
+
+type AttachmentReference struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
 type WikiPage struct {
 	ID              int        `json:"id,omitempty"`
 	Path            string     `json:"path"`
@@ -131,11 +450,17 @@ type IdentityRef struct {
 }
 
 type GitRepository struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	URL       string `json:"url"`
-	RemoteURL string `json:"remoteUrl"`
-	WebURL    string `json:"webUrl"`
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	URL       string            `json:"url"`
+	RemoteURL string            `json:"remoteUrl"`
+	WebURL    string            `json:"webUrl"`
+	Project   *ProjectReference `json:"project,omitempty"`
+}
+
+type ProjectReference struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
 }
 
 type GitPullRequest struct {
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/config"
+)
+
+// State categories shared by every process, whatever its states are called.
+const (
+	stateCategoryProposed   = "Proposed"
+	stateCategoryInProgress = "InProgress"
+	stateCategoryResolved   = "Resolved"
+	stateCategoryCompleted  = "Completed"
+	stateCategoryRemoved    = "Removed"
+)
+
+// stateCategoryAliases maps state category names and the English states of
+// the standard processes to their category, so --set state=Active works in a
+// localized process.
+var stateCategoryAliases = map[string]string{
+	"proposed":   stateCategoryProposed,
+	"new":        stateCategoryProposed,
+	"todo":       stateCategoryProposed,
+	"inprogress": stateCategoryInProgress,
+	"active":     stateCategoryInProgress,
+	"committed":  stateCategoryInProgress,
+	"doing":      stateCategoryInProgress,
+	"resolved":   stateCategoryResolved,
+	"completed":  stateCategoryCompleted,
+	"closed":     stateCategoryCompleted,
+	"done":       stateCategoryCompleted,
+	"removed":    stateCategoryRemoved,
+}
+
+// defaultValueAliases lists, per field, localized names of the English
+// picklist values of the standard processes. A value is only replaced by a
+// name the work item type actually allows.
+var defaultValueAliases = map[string]map[string][]string{
+	"microsoft.vsts.common.activity": {
+		"development":   {"Разработка"},
+		"testing":       {"Тестирование"},
+		"documentation": {"Документация"},
+		"design":        {"Проектирование", "Дизайн"},
+		"deployment":    {"Развертывание"},
+		"requirements":  {"Требования"},
+	},
+}
+
+// resolveFieldAlias returns the reference name for name: a configured alias
+// first, then names containing a dot as they are, then the built-in aliases.
+// Anything else is returned unchanged for the server's field list to resolve.
+func resolveFieldAlias(aliases map[string]string, name string) string {
+	name = strings.TrimSpace(name)
+	for alias, ref := range aliases {
+		if strings.EqualFold(alias, name) {
+			return ref
+		}
+	}
+	if strings.Contains(name, ".") {
+		return name
+	}
+	if ref, ok := columnAliases[strings.ToLower(name)]; ok {
+		return ref
+	}
+	return name
+}
+
+// aliasAssignments resolves the field names of Field=Value arguments.
+// Malformed arguments are kept for parseAssignment to report.
+func aliasAssignments(aliases map[string]string, assignments []string) []string {
+	resolved := make([]string, 0, len(assignments))
+	for _, assignment := range assignments {
+		field, value, err := parseAssignment(assignment)
+		if err != nil {
+			resolved = append(resolved, assignment)
+			continue
+		}
+		resolved = append(resolved, resolveFieldAlias(aliases, field)+"="+value)
+	}
+	return resolved
+}
+
+func aliasFieldNames(aliases map[string]string, names []string) []string {
+	resolved := make([]string, 0, len(names))
+	for _, name := range names {
+		resolved = append(resolved, resolveFieldAlias(aliases, name))
+	}
+	return resolved
+}
+
+// applyValueAliases replaces values configured in cfg.ValueAliases. Fields
+// in the configuration may be given by alias.
+func applyValueAliases(cfg config.Config, patch []map[string]interface{}) {
+	if len(cfg.ValueAliases) == 0 {
+		return
+	}
+	for _, op := range patch {
+		path, _ := op["path"].(string)
+		value, isString := op["value"].(string)
+		if !strings.HasPrefix(path, "/fields/") || !isString {
+			continue
+		}
+		field := strings.TrimPrefix(path, "/fields/")
+		for configured, values := range cfg.ValueAliases {
+			if !strings.EqualFold(resolveFieldAlias(cfg.FieldAliases, configured), field) {
+				continue
+			}
+			for from, to := range values {
+				if strings.EqualFold(from, strings.TrimSpace(value)) {
+					op["value"] = to
+				}
+			}
+		}
+	}
+}
+
+// stateCategory returns the category named by value, or "".
+func stateCategory(value string) string {
+	key := strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(value))
+	return stateCategoryAliases[key]
+}
+
+// statesInCategory returns the names of the states of the given types that
+// belong to category, in workflow order and without duplicates.
+func statesInCategory(ctx context.Context, typeStates func(context.Context, string) ([]api.WorkItemTypeState, error), types []string, category string) ([]string, error) {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, typeName := range types {
+		states, err := typeStates(ctx, typeName)
+		if err != nil {
+			return nil, err
+		}
+		for _, state := range states {
+			if strings.EqualFold(state.Category, category) && !seen[state.Name] {
+				seen[state.Name] = true
+				names = append(names, state.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// enabledTypeNames lists the names of the work item types that are in use.
+func enabledTypeNames(types []api.WorkItemType) []string {
+	names := []string{}
+	for _, item := range types {
+		if !item.IsDisabled {
+			names = append(names, item.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// listedTypeStates returns a typeStates function that answers from the
+// states included in the work item type list, so listing the types is the
+// only request. Types listed without states are fetched with fetch.
+func listedTypeStates(types []api.WorkItemType, fetch func(context.Context, string) ([]api.WorkItemTypeState, error)) func(context.Context, string) ([]api.WorkItemTypeState, error) {
+	listed := make(map[string][]api.WorkItemTypeState, len(types))
+	for _, item := range types {
+		if len(item.States) > 0 {
+			listed[strings.ToLower(item.Name)] = item.States
+		}
+	}
+	return func(ctx context.Context, typeName string) ([]api.WorkItemTypeState, error) {
+		if states, ok := listed[strings.ToLower(typeName)]; ok {
+			return states, nil
+		}
+		return fetch(ctx, typeName)
+	}
+}
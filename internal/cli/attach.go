@@ -0,0 +1,388 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+const attachedFileRel = "AttachedFile"
+
+type attachmentInfo struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Size           int64  `json:"size"`
+	Comment        string `json:"comment,omitempty"`
+	AuthorizedDate string `json:"authorizedDate,omitempty"`
+	URL            string `json:"url"`
+}
+
+func runAttach(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "attach subcommand is required", nil), true)
+		return 1
+	}
+	switch args[0] {
+	case "add":
+		return runAttachAdd(args[1:], stdout, stderr)
+	case "list":
+		return runAttachList(args[1:], stdout, stderr)
+	case "get":
+		return runAttachGet(args[1:], stdout, stderr)
+	default:
+		output.WriteError(stderr, errs.New("unknown_command", "unknown attach subcommand", args[0]), true)
+		return 1
+	}
+}
+
+func runAttachAdd(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("attach add", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	comment := fs.String("comment", "", "Attachment comment")
+	name := fs.String("name", "", "Attachment name (defaults to the file name)")
+	idArg, rest := splitPositional(args, attachValueFlags())
+	fileArg, rest := splitPositional(rest, attachValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if idArg == "" || fileArg == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id and file path are required", nil), flags.json)
+		return 1
+	}
+	id, err := strconv.Atoi(idArg)
+	if err != nil || id <= 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id must be a positive number", nil), flags.json)
+		return 1
+	}
+	file, err := os.Open(fileArg)
+	if err != nil {
+		output.WriteError(stderr, errs.New("read_error", "could not open attachment file", err.Error()), flags.json)
+		return 1
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		output.WriteError(stderr, errs.New("read_error", "could not stat attachment file", err.Error()), flags.json)
+		return 1
+	}
+	if stat.IsDir() {
+		output.WriteError(stderr, errs.New("invalid_args", "attachment path is a directory", fileArg), flags.json)
+		return 1
+	}
+	fileName := strings.TrimSpace(*name)
+	if fileName == "" {
+		fileName = filepath.Base(fileArg)
+	}
+
+	ctx, err := buildContext(flags, stdout, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	if ctx.project == "" {
+		output.WriteError(stderr, errs.New("config_missing", "project is required", nil), flags.json)
+		return 1
+	}
+	client, err := api.NewClient(ctx.baseURL, ctx.project, ctx.pat, ctx.insecure, ctx.verbose, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+
+	ref, err := client.UploadAttachment(context.Background(), fileName, file, stat.Size())
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	patch := buildAttachmentPatch(ref.URL, *comment)
+	if _, err := client.UpdateWorkItem(context.Background(), id, patch); err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+
+	attachment := attachmentInfo{
+		ID:      ref.ID,
+		Name:    fileName,
+		Size:    stat.Size(),
+		Comment: *comment,
+		URL:     ref.URL,
+	}
+	payload := map[string]interface{}{
+		"workItemId": id,
+		"attachment": attachment,
+	}
+	return renderOutput(ctx, payload, func() output.Table {
+		table := attachmentTable([]attachmentInfo{attachment})
+		table.Headers = append([]string{"WorkItemID"}, table.Headers...)
+		table.Rows[0] = append([]string{strconv.Itoa(id)}, table.Rows[0]...)
+		return table
+	}, func() {
+		fmt.Fprintf(ctx.stdout, "Attached %s (%d bytes) to work item %d\n", fileName, stat.Size(), id)
+		fmt.Fprintf(ctx.stdout, "ID: %s\n", ref.ID)
+	})
+}
+
+func runAttachList(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("attach list", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	idArg, rest := splitPositional(args, attachValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if idArg == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id is required", nil), flags.json)
+		return 1
+	}
+	id, err := strconv.Atoi(idArg)
+	if err != nil || id <= 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id must be a positive number", nil), flags.json)
+		return 1
+	}
+	ctx, err := buildContext(flags, stdout, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	if ctx.project == "" {
+		output.WriteError(stderr, errs.New("config_missing", "project is required", nil), flags.json)
+		return 1
+	}
+	client, err := api.NewClient(ctx.baseURL, ctx.project, ctx.pat, ctx.insecure, ctx.verbose, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	wi, err := client.GetWorkItem(context.Background(), id, nil, "relations")
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	attachments := extractAttachments(wi.Relations)
+	return renderOutput(ctx, attachments, func() output.Table {
+		return attachmentTable(attachments)
+	}, func() {
+		printAttachmentTable(ctx.stdout, attachments)
+	})
+}
+
+func runAttachGet(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("attach get", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	var outPath string
+	fs.StringVar(&outPath, "o", "", "Output path (defaults to the attachment name; '-' for stdout)")
+	fs.StringVar(&outPath, "output", "", "Output path (defaults to the attachment name; '-' for stdout)")
+	idArg, rest := splitPositional(args, attachValueFlags())
+	nameArg, rest := splitPositional(rest, attachValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if idArg == "" || nameArg == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id and attachment name are required", nil), flags.json)
+		return 1
+	}
+	id, err := strconv.Atoi(idArg)
+	if err != nil || id <= 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id must be a positive number", nil), flags.json)
+		return 1
+	}
+	ctx, err := buildContext(flags, stdout, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	if ctx.project == "" {
+		output.WriteError(stderr, errs.New("config_missing", "project is required", nil), flags.json)
+		return 1
+	}
+	client, err := api.NewClient(ctx.baseURL, ctx.project, ctx.pat, ctx.insecure, ctx.verbose, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	wi, err := client.GetWorkItem(context.Background(), id, nil, "relations")
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	attachment, err := findAttachment(extractAttachments(wi.Relations), nameArg)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+
+	if outPath == "-" {
+		if _, err := client.DownloadAttachment(context.Background(), attachment.ID, attachment.Name, ctx.stdout); err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			return 1
+		}
+		return 0
+	}
+	if outPath == "" {
+		outPath = filepath.Base(attachment.Name)
+	}
+	written, err := downloadAttachmentToFile(client, attachment, outPath)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	payload := map[string]interface{}{
+		"workItemId": id,
+		"attachment": attachment,
+		"path":       outPath,
+		"bytes":      written,
+	}
+	return renderOutput(ctx, payload, func() output.Table {
+		return output.RecordTable([]string{"WorkItemID", "Name", "ID", "Path", "Bytes"}, id, attachment.Name, attachment.ID, outPath, written)
+	}, func() {
+		fmt.Fprintf(ctx.stdout, "Saved %s (%d bytes) to %s\n", attachment.Name, written, outPath)
+	})
+}
+
+func downloadAttachmentToFile(client *api.Client, attachment attachmentInfo, path string) (int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tfs-attachment-*")
+	if err != nil {
+		return 0, errs.New("write_error", "could not create output file", err.Error())
+	}
+	written, err := client.DownloadAttachment(context.Background(), attachment.ID, attachment.Name, tmp)
+	// CreateTemp makes the file readable by its owner only; give the saved
+	// attachment the usual permissions.
+	if err == nil {
+		if chmodErr := tmp.Chmod(0o644); chmodErr != nil {
+			err = errs.New("write_error", "could not write output file", chmodErr.Error())
+		}
+	}
+	closeErr := tmp.Close()
+	if err == nil && closeErr != nil {
+		err = errs.New("write_error", "could not write output file", closeErr.Error())
+	}
+	if err == nil {
+		if renameErr := os.Rename(tmp.Name(), path); renameErr != nil {
+			err = errs.New("write_error", "could not write output file", renameErr.Error())
+		}
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return 0, err
+	}
+	return written, nil
+}
+
+func buildAttachmentPatch(attachmentURL, comment string) []map[string]interface{} {
+	var attributes map[string]interface{}
+	if strings.TrimSpace(comment) != "" {
+		attributes = map[string]interface{}{"comment": comment}
+	}
+	return []map[string]interface{}{addRelationOp(attachedFileRel, attachmentURL, attributes)}
+}
+
+func extractAttachments(relations []interface{}) []attachmentInfo {
+	attachments := []attachmentInfo{}
+	for _, raw := range relations {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rel, _ := m["rel"].(string)
+		if rel != attachedFileRel {
+			continue
+		}
+		url, _ := m["url"].(string)
+		attachment := attachmentInfo{
+			ID:  lastURLSegment(url),
+			URL: url,
+		}
+		if attrs, ok := m["attributes"].(map[string]interface{}); ok {
+			attachment.Name, _ = attrs["name"].(string)
+			attachment.Comment, _ = attrs["comment"].(string)
+			attachment.AuthorizedDate, _ = attrs["authorizedDate"].(string)
+			if size, ok := attrs["resourceSize"].(float64); ok {
+				attachment.Size = int64(size)
+			}
+		}
+		if attachment.Name == "" {
+			attachment.Name = attachment.ID
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments
+}
+
+func findAttachment(attachments []attachmentInfo, nameOrID string) (attachmentInfo, error) {
+	for _, attachment := range attachments {
+		if strings.EqualFold(attachment.ID, nameOrID) {
+			return attachment, nil
+		}
+	}
+	matches := []attachmentInfo{}
+	for _, attachment := range attachments {
+		if strings.EqualFold(attachment.Name, nameOrID) {
+			matches = append(matches, attachment)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return attachmentInfo{}, errs.New("attachment_not_found", "attachment not found", nameOrID)
+	case 1:
+		return matches[0], nil
+	default:
+		ids := make([]string, 0, len(matches))
+		for _, match := range matches {
+			ids = append(ids, match.ID)
+		}
+		return attachmentInfo{}, errs.New("ambiguous_attachment", "several attachments share this name; pass the attachment id instead", ids)
+	}
+}
+
+func lastURLSegment(value string) string {
+	trimmed := strings.TrimRight(value, "/")
+	if idx := strings.IndexByte(trimmed, '?'); idx >= 0 {
+		trimmed = trimmed[:idx]
+	}
+	if idx := strings.LastIndexByte(trimmed, '/'); idx >= 0 {
+		return trimmed[idx+1:]
+	}
+	return trimmed
+}
+
+func attachmentTable(attachments []attachmentInfo) output.Table {
+	table := output.Table{Headers: []string{"Name", "Size", "Date", "ID", "Comment", "URL"}}
+	for _, attachment := range attachments {
+		table.Rows = append(table.Rows, []string{attachment.Name, strconv.FormatInt(attachment.Size, 10), attachment.AuthorizedDate,
+			attachment.ID, attachment.Comment, attachment.URL})
+	}
+	return table
+}
+
+func printAttachmentTable(w io.Writer, attachments []attachmentInfo) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSIZE\tDATE\tID")
+	for _, attachment := range attachments {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", attachment.Name, attachment.Size, attachment.AuthorizedDate, attachment.ID)
+	}
+	_ = tw.Flush()
+}
+
+func attachValueFlags() map[string]bool {
+	flags := wiqlValueFlags()
+	flags["comment"] = true
+	flags["name"] = true
+	flags["o"] = true
+	flags["output"] = true
+	return flags
+}
@@ -0,0 +1,300 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+// backlogOrderField is the role the backlog configuration maps to the
+// field backlogs are ranked by.
+const backlogOrderField = "Order"
+
+type reorderSummary struct {
+	After   int                 `json:"after,omitempty"`
+	Before  int                 `json:"before,omitempty"`
+	Parent  int                 `json:"parent,omitempty"`
+	Results []api.ReorderResult `json:"results"`
+}
+
+func runBacklog(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "backlog subcommand is required", nil), true)
+		return 1
+	}
+	switch args[0] {
+	case "levels":
+		return runBacklogLevels(args[1:], stdout, stderr)
+	case "items":
+		return runBacklogItems(args[1:], stdout, stderr)
+	case "reorder":
+		return runBacklogReorder(args[1:], stdout, stderr)
+	default:
+		output.WriteError(stderr, errs.New("unknown_command", "unknown backlog subcommand", args[0]), true)
+		return 1
+	}
+}
+
+func runBacklogLevels(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("backlog levels", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	team := fs.String("team", "", "Team name (default: config team, else the project's default team)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	levels, err := client.ListBacklogs(context.Background(), sprintTeam(ctx, *team))
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	sortBacklogLevels(levels)
+	return renderOutput(ctx, levels, func() output.Table {
+		table := output.Table{Headers: []string{"Name", "Type", "ID", "Rank", "Hidden", "WorkItemTypes"}}
+		for _, level := range levels {
+			table.Rows = append(table.Rows, []string{level.Name, level.Type, level.ID, strconv.Itoa(level.Rank),
+				strconv.FormatBool(level.IsHidden), strings.Join(backlogLevelTypes(level), ", ")})
+		}
+		return table
+	}, func() {
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tTYPE\tID\tWORK ITEM TYPES")
+		for _, level := range levels {
+			name := level.Name
+			if level.IsHidden {
+				name += " (hidden)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, level.Type, level.ID, strings.Join(backlogLevelTypes(level), ", "))
+		}
+		_ = tw.Flush()
+	})
+}
+
+func backlogLevelTypes(level api.BacklogLevel) []string {
+	types := make([]string, 0, len(level.WorkItemTypes))
+	for _, item := range level.WorkItemTypes {
+		types = append(types, item.Name)
+	}
+	return types
+}
+
+func runBacklogItems(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("backlog items", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	team := fs.String("team", "", "Team name (default: config team, else the project's default team)")
+	level := fs.String("level", "", "Backlog level name or id, e.g. Requirements, Features or Epics")
+	columnsCSV := fs.String("columns", "", "Comma-separated field reference names or aliases to show")
+	jsonExplicit := flagProvided(args, "json")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if strings.TrimSpace(*level) == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "--level is required", nil), flags.json)
+		return 1
+	}
+	columns, err := parseColumns(*columnsCSV)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	if !jsonExplicit && !flags.format.set {
+		ctx.jsonMode = false
+	}
+	teamName := sprintTeam(ctx, *team)
+	reqCtx := context.Background()
+	backlogConfig, err := client.GetBacklogConfiguration(reqCtx, teamName)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	selected, err := findBacklogLevel(backlogLevels(backlogConfig), *level)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	links, err := client.GetBacklogWorkItems(reqCtx, teamName, selected.ID)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	rankField := backlogConfig.BacklogFields.TypeFields[backlogOrderField]
+	extra := columnFields(columns)
+	if rankField != "" {
+		extra = append(extra, rankField)
+	}
+	items, err := fetchWorkItems(reqCtx, client, collectIDs(api.WiqlResponse{WorkItems: sprintItemRefs(links)}), extra...)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	sortByRank(items, rankField)
+	return renderColumnList(ctx, items, columns)
+}
+
+func runBacklogReorder(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("backlog reorder", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	team := fs.String("team", "", "Team name (default: config team, else the project's default team)")
+	after := fs.Int("after", 0, "Place the items right after this work item")
+	before := fs.Int("before", 0, "Place the items right before this work item")
+	parent := fs.Int("parent", 0, "Parent of the items, when reordering under a parent")
+	idArgs, rest := splitPositionals(args, backlogValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if len(idArgs) == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "at least one work item id is required", nil), flags.json)
+		return 1
+	}
+	ids, err := parseWorkItemIDs(idArgs)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	op, err := reorderOperation(ids, *after, *before, *parent)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	results, err := client.ReorderWorkItems(context.Background(), sprintTeam(ctx, *team), op)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	summary := reorderSummary{After: op.PreviousID, Before: op.NextID, Parent: op.ParentID, Results: results}
+	return renderOutput(ctx, summary, func() output.Table {
+		table := output.Table{Headers: []string{"ID", "Order"}}
+		for _, result := range results {
+			table.Rows = append(table.Rows, []string{strconv.Itoa(result.ID), editScalar(result.Order)})
+		}
+		return table
+	}, func() {
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tORDER")
+		for _, result := range results {
+			fmt.Fprintf(tw, "%d\t%s\n", result.ID, editScalar(result.Order))
+		}
+		_ = tw.Flush()
+		place := []string{}
+		if op.PreviousID > 0 {
+			place = append(place, fmt.Sprintf("after %d", op.PreviousID))
+		}
+		if op.NextID > 0 {
+			place = append(place, fmt.Sprintf("before %d", op.NextID))
+		}
+		fmt.Fprintf(stdout, "Reordered %d work items %s\n", len(results), strings.Join(place, " and "))
+	})
+}
+
+// reorderOperation builds the reorder request. At least one neighbour is
+// required, and neither may be one of the items being moved.
+func reorderOperation(ids []int, after, before, parent int) (api.ReorderOperation, error) {
+	if after < 0 || before < 0 || parent < 0 {
+		return api.ReorderOperation{}, errs.New("invalid_args", "work item id must be a positive number", nil)
+	}
+	if after == 0 && before == 0 {
+		return api.ReorderOperation{}, errs.New("invalid_args", "--after or --before is required", nil)
+	}
+	for _, id := range ids {
+		if id == after || id == before {
+			return api.ReorderOperation{}, errs.New("invalid_args", fmt.Sprintf("work item %d cannot be placed next to itself", id), nil)
+		}
+	}
+	return api.ReorderOperation{IDs: ids, PreviousID: after, NextID: before, ParentID: parent}, nil
+}
+
+// backlogLevels lists every level of a backlog configuration.
+func backlogLevels(config api.BacklogConfiguration) []api.BacklogLevel {
+	levels := append([]api.BacklogLevel{}, config.PortfolioBacklogs...)
+	for _, level := range []api.BacklogLevel{config.RequirementBacklog, config.TaskBacklog} {
+		if level.ID != "" {
+			levels = append(levels, level)
+		}
+	}
+	sortBacklogLevels(levels)
+	return levels
+}
+
+// sortBacklogLevels orders levels from the top of the hierarchy down.
+func sortBacklogLevels(levels []api.BacklogLevel) {
+	sort.SliceStable(levels, func(i, j int) bool {
+		return levels[i].Rank > levels[j].Rank
+	})
+}
+
+// findBacklogLevel picks a level by name or id. The level types are names
+// too, so "Requirements" finds the requirement backlog whatever the process
+// calls it; a trailing "s" is optional.
+func findBacklogLevel(levels []api.BacklogLevel, name string) (api.BacklogLevel, error) {
+	wanted := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), "s")
+	for _, level := range levels {
+		if strings.EqualFold(level.ID, strings.TrimSpace(name)) || strings.TrimSuffix(strings.ToLower(level.Name), "s") == wanted {
+			return level, nil
+		}
+	}
+	for _, level := range levels {
+		if level.Type != "portfolio" && strings.EqualFold(level.Type, wanted) {
+			return level, nil
+		}
+	}
+	names := make([]string, 0, len(levels))
+	for _, level := range levels {
+		names = append(names, level.Name)
+	}
+	return api.BacklogLevel{}, errs.New("backlog_not_found", fmt.Sprintf("the team has no backlog level %q", name), names)
+}
+
+// sortByRank orders items by the backlog rank field. Items without a rank
+// go last, as they do on the backlog.
+func sortByRank(items []output.WorkItem, field string) {
+	if field == "" {
+		return
+	}
+	rank := func(item output.WorkItem) (float64, bool) {
+		value, ok := item.Fields[field].(float64)
+		return value, ok
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		left, leftOK := rank(items[i])
+		right, rightOK := rank(items[j])
+		if leftOK != rightOK {
+			return leftOK
+		}
+		return leftOK && left < right
+	})
+}
+
+func backlogValueFlags() map[string]bool {
+	flags := wiqlValueFlags()
+	flags["team"] = true
+	flags["after"] = true
+	flags["before"] = true
+	flags["parent"] = true
+	return flags
+}
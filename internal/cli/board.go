@@ -0,0 +1,407 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+// boardView is a board with its items placed in columns. Items holds a
+// column's cards, or its Doing half when the column is split.
+type boardView struct {
+	Board   string            `json:"board"`
+	Team    string            `json:"team,omitempty"`
+	Lanes   []string          `json:"lanes"`
+	Columns []boardColumnView `json:"columns"`
+}
+
+type boardColumnView struct {
+	Name       string            `json:"name"`
+	ColumnType string            `json:"columnType,omitempty"`
+	ItemLimit  int               `json:"itemLimit,omitempty"`
+	IsSplit    bool              `json:"isSplit,omitempty"`
+	Items      []output.WorkItem `json:"items"`
+	Done       []output.WorkItem `json:"done,omitempty"`
+}
+
+type boardMoveResult struct {
+	ID     int    `json:"id"`
+	Board  string `json:"board"`
+	Column string `json:"column"`
+	Done   bool   `json:"done,omitempty"`
+	Lane   string `json:"lane,omitempty"`
+	State  string `json:"state"`
+	Rev    int    `json:"rev"`
+}
+
+func runBoard(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "board subcommand is required", nil), true)
+		return 1
+	}
+	switch args[0] {
+	case "show":
+		return runBoardShow(args[1:], stdout, stderr)
+	case "move":
+		return runBoardMove(args[1:], stdout, stderr)
+	default:
+		output.WriteError(stderr, errs.New("unknown_command", "unknown board subcommand", args[0]), true)
+		return 1
+	}
+}
+
+func runBoardShow(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("board show", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	team := fs.String("team", "", "Team name (default: config team, else the project's default team)")
+	boardName := fs.String("board", "", "Board name or backlog level, e.g. Stories or Features (default: the requirement backlog)")
+	jsonExplicit := flagProvided(args, "json")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	if !jsonExplicit && !flags.format.set {
+		ctx.jsonMode = false
+	}
+	teamName := sprintTeam(ctx, *team)
+	reqCtx := context.Background()
+	board, level, err := loadBoard(reqCtx, client, teamName, *boardName)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	links, err := client.GetBacklogWorkItems(reqCtx, teamName, level.ID)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	extra := []string{}
+	for _, field := range []api.FieldReference{board.Fields.ColumnField, board.Fields.DoneField, board.Fields.RowField} {
+		if field.ReferenceName != "" {
+			extra = append(extra, field.ReferenceName)
+		}
+	}
+	items, err := fetchWorkItems(reqCtx, client, collectIDs(api.WiqlResponse{WorkItems: sprintItemRefs(links)}), extra...)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	view := buildBoardView(board, items)
+	view.Team = teamName
+	rowField := board.Fields.RowField.ReferenceName
+	return renderOutput(ctx, view, func() output.Table { return boardTable(view, rowField) }, func() {
+		printBoard(stdout, view, rowField)
+	})
+}
+
+func runBoardMove(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("board move", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	team := fs.String("team", "", "Team name (default: config team, else the project's default team)")
+	boardName := fs.String("board", "", "Board name or backlog level, e.g. Stories or Features (default: the requirement backlog)")
+	column := fs.String("column", "", "Column to move the card to")
+	done := fs.Bool("done", false, "Put the card in the Done half of a split column")
+	lane := fs.String("lane", "", "Swimlane to move the card to (default: leave it in its lane)")
+	idArg, rest := splitPositional(args, boardValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if idArg == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id is required", nil), flags.json)
+		return 1
+	}
+	id, err := strconv.Atoi(idArg)
+	if err != nil || id <= 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id must be a positive number", idArg), flags.json)
+		return 1
+	}
+	if strings.TrimSpace(*column) == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "--column is required", nil), flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	teamName := sprintTeam(ctx, *team)
+	reqCtx := context.Background()
+	board, _, err := loadBoard(reqCtx, client, teamName, *boardName)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	wi, err := client.GetWorkItem(reqCtx, id, nil, "")
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	patch, result, err := planBoardMove(board, wi, *column, *done, *lane, flagProvided(args, "lane"))
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	updated, err := client.UpdateWorkItem(reqCtx, id, patch)
+	if err != nil {
+		output.WriteError(stderr, explainConflict(reqCtx, client, id, wi.Rev, err), ctx.jsonMode)
+		return 1
+	}
+	result.Rev = updated.Rev
+	table := output.RecordTable([]string{"ID", "Board", "Column", "Done", "Lane", "State", "Rev"},
+		result.ID, result.Board, result.Column, result.Done, result.Lane, result.State, result.Rev)
+	return renderOutput(ctx, result, func() output.Table { return table }, func() {
+		fmt.Fprintf(stdout, "Moved %d to %s (%s)\n", id, boardPlace(result.Column, result.Done, board), result.State)
+	})
+}
+
+// loadBoard finds the board named by name and the backlog level it shows.
+// Boards are named after their backlog level, so level names such as
+// Requirements work too; the default is the requirement backlog.
+func loadBoard(ctx context.Context, client *api.Client, team, name string) (api.Board, api.BacklogLevel, error) {
+	config, err := client.GetBacklogConfiguration(ctx, team)
+	if err != nil {
+		return api.Board{}, api.BacklogLevel{}, err
+	}
+	levels := backlogLevels(config)
+	level := config.RequirementBacklog
+	if strings.TrimSpace(name) != "" {
+		if level, err = findBacklogLevel(levels, name); err != nil {
+			return api.Board{}, api.BacklogLevel{}, errs.New("board_not_found", fmt.Sprintf("the team has no board %q", name), boardNames(levels))
+		}
+	}
+	board, err := client.GetBoard(ctx, team, level.Name)
+	if err != nil {
+		return api.Board{}, api.BacklogLevel{}, err
+	}
+	return board, level, nil
+}
+
+func boardNames(levels []api.BacklogLevel) []string {
+	names := []string{}
+	for _, level := range levels {
+		if level.Type != "task" {
+			names = append(names, level.Name)
+		}
+	}
+	return names
+}
+
+// buildBoardView places items in the board's columns. An item's column is
+// the value of the board's column field; items that have not been on the
+// board yet go to the column their state maps to.
+func buildBoardView(board api.Board, items []output.WorkItem) boardView {
+	view := boardView{Board: board.Name, Lanes: []string{}, Columns: make([]boardColumnView, 0, len(board.Columns))}
+	for _, row := range board.Rows {
+		view.Lanes = append(view.Lanes, row.Name)
+	}
+	for _, column := range board.Columns {
+		view.Columns = append(view.Columns, boardColumnView{
+			Name:       column.Name,
+			ColumnType: column.ColumnType,
+			ItemLimit:  column.ItemLimit,
+			IsSplit:    column.IsSplit,
+			Items:      []output.WorkItem{},
+		})
+	}
+	for _, item := range items {
+		index := boardColumnIndex(board, item)
+		if index < 0 {
+			continue
+		}
+		column := &view.Columns[index]
+		if done, _ := item.Fields[board.Fields.DoneField.ReferenceName].(bool); done && column.IsSplit {
+			column.Done = append(column.Done, item)
+			continue
+		}
+		column.Items = append(column.Items, item)
+	}
+	return view
+}
+
+func boardColumnIndex(board api.Board, item output.WorkItem) int {
+	name, _ := item.Fields[board.Fields.ColumnField.ReferenceName].(string)
+	for index, column := range board.Columns {
+		if name != "" && strings.EqualFold(column.Name, name) {
+			return index
+		}
+	}
+	typeName, state := stringValue(item.Type), stringValue(item.State)
+	for index, column := range board.Columns {
+		if mapped, ok := column.StateMappings[typeName]; ok && strings.EqualFold(mapped, state) {
+			return index
+		}
+	}
+	return -1
+}
+
+// planBoardMove builds the patch moving wi to column, and to lane when
+// laneSet. The item's state follows the column's state mapping, as it does
+// when a card is dragged on the board.
+func planBoardMove(board api.Board, wi api.WorkItem, columnName string, done bool, lane string, laneSet bool) ([]map[string]interface{}, boardMoveResult, error) {
+	typeName := workItemType(wi)
+	result := boardMoveResult{ID: wi.ID, Board: board.Name}
+	var column *api.BoardColumn
+	names := make([]string, 0, len(board.Columns))
+	for index := range board.Columns {
+		names = append(names, board.Columns[index].Name)
+		if strings.EqualFold(board.Columns[index].Name, strings.TrimSpace(columnName)) {
+			column = &board.Columns[index]
+		}
+	}
+	if column == nil {
+		return nil, result, errs.New("invalid_column", fmt.Sprintf("board %s has no column %q", board.Name, columnName), names)
+	}
+	state, ok := column.StateMappings[typeName]
+	if !ok {
+		return nil, result, errs.New("invalid_column", fmt.Sprintf("%s items cannot go to column %s on board %s", typeName, column.Name, board.Name), nil)
+	}
+	if done && !column.IsSplit {
+		return nil, result, errs.New("invalid_args", fmt.Sprintf("column %s is not split into Doing and Done", column.Name), nil)
+	}
+	if board.Fields.ColumnField.ReferenceName == "" {
+		return nil, result, errs.New("invalid_board", fmt.Sprintf("board %s has no column field; open it once in the web UI", board.Name), nil)
+	}
+	result.Column, result.Done, result.State = column.Name, done, state
+	patch := []map[string]interface{}{
+		revisionTestOp(wi.Rev),
+		{"op": "add", "path": "/fields/" + board.Fields.ColumnField.ReferenceName, "value": column.Name},
+	}
+	if column.IsSplit && board.Fields.DoneField.ReferenceName != "" {
+		patch = append(patch, map[string]interface{}{"op": "add", "path": "/fields/" + board.Fields.DoneField.ReferenceName, "value": done})
+	}
+	if current, _ := wi.Fields["System.State"].(string); !strings.EqualFold(current, state) {
+		patch = append(patch, map[string]interface{}{"op": "add", "path": "/fields/System.State", "value": state})
+	}
+	if laneSet {
+		row, found := api.BoardRow{}, false
+		lanes := make([]string, 0, len(board.Rows))
+		for _, candidate := range board.Rows {
+			lanes = append(lanes, laneName(candidate.Name))
+			if strings.EqualFold(candidate.Name, strings.TrimSpace(lane)) || (candidate.Name == "" && strings.EqualFold(laneName(""), strings.TrimSpace(lane))) {
+				row, found = candidate, true
+			}
+		}
+		if !found || board.Fields.RowField.ReferenceName == "" {
+			return nil, result, errs.New("invalid_lane", fmt.Sprintf("board %s has no swimlane %q", board.Name, lane), lanes)
+		}
+		result.Lane = laneName(row.Name)
+		patch = append(patch, map[string]interface{}{"op": "add", "path": "/fields/" + board.Fields.RowField.ReferenceName, "value": row.Name})
+	}
+	return patch, result, nil
+}
+
+// laneName is the display name of a swimlane; the default lane has none.
+func laneName(name string) string {
+	if name == "" {
+		return "Default lane"
+	}
+	return name
+}
+
+func boardPlace(column string, done bool, board api.Board) string {
+	for _, candidate := range board.Columns {
+		if strings.EqualFold(candidate.Name, column) && candidate.IsSplit {
+			if done {
+				return column + " / Done"
+			}
+			return column + " / Doing"
+		}
+	}
+	return column
+}
+
+// boardTable lists the cards one per row with their column, half and lane.
+func boardTable(view boardView, rowField string) output.Table {
+	table := output.Table{Headers: []string{"Column", "Done", "Lane", "ID", "Title", "State", "AssignedTo"}}
+	add := func(column string, done bool, items []output.WorkItem) {
+		for _, item := range items {
+			lane := ""
+			if rowField != "" {
+				lane, _ = item.Fields[rowField].(string)
+			}
+			table.Rows = append(table.Rows, []string{column, strconv.FormatBool(done), lane, strconv.Itoa(item.ID),
+				stringValue(item.Title), stringValue(item.State), stringValue(item.AssignedTo)})
+		}
+	}
+	for _, column := range view.Columns {
+		add(column.Name, false, column.Items)
+		add(column.Name, true, column.Done)
+	}
+	return table
+}
+
+// printBoard prints the columns top to bottom, once per swimlane when the
+// board has more than one.
+func printBoard(w io.Writer, view boardView, rowField string) {
+	fmt.Fprintf(w, "Board: %s\n", view.Board)
+	lanes := view.Lanes
+	if len(lanes) <= 1 {
+		lanes = []string{""}
+		rowField = ""
+	}
+	inLane := func(items []output.WorkItem, lane string) []output.WorkItem {
+		if rowField == "" {
+			return items
+		}
+		matched := []output.WorkItem{}
+		for _, item := range items {
+			if value, _ := item.Fields[rowField].(string); strings.EqualFold(value, lane) {
+				matched = append(matched, item)
+			}
+		}
+		return matched
+	}
+	printCards := func(indent string, items []output.WorkItem) {
+		for _, item := range items {
+			line := fmt.Sprintf("%s%d  %s", indent, item.ID, stringValue(item.Title))
+			if assigned := stringValue(item.AssignedTo); assigned != "" {
+				line += "  @" + assigned
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+	for _, lane := range lanes {
+		if rowField != "" {
+			fmt.Fprintf(w, "\n=== %s ===\n", laneName(lane))
+		}
+		for _, column := range view.Columns {
+			doing, done := inLane(column.Items, lane), inLane(column.Done, lane)
+			count := strconv.Itoa(len(doing) + len(done))
+			if column.ItemLimit > 0 {
+				count += "/" + strconv.Itoa(column.ItemLimit)
+			}
+			fmt.Fprintf(w, "\n%s (%s)\n", column.Name, count)
+			if !column.IsSplit {
+				printCards("  ", doing)
+				continue
+			}
+			fmt.Fprintln(w, "  Doing")
+			printCards("    ", doing)
+			fmt.Fprintln(w, "  Done")
+			printCards("    ", done)
+		}
+	}
+}
+
+func boardValueFlags() map[string]bool {
+	flags := wiqlValueFlags()
+	flags["team"] = true
+	flags["board"] = true
+	flags["column"] = true
+	flags["lane"] = true
+	flags["done"] = false
+	return flags
+}
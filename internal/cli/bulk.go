@@ -0,0 +1,399 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+type bulkResult struct {
+	ID        int                 `json:"id"`
+	OK        bool                `json:"ok"`
+	Rev       int                 `json:"rev,omitempty"`
+	Unchanged bool                `json:"unchanged,omitempty"`
+	Error     *output.ErrorDetail `json:"error,omitempty"`
+}
+
+type bulkSummary struct {
+	Total     int          `json:"total"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []bulkResult `json:"results"`
+}
+
+type bulkPreviewChange struct {
+	Field   string      `json:"field"`
+	Current interface{} `json:"current"`
+	New     interface{} `json:"new"`
+}
+
+type bulkPreviewItem struct {
+	ID      int                      `json:"id"`
+	Title   string                   `json:"title"`
+	Changes []bulkPreviewChange      `json:"changes"`
+	Patch   []map[string]interface{} `json:"-"`
+}
+
+func runBulk(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "bulk subcommand is required", nil), true)
+		return 1
+	}
+	switch args[0] {
+	case "update":
+		return runBulkUpdate(args[1:], stdout, stderr)
+	default:
+		output.WriteError(stderr, errs.New("unknown_command", "unknown bulk subcommand", args[0]), true)
+		return 1
+	}
+}
+
+func runBulkUpdate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("bulk update", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	wiql := fs.String("wiql", "", "WIQL query selecting the work items to update")
+	sets := stringSliceFlag{}
+	fs.Var(&sets, "set", "Field=Value (repeatable)")
+	comment := fs.String("add-comment", "", "Add a Markdown comment to System.History on every item")
+	parent := fs.Int("parent", 0, "Move every item under this parent work item")
+	parentRel := fs.String("parent-rel", parentRelation, "Parent relation type")
+	var top int
+	fs.IntVar(&top, "top", 0, "Maximum number of matched items")
+	dryRun := fs.Bool("dry-run", false, "Preview the changes without updating anything")
+	noValidate := fs.Bool("no-validate", false, "Send values as given without checking them against the field definitions")
+	yes := fs.Bool("yes", false, "Confirm updating every matched item")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if strings.TrimSpace(*wiql) == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "--wiql is required", nil), flags.json)
+		return 1
+	}
+	if len(sets.values) == 0 && *comment == "" && *parent == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "at least one of --set, --add-comment, or --parent is required", nil), flags.json)
+		return 1
+	}
+	if *parent < 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "parent must be a positive work item id", *parent), flags.json)
+		return 1
+	}
+	ctx, err := buildContext(flags, stdout, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	if ctx.project == "" {
+		output.WriteError(stderr, errs.New("config_missing", "project is required", nil), flags.json)
+		return 1
+	}
+	patch, err := buildPatch(aliasAssignments(ctx.cfg.FieldAliases, sets.values), *comment)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	applyValueAliases(ctx.cfg, patch)
+	client, err := api.NewClient(ctx.baseURL, ctx.project, ctx.pat, ctx.insecure, ctx.verbose, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	resp, err := client.Wiql(context.Background(), *wiql, top, time.Time{})
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	ids := collectIDs(resp)
+	var typed map[int][]map[string]interface{}
+	if !*noValidate && len(patch) > 0 && len(ids) > 0 {
+		typed, err = validateBulkPatch(context.Background(), client, newFieldValidator(client), ids, patch)
+		if err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			return 1
+		}
+	}
+
+	var items []bulkPreviewItem
+	if *dryRun || *parent > 0 {
+		current, err := fetchWorkItemsWithRelations(context.Background(), client, ids)
+		if err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			return 1
+		}
+		items = planBulkUpdate(ids, current, patch, typed, *parent, *parentRel, client.WorkItemURL(*parent))
+	} else {
+		items = planBulkUpdate(ids, nil, patch, typed, 0, "", "")
+	}
+
+	if *dryRun {
+		return renderBulkPreview(ctx, items, patch)
+	}
+	if len(ids) == 0 {
+		return renderBulkSummary(ctx, bulkSummary{Results: []bulkResult{}})
+	}
+	if !*yes {
+		output.WriteError(stderr, errs.New("confirmation_required",
+			fmt.Sprintf("%d work items will be updated; review with --dry-run and use --yes to proceed", len(ids)),
+			map[string]interface{}{"count": len(ids), "ids": ids}), ctx.jsonMode)
+		return 1
+	}
+
+	summary := applyBulkPatch(context.Background(), items, client.BatchWorkItems, stderr)
+	return renderBulkSummary(ctx, summary)
+}
+
+// validateBulkPatch checks patch against every work item type among ids and
+// returns each item's patch converted for its type. Items of the same type
+// share one converted patch.
+func validateBulkPatch(ctx context.Context, client *api.Client, validator *fieldValidator, ids []int, patch []map[string]interface{}) (map[int][]map[string]interface{}, error) {
+	itemTypes := make(map[int]string, len(ids))
+	for i := 0; i < len(ids); i += maxBatchSize {
+		end := i + maxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		items, err := client.GetWorkItemsBatch(ctx, ids[i:end], []string{"System.WorkItemType"}, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			itemTypes[item.ID] = workItemType(item)
+		}
+	}
+	byType := map[string][]map[string]interface{}{}
+	for _, id := range ids {
+		name, ok := itemTypes[id]
+		if !ok {
+			continue
+		}
+		if _, done := byType[name]; done {
+			continue
+		}
+		converted := clonePatch(patch)
+		if err := validator.apply(ctx, name, converted); err != nil {
+			return nil, err
+		}
+		byType[name] = converted
+	}
+	typed := make(map[int][]map[string]interface{}, len(itemTypes))
+	for id, name := range itemTypes {
+		typed[id] = byType[name]
+	}
+	return typed, nil
+}
+
+func clonePatch(patch []map[string]interface{}) []map[string]interface{} {
+	cloned := make([]map[string]interface{}, 0, len(patch))
+	for _, op := range patch {
+		copied := make(map[string]interface{}, len(op))
+		for key, value := range op {
+			copied[key] = value
+		}
+		cloned = append(cloned, copied)
+	}
+	return cloned
+}
+
+func fetchWorkItemsWithRelations(ctx context.Context, client *api.Client, ids []int) (map[int]api.WorkItem, error) {
+	byID := make(map[int]api.WorkItem, len(ids))
+	for i := 0; i < len(ids); i += maxBatchSize {
+		end := i + maxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		items, err := client.GetWorkItemsBatchExpanded(ctx, ids[i:end], "relations")
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			byID[item.ID] = item
+		}
+	}
+	return byID, nil
+}
+
+// planBulkUpdate builds the per-item patch and preview. typed holds patches
+// converted for an item's type and replaces patch for those items. current
+// is only needed for previews and reparenting; parentID 0 leaves parents
+// alone.
+func planBulkUpdate(ids []int, current map[int]api.WorkItem, patch []map[string]interface{}, typed map[int][]map[string]interface{}, parentID int, parentRel, parentURL string) []bulkPreviewItem {
+	items := make([]bulkPreviewItem, 0, len(ids))
+	for _, id := range ids {
+		wi := current[id]
+		item := bulkPreviewItem{ID: id, Changes: []bulkPreviewChange{}}
+		item.Title, _ = wi.Fields["System.Title"].(string)
+		itemPatch := patch
+		if converted, ok := typed[id]; ok {
+			itemPatch = converted
+		}
+		item.Patch = append(item.Patch, itemPatch...)
+		for _, op := range itemPatch {
+			path, _ := op["path"].(string)
+			field := strings.TrimPrefix(path, "/fields/")
+			if field == path {
+				continue
+			}
+			item.Changes = append(item.Changes, bulkPreviewChange{
+				Field:   field,
+				Current: wi.Fields[field],
+				New:     op["value"],
+			})
+		}
+		if parentID > 0 {
+			parentPatch, currentParent := reparentPatch(wi.Relations, parentID, parentRel, parentURL)
+			if parentPatch != nil {
+				item.Patch = append(item.Patch, parentPatch...)
+				if wi.Rev > 0 {
+					// Relations are removed by index; test the revision
+					// they were read from.
+					item.Patch = withRevisionTest(item.Patch, wi.Rev)
+				}
+				var from interface{}
+				if currentParent > 0 {
+					from = currentParent
+				}
+				item.Changes = append(item.Changes, bulkPreviewChange{Field: "Parent", Current: from, New: parentID})
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// applyBulkPatch sends the planned patches through $batch, one request per
+// api.MaxWorkItemBatchSize items, reporting progress after each request.
+func applyBulkPatch(ctx context.Context, items []bulkPreviewItem, batch func(context.Context, []api.WorkItemBatchOperation) ([]api.WorkItemBatchResult, error), progress io.Writer) bulkSummary {
+	summary := bulkSummary{Total: len(items), Results: make([]bulkResult, 0, len(items))}
+	for start := 0; start < len(items); start += api.MaxWorkItemBatchSize {
+		end := start + api.MaxWorkItemBatchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		ops := []api.WorkItemBatchOperation{}
+		for _, item := range items[start:end] {
+			if len(item.Patch) > 0 {
+				ops = append(ops, api.WorkItemBatchOperation{ID: item.ID, Patch: item.Patch})
+			}
+		}
+		byID := map[int]api.WorkItemBatchResult{}
+		if len(ops) > 0 {
+			results, err := batch(ctx, ops)
+			for index, op := range ops {
+				if err != nil {
+					byID[op.ID] = api.WorkItemBatchResult{ID: op.ID, Err: err}
+				} else {
+					byID[op.ID] = results[index]
+				}
+			}
+		}
+		failed := 0
+		for _, item := range items[start:end] {
+			result := bulkResult{ID: item.ID}
+			if len(item.Patch) == 0 {
+				result.OK = true
+				result.Unchanged = true
+			} else if batchResult := byID[item.ID]; batchResult.Err != nil {
+				detail := output.NewErrorDetail(batchResult.Err)
+				result.Error = &detail
+				failed++
+			} else {
+				result.OK = true
+				result.Rev = batchResult.WorkItem.Rev
+			}
+			summary.Results = append(summary.Results, result)
+		}
+		summary.Failed += failed
+		summary.Succeeded += end - start - failed
+		if progress != nil {
+			fmt.Fprintf(progress, "[%d/%d] updated %d, failed %d\n", end, len(items), end-start-failed, failed)
+		}
+	}
+	return summary
+}
+
+func renderBulkPreview(ctx commandContext, preview []bulkPreviewItem, patch []map[string]interface{}) int {
+	payload := map[string]interface{}{
+		"dryRun": true,
+		"count":  len(preview),
+		"patch":  patch,
+		"items":  preview,
+	}
+	return renderOutput(ctx, payload, func() output.Table {
+		table := output.Table{Headers: []string{"ID", "Title", "Field", "Current", "New"}}
+		for _, item := range preview {
+			for _, change := range item.Changes {
+				table.Rows = append(table.Rows, []string{strconv.Itoa(item.ID), item.Title, change.Field,
+					output.FormatColumnValue(change.Current), output.FormatColumnValue(change.New)})
+			}
+		}
+		return table
+	}, func() {
+		fmt.Fprintf(ctx.stdout, "Dry run: %d work items would be updated\n", len(preview))
+		if len(preview) == 0 {
+			return
+		}
+		tw := tabwriter.NewWriter(ctx.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTITLE\tFIELD\tCURRENT\tNEW")
+		for _, item := range preview {
+			for index, change := range item.Changes {
+				id, title := fmt.Sprint(item.ID), item.Title
+				if index > 0 {
+					id, title = "", ""
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", id, title, change.Field, formatHistoryValue(change.Current), formatHistoryValue(change.New))
+			}
+		}
+		_ = tw.Flush()
+	})
+}
+
+func renderBulkSummary(ctx commandContext, summary bulkSummary) int {
+	code := renderOutput(ctx, summary, func() output.Table {
+		table := output.Table{Headers: []string{"ID", "Status", "Rev", "Error"}}
+		for _, result := range summary.Results {
+			status, message := bulkResultStatus(result)
+			rev := ""
+			if result.Rev > 0 {
+				rev = strconv.Itoa(result.Rev)
+			}
+			table.Rows = append(table.Rows, []string{strconv.Itoa(result.ID), status, rev, message})
+		}
+		return table
+	}, func() {
+		if len(summary.Results) > 0 {
+			tw := tabwriter.NewWriter(ctx.stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tSTATUS\tERROR")
+			for _, result := range summary.Results {
+				status, message := bulkResultStatus(result)
+				fmt.Fprintf(tw, "%d\t%s\t%s\n", result.ID, status, message)
+			}
+			_ = tw.Flush()
+		}
+		fmt.Fprintf(ctx.stdout, "Updated %d of %d work items (%d failed)\n", summary.Succeeded, summary.Total, summary.Failed)
+	})
+	if code == 0 && summary.Failed > 0 {
+		return 1
+	}
+	return code
+}
+
+func bulkResultStatus(result bulkResult) (status, message string) {
+	switch {
+	case result.Unchanged:
+		return "unchanged", ""
+	case !result.OK:
+		return "failed", result.Error.Message
+	default:
+		return "ok", ""
+	}
+}
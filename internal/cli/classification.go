@@ -0,0 +1,531 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+// classificationGroup describes one of the two classification trees: the
+// command that manages it, the API structure group and the work item field
+// that refers to its nodes.
+type classificationGroup struct {
+	command string
+	group   string
+	field   string
+	noun    string
+	dates   bool
+}
+
+var (
+	areaGroup      = classificationGroup{command: "areas", group: api.AreaNodes, field: "System.AreaPath", noun: "area"}
+	iterationGroup = classificationGroup{command: "iterations", group: api.IterationNodes, field: "System.IterationPath", noun: "iteration", dates: true}
+)
+
+// classificationGroupOf returns the group whose nodes field refers to.
+func classificationGroupOf(field string) (classificationGroup, bool) {
+	for _, g := range []classificationGroup{areaGroup, iterationGroup} {
+		if strings.EqualFold(g.field, field) {
+			return g, true
+		}
+	}
+	return classificationGroup{}, false
+}
+
+// classificationEntry is an area or iteration as printed: Path is the value
+// System.AreaPath or System.IterationPath takes for it.
+type classificationEntry struct {
+	ID          int                   `json:"id"`
+	Name        string                `json:"name"`
+	Path        string                `json:"path"`
+	StartDate   string                `json:"startDate,omitempty"`
+	FinishDate  string                `json:"finishDate,omitempty"`
+	HasChildren bool                  `json:"hasChildren,omitempty"`
+	Children    []classificationEntry `json:"children,omitempty"`
+}
+
+func runAreas(args []string, stdout, stderr io.Writer) int {
+	return runClassification(areaGroup, args, stdout, stderr)
+}
+
+func runIterations(args []string, stdout, stderr io.Writer) int {
+	return runClassification(iterationGroup, args, stdout, stderr)
+}
+
+func runClassification(g classificationGroup, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", g.command+" subcommand is required", nil), true)
+		return 1
+	}
+	switch args[0] {
+	case "list":
+		return runClassificationList(g, false, args[1:], stdout, stderr)
+	case "tree":
+		return runClassificationList(g, true, args[1:], stdout, stderr)
+	case "create":
+		return runClassificationCreate(g, args[1:], stdout, stderr)
+	case "rename":
+		return runClassificationRename(g, args[1:], stdout, stderr)
+	case "dates":
+		if g.dates {
+			return runClassificationDates(g, args[1:], stdout, stderr)
+		}
+	case "move":
+		return runClassificationMove(g, args[1:], stdout, stderr)
+	case "delete":
+		return runClassificationDelete(g, args[1:], stdout, stderr)
+	}
+	output.WriteError(stderr, errs.New("unknown_command", "unknown "+g.command+" subcommand", args[0]), true)
+	return 1
+}
+
+func runClassificationList(g classificationGroup, tree bool, args []string, stdout, stderr io.Writer) int {
+	name := g.command + " list"
+	if tree {
+		name = g.command + " tree"
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	depth := fs.Int("depth", 10, "Levels below the starting node to include")
+	pathArg, rest := splitPositional(args, classificationValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if *depth < 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "depth must not be negative", *depth), flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	node, err := client.GetClassificationNode(context.Background(), g.group, nodeRelativePath(ctx.project, pathArg), *depth)
+	if err != nil {
+		output.WriteError(stderr, explainNodeNotFound(g, pathArg, err), ctx.jsonMode)
+		return 1
+	}
+	root := classificationEntryOf(node, "")
+	entries := flattenClassification(root)
+	table := func() output.Table {
+		return classificationTable(g, entries)
+	}
+	if tree {
+		return renderOutput(ctx, root, table, func() {
+			printClassificationTree(stdout, g, root, 0)
+		})
+	}
+	return renderOutput(ctx, entries, table, func() {
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		if g.dates {
+			fmt.Fprintln(tw, "PATH\tID\tSTART\tFINISH")
+		} else {
+			fmt.Fprintln(tw, "PATH\tID")
+		}
+		for _, entry := range entries {
+			if g.dates {
+				fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", entry.Path, entry.ID, shortDate(entry.StartDate), shortDate(entry.FinishDate))
+			} else {
+				fmt.Fprintf(tw, "%s\t%d\n", entry.Path, entry.ID)
+			}
+		}
+		_ = tw.Flush()
+	})
+}
+
+func runClassificationCreate(g classificationGroup, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(g.command+" create", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	parents := fs.Bool("parents", false, "Create missing parent nodes too")
+	start, finish := stringFlag{}, stringFlag{}
+	if g.dates {
+		fs.Var(&start, "start", "Start date (YYYY-MM-DD)")
+		fs.Var(&finish, "finish", "Finish date (YYYY-MM-DD)")
+	}
+	pathArg, rest := splitPositional(args, classificationValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	attributes, err := iterationAttributes(start, finish)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	relative := nodeRelativePath(ctx.project, pathArg)
+	if relative == "" {
+		output.WriteError(stderr, errs.New("invalid_args", g.noun+" path is required", nil), ctx.jsonMode)
+		return 1
+	}
+	parentPath, name := splitNodePath(relative)
+	reqCtx := context.Background()
+	if *parents && parentPath != "" {
+		if err := ensureClassificationPath(reqCtx, client, g, parentPath); err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			return 1
+		}
+	}
+	created, err := client.CreateClassificationNode(reqCtx, g.group, parentPath, api.ClassificationNode{Name: name, Attributes: attributes})
+	if err != nil {
+		output.WriteError(stderr, explainNodeNotFound(g, parentPath, err), ctx.jsonMode)
+		return 1
+	}
+	return renderClassificationChange(ctx, g, "Created", classificationEntryOf(created, nodeFieldPath(ctx.project, relative)))
+}
+
+func runClassificationRename(g classificationGroup, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(g.command+" rename", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	name := fs.String("name", "", "New name")
+	pathArg, rest := splitPositional(args, classificationValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if strings.TrimSpace(*name) == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "--name is required", nil), flags.json)
+		return 1
+	}
+	return updateClassificationNode(g, flags, pathArg, api.ClassificationNode{Name: strings.TrimSpace(*name)}, "Renamed", stdout, stderr)
+}
+
+func runClassificationDates(g classificationGroup, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(g.command+" dates", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	start, finish := stringFlag{}, stringFlag{}
+	fs.Var(&start, "start", "Start date (YYYY-MM-DD)")
+	fs.Var(&finish, "finish", "Finish date (YYYY-MM-DD)")
+	pathArg, rest := splitPositional(args, classificationValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	attributes, err := iterationAttributes(start, finish)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	if attributes == nil {
+		output.WriteError(stderr, errs.New("invalid_args", "--start and --finish are required", nil), flags.json)
+		return 1
+	}
+	return updateClassificationNode(g, flags, pathArg, api.ClassificationNode{Attributes: attributes}, "Updated", stdout, stderr)
+}
+
+func updateClassificationNode(g classificationGroup, flags globalFlags, pathArg string, change api.ClassificationNode, verb string, stdout, stderr io.Writer) int {
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	relative := nodeRelativePath(ctx.project, pathArg)
+	if relative == "" {
+		output.WriteError(stderr, errs.New("invalid_args", g.noun+" path is required; the root cannot be changed", nil), ctx.jsonMode)
+		return 1
+	}
+	updated, err := client.UpdateClassificationNode(context.Background(), g.group, relative, change)
+	if err != nil {
+		output.WriteError(stderr, explainNodeNotFound(g, pathArg, err), ctx.jsonMode)
+		return 1
+	}
+	parentPath, _ := splitNodePath(relative)
+	return renderClassificationChange(ctx, g, verb, classificationEntryOf(updated, nodeFieldPath(ctx.project, joinNodePath(parentPath, updated.Name))))
+}
+
+func runClassificationMove(g classificationGroup, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(g.command+" move", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	to := stringFlag{}
+	fs.Var(&to, "to", "New parent path (the root when empty)")
+	pathArg, rest := splitPositional(args, classificationValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if !to.set {
+		output.WriteError(stderr, errs.New("invalid_args", "--to is required", nil), flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	relative := nodeRelativePath(ctx.project, pathArg)
+	if relative == "" {
+		output.WriteError(stderr, errs.New("invalid_args", g.noun+" path is required; the root cannot be moved", nil), ctx.jsonMode)
+		return 1
+	}
+	target := nodeRelativePath(ctx.project, to.value)
+	if target == relative || strings.HasPrefix(strings.ToLower(target)+"/", strings.ToLower(relative)+"/") {
+		output.WriteError(stderr, errs.New("invalid_args", "cannot move a node under itself", to.value), ctx.jsonMode)
+		return 1
+	}
+	reqCtx := context.Background()
+	node, err := client.GetClassificationNode(reqCtx, g.group, relative, 0)
+	if err != nil {
+		output.WriteError(stderr, explainNodeNotFound(g, pathArg, err), ctx.jsonMode)
+		return 1
+	}
+	moved, err := client.CreateClassificationNode(reqCtx, g.group, target, api.ClassificationNode{ID: node.ID})
+	if err != nil {
+		output.WriteError(stderr, explainNodeNotFound(g, to.value, err), ctx.jsonMode)
+		return 1
+	}
+	return renderClassificationChange(ctx, g, "Moved", classificationEntryOf(moved, nodeFieldPath(ctx.project, joinNodePath(target, moved.Name))))
+}
+
+func runClassificationDelete(g classificationGroup, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(g.command+" delete", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	reclassify := stringFlag{}
+	fs.Var(&reclassify, "reclassify", "Move work items of the deleted nodes here (default: the parent)")
+	yes := fs.Bool("yes", false, "Confirm deletion")
+	pathArg, rest := splitPositional(args, classificationValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if !*yes {
+		output.WriteError(stderr, errs.New("confirmation_required", "delete removes the node and its children; use --yes to proceed", pathArg), flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	relative := nodeRelativePath(ctx.project, pathArg)
+	if relative == "" {
+		output.WriteError(stderr, errs.New("invalid_args", g.noun+" path is required; the root cannot be deleted", nil), ctx.jsonMode)
+		return 1
+	}
+	target, _ := splitNodePath(relative)
+	if reclassify.set {
+		target = nodeRelativePath(ctx.project, reclassify.value)
+	}
+	if target == relative || strings.HasPrefix(strings.ToLower(target)+"/", strings.ToLower(relative)+"/") {
+		output.WriteError(stderr, errs.New("invalid_args", "work items cannot be reclassified into a node being deleted", reclassify.value), ctx.jsonMode)
+		return 1
+	}
+	reqCtx := context.Background()
+	targetNode, err := client.GetClassificationNode(reqCtx, g.group, target, 0)
+	if err != nil {
+		output.WriteError(stderr, explainNodeNotFound(g, nodeFieldPath(ctx.project, target), err), ctx.jsonMode)
+		return 1
+	}
+	if err := client.DeleteClassificationNode(reqCtx, g.group, relative, targetNode.ID); err != nil {
+		output.WriteError(stderr, explainNodeNotFound(g, pathArg, err), ctx.jsonMode)
+		return 1
+	}
+	deleted := nodeFieldPath(ctx.project, relative)
+	reclassified := nodeFieldPath(ctx.project, target)
+	return renderOutput(ctx, map[string]interface{}{"deleted": deleted, "reclassifiedTo": reclassified}, func() output.Table {
+		return output.RecordTable([]string{"Deleted", "ReclassifiedTo"}, deleted, reclassified)
+	}, func() {
+		fmt.Fprintf(stdout, "Deleted %s; its work items now use %s\n", deleted, reclassified)
+	})
+}
+
+// ensureClassificationPath creates the missing nodes of relative, parents
+// first.
+func ensureClassificationPath(ctx context.Context, client *api.Client, g classificationGroup, relative string) error {
+	current := ""
+	for _, name := range strings.Split(relative, "/") {
+		next := joinNodePath(current, name)
+		_, err := client.GetClassificationNode(ctx, g.group, next, 0)
+		if api.IsStatus(err, http.StatusNotFound) {
+			_, err = client.CreateClassificationNode(ctx, g.group, current, api.ClassificationNode{Name: name})
+		}
+		if err != nil {
+			return err
+		}
+		current = next
+	}
+	return nil
+}
+
+// iterationAttributes parses --start and --finish. Iteration dates have no
+// time of day, so they are sent as midnight UTC the way the web UI does.
+func iterationAttributes(start, finish stringFlag) (*api.ClassificationNodeAttributes, error) {
+	if !start.set && !finish.set {
+		return nil, nil
+	}
+	if !start.set || !finish.set {
+		return nil, errs.New("invalid_args", "--start and --finish must be given together", nil)
+	}
+	attributes := &api.ClassificationNodeAttributes{}
+	for _, date := range []struct {
+		name  string
+		value string
+		into  *string
+	}{{"start", start.value, &attributes.StartDate}, {"finish", finish.value, &attributes.FinishDate}} {
+		parsed, ok := parseFieldDate(strings.TrimSpace(date.value))
+		if !ok {
+			return nil, errs.New("invalid_args", fmt.Sprintf("invalid --%s date, expected YYYY-MM-DD", date.name), date.value)
+		}
+		*date.into = parsed.Format("2006-01-02") + "T00:00:00Z"
+	}
+	if attributes.FinishDate < attributes.StartDate {
+		return nil, errs.New("invalid_args", "--finish must not be before --start", map[string]string{"start": start.value, "finish": finish.value})
+	}
+	return attributes, nil
+}
+
+// nodeRelativePath turns a path given on the command line, with / or \
+// separators and with or without the leading project name, into a path
+// relative to the root of the tree, separated by /.
+func nodeRelativePath(project, path string) string {
+	segments := []string{}
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) > 0 && strings.EqualFold(segments[0], project) {
+		segments = segments[1:]
+	}
+	return strings.Join(segments, "/")
+}
+
+// nodeFieldPath is the System.AreaPath or System.IterationPath value of the
+// node at relative.
+func nodeFieldPath(project, relative string) string {
+	if relative == "" {
+		return project
+	}
+	return project + `\` + strings.ReplaceAll(relative, "/", `\`)
+}
+
+func splitNodePath(relative string) (string, string) {
+	index := strings.LastIndex(relative, "/")
+	if index < 0 {
+		return "", relative
+	}
+	return relative[:index], relative[index+1:]
+}
+
+func joinNodePath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "/" + name
+}
+
+// classificationFieldPath derives the field value of a node from its server
+// path by dropping the structure name, e.g. \Project\Iteration\Sprint 1
+// becomes Project\Sprint 1. fallback is used when the server sent no path.
+func classificationFieldPath(node api.ClassificationNode, fallback string) string {
+	segments := strings.Split(strings.Trim(node.Path, `\`), `\`)
+	if node.Path == "" || len(segments) < 2 {
+		if fallback != "" {
+			return fallback
+		}
+		return node.Name
+	}
+	return strings.Join(append(segments[:1:1], segments[2:]...), `\`)
+}
+
+func classificationEntryOf(node api.ClassificationNode, fallback string) classificationEntry {
+	entry := classificationEntry{ID: node.ID, Name: node.Name, Path: classificationFieldPath(node, fallback), HasChildren: node.HasChildren}
+	if node.Attributes != nil {
+		entry.StartDate = node.Attributes.StartDate
+		entry.FinishDate = node.Attributes.FinishDate
+	}
+	for _, child := range node.Children {
+		entry.Children = append(entry.Children, classificationEntryOf(child, entry.Path+`\`+child.Name))
+	}
+	return entry
+}
+
+// flattenClassification lists root and its descendants depth-first, without
+// their children.
+func flattenClassification(root classificationEntry) []classificationEntry {
+	children := root.Children
+	root.Children = nil
+	entries := []classificationEntry{root}
+	for _, child := range children {
+		entries = append(entries, flattenClassification(child)...)
+	}
+	return entries
+}
+
+func printClassificationTree(w io.Writer, g classificationGroup, entry classificationEntry, depth int) {
+	line := strings.Repeat("  ", depth) + entry.Name
+	if g.dates && entry.StartDate != "" {
+		line += fmt.Sprintf("  (%s - %s)", shortDate(entry.StartDate), shortDate(entry.FinishDate))
+	}
+	fmt.Fprintln(w, line)
+	for _, child := range entry.Children {
+		printClassificationTree(w, g, child, depth+1)
+	}
+}
+
+func renderClassificationChange(ctx commandContext, g classificationGroup, verb string, entry classificationEntry) int {
+	return renderOutput(ctx, entry, func() output.Table {
+		return classificationTable(g, []classificationEntry{entry})
+	}, func() {
+		fmt.Fprintf(ctx.stdout, "%s %s (id %d)\n", verb, entry.Path, entry.ID)
+	})
+}
+
+// classificationTable has one row per node; iterations add their dates.
+func classificationTable(g classificationGroup, entries []classificationEntry) output.Table {
+	table := output.Table{Headers: []string{"Path", "Name", "ID"}}
+	if g.dates {
+		table.Headers = append(table.Headers, "StartDate", "FinishDate")
+	}
+	for _, entry := range entries {
+		row := []string{entry.Path, entry.Name, strconv.Itoa(entry.ID)}
+		if g.dates {
+			row = append(row, shortDate(entry.StartDate), shortDate(entry.FinishDate))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// explainNodeNotFound turns a 404 for a classification node into a
+// not_found error naming the missing node.
+func explainNodeNotFound(g classificationGroup, path string, err error) error {
+	if api.IsStatus(err, http.StatusNotFound) {
+		return errs.New("not_found", fmt.Sprintf("%s %q does not exist", g.noun, path), err.(errs.AppError).Details)
+	}
+	return err
+}
+
+func shortDate(value string) string {
+	if len(value) >= len("2006-01-02") {
+		return value[:len("2006-01-02")]
+	}
+	return value
+}
+
+func classificationValueFlags() map[string]bool {
+	flags := wiqlValueFlags()
+	flags["depth"] = true
+	flags["start"] = true
+	flags["finish"] = true
+	flags["name"] = true
+	flags["to"] = true
+	flags["reclassify"] = true
+	flags["parents"] = false
+	flags["yes"] = false
+	return flags
+}
@@ -10,11 +10,13 @@ import (
 	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"tfs-cli/internal/api"
 	"tfs-cli/internal/config"
 	"tfs-cli/internal/diff"
 	"tfs-cli/internal/errs"
+	"tfs-cli/internal/jmes"
 	"tfs-cli/internal/output"
 )
 
@@ -54,6 +56,8 @@ type globalFlags struct {
 	baseURL  stringFlag
 	project  stringFlag
 	pat      stringFlag
+	format   stringFlag
+	query    stringFlag
 	json     bool
 	verbose  bool
 	insecure bool
@@ -62,6 +66,9 @@ type globalFlags struct {
 type commandContext struct {
 	cfg      config.Config
 	jsonMode bool
+	format   output.Format
+	query    *jmes.Expression
+	richText string
 	verbose  bool
 	insecure bool
 	stdout   io.Writer
@@ -87,6 +94,10 @@ func Run(args []string, stdout, stderr io.Writer) int {
 		return runView(args[1:], stdout, stderr)
 	case "update":
 		return runUpdate(args[1:], stdout, stderr)
+	case "edit":
+		return runEdit(args[1:], stdout, stderr)
+	case "move":
+		return runMove(args[1:], stdout, stderr)
 	case "create":
 		return runCreate(args[1:], stdout, stderr)
 	case "delete":
@@ -97,12 +108,38 @@ func Run(args []string, stdout, stderr io.Writer) int {
 		return runMy(args[1:], stdout, stderr)
 	case "show":
 		return runShow(args[1:], stdout, stderr)
+	case "history":
+		return runHistory(args[1:], stdout, stderr)
+	case "attach":
+		return runAttach(args[1:], stdout, stderr)
+	case "link":
+		return runLink(args[1:], stdout, stderr)
+	case "bulk":
+		return runBulk(args[1:], stdout, stderr)
+	case "query":
+		return runQuery(args[1:], stdout, stderr)
 	case "pr":
 		return runPR(args[1:], stdout, stderr)
 	case "wiki":
 		return runWiki(args[1:], stdout, stderr)
 	case "types":
 		return runTypes(args[1:], stdout, stderr)
+	case "sprint":
+		return runSprint(args[1:], stdout, stderr)
+	case "backlog":
+		return runBacklog(args[1:], stdout, stderr)
+	case "board":
+		return runBoard(args[1:], stdout, stderr)
+	case "report":
+		return runReport(args[1:], stdout, stderr)
+	case "diff-snapshot":
+		return runDiffSnapshot(args[1:], stdout, stderr)
+	case "watch":
+		return runWatch(args[1:], stdout, stderr)
+	case "areas":
+		return runAreas(args[1:], stdout, stderr)
+	case "iterations":
+		return runIterations(args[1:], stdout, stderr)
 	case "whoami":
 		return runWhoami(args[1:], stdout, stderr)
 	case "config":
@@ -189,7 +226,10 @@ func runWiql(args []string, stdout, stderr io.Writer) int {
 	addGlobalFlags(fs, &flags)
 	var top int
 	fs.IntVar(&top, "top", 0, "Maximum number of results")
-	queryArg, rest := splitPositional(args, wiqlValueFlags())
+	depth := fs.Int("depth", 0, "Maximum tree levels to show for tree and one-hop queries (0 = all)")
+	columnsCSV := fs.String("columns", "", "Comma-separated field reference names or aliases to show")
+	asOfArg := fs.String("asof", "", "Run the query against the work items as they were at this date or time (local time)")
+	queryArg, rest := splitPositional(args, wiqlAsOfValueFlags())
 	if err := fs.Parse(rest); err != nil {
 		return 1
 	}
@@ -197,6 +237,24 @@ func runWiql(args []string, stdout, stderr io.Writer) int {
 		output.WriteError(stderr, errs.New("invalid_args", "WIQL query is required", nil), flags.json)
 		return 1
 	}
+	asOf, err := parseAsOf("asof", *asOfArg)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	if !asOf.IsZero() && api.HasAsOfClause(queryArg) {
+		output.WriteError(stderr, errs.New("invalid_args", "the query has its own ASOF clause; remove it to use --asof", nil), flags.json)
+		return 1
+	}
+	if *depth < 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "depth must not be negative", *depth), flags.json)
+		return 1
+	}
+	columns, err := parseColumns(*columnsCSV)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
 	ctx, err := buildContext(flags, stdout, stderr)
 	if err != nil {
 		output.WriteError(stderr, err, flags.json)
@@ -212,32 +270,32 @@ func runWiql(args []string, stdout, stderr io.Writer) int {
 		output.WriteError(stderr, err, ctx.jsonMode)
 		return 1
 	}
-	resp, err := client.Wiql(context.Background(), query, top)
+	resp, err := client.Wiql(context.Background(), query, top, asOf)
 	if err != nil {
 		output.WriteError(stderr, err, ctx.jsonMode)
 		return 1
 	}
-	ids := collectIDs(resp)
-	items, err := fetchWorkItems(context.Background(), client, ids)
-	if err != nil {
-		output.WriteError(stderr, err, ctx.jsonMode)
-		return 1
-	}
-	return renderList(ctx, items)
+	return renderQueryResult(ctx, client, resp, asOf, *depth, columns)
 }
 
 func runSearch(args []string, stdout, stderr io.Writer) int {
 	fs := flag.NewFlagSet("search", flag.ContinueOnError)
 	fs.SetOutput(stderr)
+	queryFlag := fs.String("query", "", "Search text")
 	flags := globalFlags{}
 	addGlobalFlags(fs, &flags)
 	var top int
-	queryFlag := fs.String("query", "", "Search text")
 	fs.IntVar(&top, "top", 0, "Maximum number of results")
+	columnsCSV := fs.String("columns", "", "Comma-separated field reference names or aliases to show")
 	queryArg, rest := splitPositional(args, searchValueFlags())
 	if err := fs.Parse(rest); err != nil {
 		return 1
 	}
+	columns, err := parseColumns(*columnsCSV)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
 	query := *queryFlag
 	if query == "" && queryArg != "" {
 		query = queryArg
@@ -261,18 +319,18 @@ func runSearch(args []string, stdout, stderr io.Writer) int {
 		output.WriteError(stderr, err, ctx.jsonMode)
 		return 1
 	}
-	resp, err := client.Wiql(context.Background(), query, top)
+	resp, err := client.Wiql(context.Background(), query, top, time.Time{})
 	if err != nil {
 		output.WriteError(stderr, err, ctx.jsonMode)
 		return 1
 	}
 	ids := collectIDs(resp)
-	items, err := fetchWorkItems(context.Background(), client, ids)
+	items, err := fetchWorkItems(context.Background(), client, ids, columnFields(columns)...)
 	if err != nil {
 		output.WriteError(stderr, err, ctx.jsonMode)
 		return 1
 	}
-	return renderList(ctx, items)
+	return renderColumnList(ctx, items, columns)
 }
 
 func runMy(args []string, stdout, stderr io.Writer) int {
@@ -286,16 +344,22 @@ func runMy(args []string, stdout, stderr io.Writer) int {
 	excludeState := fs.String("exclude-state", "", "Exclude items with this state (overrides default state filter)")
 	allStates := fs.Bool("all-states", false, "Do not filter by state")
 	fs.IntVar(&top, "top", 0, "Maximum number of results")
+	columnsCSV := fs.String("columns", "", "Comma-separated field reference names or aliases to show")
 	jsonExplicit := flagProvided(args, "json")
 	if err := fs.Parse(args); err != nil {
 		return 1
 	}
+	columns, err := parseColumns(*columnsCSV)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
 	ctx, err := buildContext(flags, stdout, stderr)
 	if err != nil {
 		output.WriteError(stderr, err, flags.json)
 		return 1
 	}
-	if !jsonExplicit {
+	if !jsonExplicit && !flags.format.set {
 		ctx.jsonMode = false
 	}
 	if ctx.project == "" {
@@ -311,18 +375,26 @@ func runMy(args []string, stdout, stderr io.Writer) int {
 	if strings.TrimSpace(*typeFilter) != "" {
 		allTypesEffective = false
 	}
-	resp, err := client.Wiql(context.Background(), myWiqlQuery(*typeFilter, allTypesEffective, *excludeState, *allStates), top)
+	var activeStates []string
+	if !*allStates && strings.TrimSpace(*excludeState) == "" {
+		activeStates, err = inProgressStates(context.Background(), client, *typeFilter)
+		if err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			return 1
+		}
+	}
+	resp, err := client.Wiql(context.Background(), myWiqlQuery(*typeFilter, allTypesEffective, *excludeState, *allStates, activeStates), top, time.Time{})
 	if err != nil {
 		output.WriteError(stderr, err, ctx.jsonMode)
 		return 1
 	}
 	ids := collectIDs(resp)
-	items, err := fetchWorkItems(context.Background(), client, ids)
+	items, err := fetchWorkItems(context.Background(), client, ids, columnFields(columns)...)
 	if err != nil {
 		output.WriteError(stderr, err, ctx.jsonMode)
 		return 1
 	}
-	return renderList(ctx, items)
+	return renderColumnList(ctx, items, columns)
 }
 
 func runView(args []string, stdout, stderr io.Writer) int {
@@ -334,10 +406,15 @@ func runView(args []string, stdout, stderr io.Writer) int {
 	var expand string
 	fs.StringVar(&fieldsCSV, "fields", "", "Comma-separated list of fields")
 	fs.StringVar(&expand, "expand", "none", "Expand: none, relations, all")
+	richText := fs.String("rich-text", "", "Rich-text fields as markdown, html, or plain (default: markdown for text, html for JSON)")
 	idArg, rest := splitPositional(args, viewValueFlags())
 	if err := fs.Parse(rest); err != nil {
 		return 1
 	}
+	if _, err := parseRichTextMode(*richText, flags.json); err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
 	if idArg == "" {
 		output.WriteError(stderr, errs.New("invalid_args", "work item id is required", nil), flags.json)
 		return 1
@@ -373,6 +450,7 @@ func runView(args []string, stdout, stderr io.Writer) int {
 		return 1
 	}
 
+	ctx.richText = *richText
 	return renderWorkItem(ctx, wi)
 }
 
@@ -384,10 +462,21 @@ func runShow(args []string, stdout, stderr io.Writer) int {
 	childrenRel := fs.String("children-rel", "System.LinkTypes.Hierarchy-Forward", "Relation type used for children")
 	maxChildren := fs.Int("max-children", 20, "Maximum number of children to show")
 	maxComments := fs.Int("max-comments", 0, "Maximum number of comments to show (0 = all)")
+	rawFieldsList := fs.String("raw-fields", "", "Comma-separated expressions selecting what to keep of the raw payload")
+	richText := fs.String("rich-text", "", "Rich-text fields and comments as markdown, html, or plain (default: markdown for text, html for JSON)")
 	idArg, rest := splitPositional(args, showValueFlags())
 	if err := fs.Parse(rest); err != nil {
 		return 1
 	}
+	if _, err := parseRichTextMode(*richText, flags.json); err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	rawFields, err := parseRawFields(*rawFieldsList)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
 	if idArg == "" {
 		output.WriteError(stderr, errs.New("invalid_args", "work item id is required", nil), flags.json)
 		return 1
@@ -406,9 +495,10 @@ func runShow(args []string, stdout, stderr io.Writer) int {
 		output.WriteError(stderr, err, flags.json)
 		return 1
 	}
-	if !flagProvided(args, "json") {
+	if !flagProvided(args, "json") && !flags.format.set {
 		ctx.jsonMode = false
 	}
+	ctx.richText = *richText
 	if ctx.project == "" {
 		output.WriteError(stderr, errs.New("config_missing", "project is required", nil), flags.json)
 		return 1
@@ -442,6 +532,8 @@ func runShow(args []string, stdout, stderr io.Writer) int {
 		output.WriteError(stderr, err, ctx.jsonMode)
 		return 1
 	}
+	wi = convertWorkItemRichText(wi, ctx.richTextMode())
+	comments = convertCommentsRichText(comments, ctx.richTextMode())
 	childrenIDs := extractRelationIDs(wi.Relations, *childrenRel)
 	if *maxChildren > 0 && len(childrenIDs) > *maxChildren {
 		childrenIDs = childrenIDs[:*maxChildren]
@@ -455,21 +547,30 @@ func runShow(args []string, stdout, stderr io.Writer) int {
 		}
 		children = childItems
 	}
-	if ctx.jsonMode {
-		payload := map[string]interface{}{
-			"workItem": normalized,
-			"comments": comments,
-			"children": children,
-			"raw":      wi,
-		}
-		if err := output.PrintJSON(ctx.stdout, payload); err != nil {
-			output.WriteError(ctx.stderr, err, ctx.jsonMode)
-			return 1
-		}
-		return 0
+	raw, err := projectRaw(rawFields, wi)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
 	}
-	printWorkItemDetails(ctx.stdout, normalized, wi.Fields, comments, children)
-	return 0
+	payload := map[string]interface{}{
+		"workItem": normalized,
+		"comments": comments,
+		"children": children,
+		"raw":      raw,
+	}
+	return renderOutput(ctx, payload, func() output.Table {
+		childIDs := make([]string, 0, len(children))
+		for _, child := range children {
+			childIDs = append(childIDs, strconv.Itoa(child.ID))
+		}
+		return output.RecordTable(
+			[]string{"ID", "Title", "Type", "State", "AssignedTo", "Tags", "Description", "Comments", "Children"},
+			normalized.ID, normalized.Title, normalized.Type, normalized.State, normalized.AssignedTo,
+			normalized.Tags, wi.Fields["System.Description"], len(comments), strings.Join(childIDs, ", "),
+		)
+	}, func() {
+		printWorkItemDetails(ctx.stdout, normalized, wi.Fields, comments, children)
+	})
 }
 
 func runUpdate(args []string, stdout, stderr io.Writer) int {
@@ -483,6 +584,16 @@ func runUpdate(args []string, stdout, stderr io.Writer) int {
 	parent := fs.Int("parent", 0, "Parent work item ID (reparent)")
 	parentRel := fs.String("parent-rel", "System.LinkTypes.Hierarchy-Reverse", "Parent relation type")
 	yes := fs.Bool("yes", false, "Confirm bulk updates (>5 fields)")
+	ifRev := fs.Int("if-rev", 0, "Only update if the work item is still at this revision")
+	unsets := stringSliceFlag{}
+	fs.Var(&unsets, "unset", "Field to clear (repeatable)")
+	appends := stringSliceFlag{}
+	fs.Var(&appends, "append", "Field=Text to append to the current value (repeatable)")
+	addTags := stringSliceFlag{}
+	fs.Var(&addTags, "add-tag", "Tag to add to System.Tags (repeatable)")
+	removeTags := stringSliceFlag{}
+	fs.Var(&removeTags, "remove-tag", "Tag to remove from System.Tags, case-insensitive (repeatable)")
+	noValidate := fs.Bool("no-validate", false, "Send values as given without checking them against the field definitions")
 	idArg, rest := splitPositional(args, updateValueFlags())
 	if err := fs.Parse(rest); err != nil {
 		return 1
@@ -496,11 +607,16 @@ func runUpdate(args []string, stdout, stderr io.Writer) int {
 		output.WriteError(stderr, errs.New("invalid_args", "work item id must be a number", nil), flags.json)
 		return 1
 	}
-	if len(sets.values) == 0 && *comment == "" && *parent == 0 {
-		output.WriteError(stderr, errs.New("invalid_args", "at least one --set, --add-comment, or --parent is required", nil), flags.json)
+	edits := fieldEdits{Unset: unsets.values, Append: appends.values, AddTags: addTags.values, RemoveTags: removeTags.values}
+	if len(sets.values) == 0 && edits.count() == 0 && *comment == "" && *parent == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "at least one --set, --unset, --append, --add-tag, --remove-tag, --add-comment, or --parent is required", nil), flags.json)
 		return 1
 	}
-	if len(sets.values) > 5 && !*yes {
+	if *ifRev < 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "if-rev must be a positive revision", *ifRev), flags.json)
+		return 1
+	}
+	if len(sets.values)+edits.count() > 5 && !*yes {
 		output.WriteError(stderr, errs.New("confirmation_required", "more than 5 fields updated; use --yes to proceed", nil), flags.json)
 		return 1
 	}
@@ -513,6 +629,13 @@ func runUpdate(args []string, stdout, stderr io.Writer) int {
 		output.WriteError(stderr, errs.New("config_missing", "project is required", nil), flags.json)
 		return 1
 	}
+	setValues := aliasAssignments(ctx.cfg.FieldAliases, sets.values)
+	edits.Unset = aliasFieldNames(ctx.cfg.FieldAliases, edits.Unset)
+	edits.Append = aliasAssignments(ctx.cfg.FieldAliases, edits.Append)
+	if err := validateFieldEdits(setValues, edits); err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
 
 	client, err := api.NewClient(ctx.baseURL, ctx.project, ctx.pat, ctx.insecure, ctx.verbose, stderr)
 	if err != nil {
@@ -520,22 +643,63 @@ func runUpdate(args []string, stdout, stderr io.Writer) int {
 		return 1
 	}
 
-	patch, err := buildPatch(sets.values, *comment)
+	patch, err := buildPatch(setValues, *comment)
 	if err != nil {
 		output.WriteError(stderr, err, ctx.jsonMode)
 		return 1
 	}
 
-	parentPatch, err := buildParentPatch(context.Background(), client, id, *parent, *parentRel)
+	// Appends, tag changes and reparenting are computed from the current
+	// item, so the update is tested against the revision that was read.
+	expectedRev := *ifRev
+	current := api.WorkItem{}
+	if *parent != 0 || edits.needsCurrent() {
+		current, err = client.GetWorkItem(context.Background(), id, nil, "relations")
+		if err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			return 1
+		}
+		if expectedRev == 0 {
+			expectedRev = current.Rev
+		}
+	}
+	fieldPatch, err := buildFieldEditPatch(current, edits)
 	if err != nil {
 		output.WriteError(stderr, err, ctx.jsonMode)
 		return 1
 	}
-	patch = append(patch, parentPatch...)
+	patch = append(patch, fieldPatch...)
+	applyValueAliases(ctx.cfg, patch)
+	if *parent != 0 {
+		parentPatch, _ := reparentPatch(current.Relations, *parent, *parentRel, client.WorkItemURL(*parent))
+		patch = append(patch, parentPatch...)
+	}
+	if len(patch) == 0 {
+		// Nothing left to change, e.g. the tags were already as requested.
+		return renderWorkItem(ctx, current)
+	}
+	if !*noValidate {
+		typeName := workItemType(current)
+		if typeName == "" {
+			typed, err := client.GetWorkItem(context.Background(), id, []string{"System.WorkItemType"}, "")
+			if err != nil {
+				output.WriteError(stderr, err, ctx.jsonMode)
+				return 1
+			}
+			typeName = workItemType(typed)
+		}
+		if err := newFieldValidator(client).apply(context.Background(), typeName, patch); err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			return 1
+		}
+	}
+	if expectedRev > 0 {
+		patch = withRevisionTest(patch, expectedRev)
+	}
 
 	wi, err := client.UpdateWorkItem(context.Background(), id, patch)
 	if err != nil {
-		output.WriteError(stderr, err, ctx.jsonMode)
+		output.WriteError(stderr, explainConflict(context.Background(), client, id, expectedRev, err), ctx.jsonMode)
 		return 1
 	}
 	return renderWorkItem(ctx, wi)
@@ -599,10 +763,19 @@ func runCreate(args []string, stdout, stderr io.Writer) int {
 	parentRel := fs.String("parent-rel", "System.LinkTypes.Hierarchy-Reverse", "Parent relation type")
 	sets := stringSliceFlag{}
 	fs.Var(&sets, "set", "Field=Value (repeatable)")
+	from := fs.String("from", "", "Create every item of a YAML/JSON plan file ('-' reads stdin)")
+	statePath := fs.String("state", "", "Record created IDs in this file and skip them when re-run (with --from)")
+	rollback := fs.Bool("rollback", false, "Delete items created by this run if a later item fails (with --from)")
+	noValidate := fs.Bool("no-validate", false, "Send values as given without checking them against the field definitions")
 	if err := fs.Parse(args); err != nil {
 		return 1
 	}
-	if *wiType == "" || *title == "" {
+	if *from != "" {
+		if *wiType != "" || *title != "" || *parent != 0 || len(sets.values) > 0 {
+			output.WriteError(stderr, errs.New("invalid_args", "--from cannot be combined with --type, --title, --parent, or --set", nil), flags.json)
+			return 1
+		}
+	} else if *wiType == "" || *title == "" {
 		output.WriteError(stderr, errs.New("invalid_args", "--type and --title are required", nil), flags.json)
 		return 1
 	}
@@ -615,6 +788,9 @@ func runCreate(args []string, stdout, stderr io.Writer) int {
 		output.WriteError(stderr, errs.New("config_missing", "project is required", nil), flags.json)
 		return 1
 	}
+	if *from != "" {
+		return runCreateFromPlan(ctx, *from, *statePath, *rollback, !*noValidate, os.Stdin)
+	}
 
 	client, err := api.NewClient(ctx.baseURL, ctx.project, ctx.pat, ctx.insecure, ctx.verbose, stderr)
 	if err != nil {
@@ -622,11 +798,18 @@ func runCreate(args []string, stdout, stderr io.Writer) int {
 		return 1
 	}
 
-	patch, err := buildCreatePatch(context.Background(), client, *title, *assigned, sets.values, *parent, *parentRel)
+	patch, err := buildCreatePatch(context.Background(), client, *title, *assigned, aliasAssignments(ctx.cfg.FieldAliases, sets.values), *parent, *parentRel)
 	if err != nil {
 		output.WriteError(stderr, err, ctx.jsonMode)
 		return 1
 	}
+	applyValueAliases(ctx.cfg, patch)
+	if !*noValidate {
+		if err := newFieldValidator(client).apply(context.Background(), *wiType, patch); err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			return 1
+		}
+	}
 	wi, err := client.CreateWorkItem(context.Background(), *wiType, patch)
 	if err != nil {
 		output.WriteError(stderr, err, ctx.jsonMode)
@@ -743,10 +926,16 @@ func runPRShow(args []string, stdout, stderr io.Writer) int {
 	repository := fs.String("repository", "", "Repository name or ID (required when <id> is not a URL)")
 	maxThreads := fs.Int("max-threads", 0, "Maximum number of comment threads to show (0 = all)")
 	gitDiff := fs.Bool("git-diff", false, "Show git diff of pull request changes")
+	rawFieldsList := fs.String("raw-fields", "", "Comma-separated expressions selecting what to keep of the raw payload")
 	arg, rest := splitPositional(args, prShowValueFlags())
 	if err := fs.Parse(rest); err != nil {
 		return 1
 	}
+	rawFields, err := parseRawFields(*rawFieldsList)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
 	if arg == "" {
 		output.WriteError(stderr, errs.New("invalid_args", "pull request URL or ID is required", nil), flags.json)
 		return 1
@@ -851,7 +1040,7 @@ func runPRShow(args []string, stdout, stderr io.Writer) int {
 		}
 	}
 
-	return renderPullRequestDetails(ctx, pr, workItems, threads, fileDiffs, *gitDiff)
+	return renderPullRequestDetails(ctx, pr, workItems, threads, fileDiffs, *gitDiff, rawFields)
 }
 
 func runPRComment(args []string, stdout, stderr io.Writer) int {
@@ -973,7 +1162,7 @@ func runPRComment(args []string, stdout, stderr io.Writer) int {
 
 func renderPullRequestComment(ctx commandContext, thread api.GitPullRequestThread) int {
 	if ctx.jsonMode {
-		if err := output.PrintJSON(ctx.stdout, thread); err != nil {
+		if err := printJSON(ctx, thread); err != nil {
 			output.WriteError(ctx.stderr, err, ctx.jsonMode)
 			return 1
 		}
@@ -1072,7 +1261,7 @@ func runTypes(args []string, stdout, stderr io.Writer) int {
 				"isDisabled":    item.IsDisabled,
 			})
 		}
-		if err := output.PrintJSON(ctx.stdout, payload); err != nil {
+		if err := printJSON(ctx, payload); err != nil {
 			output.WriteError(ctx.stderr, err, ctx.jsonMode)
 			return 1
 		}
@@ -1118,7 +1307,7 @@ func runWhoami(args []string, stdout, stderr io.Writer) int {
 				"assignedTo":  assigned,
 				"source":      "profile",
 			}
-			if err := output.PrintJSON(ctx.stdout, payload); err != nil {
+			if err := printJSON(ctx, payload); err != nil {
 				output.WriteError(ctx.stderr, err, ctx.jsonMode)
 				return 1
 			}
@@ -1154,7 +1343,7 @@ func runWhoami(args []string, stdout, stderr io.Writer) int {
 			"assignedTo": assignedValue,
 			"source":     "headers",
 		}
-		if err := output.PrintJSON(ctx.stdout, payload); err != nil {
+		if err := printJSON(ctx, payload); err != nil {
 			output.WriteError(ctx.stderr, err, ctx.jsonMode)
 			return 1
 		}
@@ -1177,6 +1366,8 @@ func runConfigView(args []string, stdout, stderr io.Writer) int {
 	fs := flag.NewFlagSet("config view", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 	jsonMode := fs.Bool("json", true, "Output JSON (set --json=false for text)")
+	queryExpr := stringFlag{}
+	fs.Var(&queryExpr, "query", "JMESPath expression applied to the JSON output")
 	if err := fs.Parse(args); err != nil {
 		return 1
 	}
@@ -1186,7 +1377,7 @@ func runConfigView(args []string, stdout, stderr io.Writer) int {
 		return 1
 	}
 	if *jsonMode {
-		if err := output.PrintJSON(stdout, cfg.Redacted()); err != nil {
+		if err := printConfigJSON(stdout, queryExpr, cfg.Redacted()); err != nil {
 			output.WriteError(stderr, err, *jsonMode)
 			return 1
 		}
@@ -1200,17 +1391,23 @@ func runConfigSet(args []string, stdout, stderr io.Writer) int {
 	fs := flag.NewFlagSet("config set", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 	jsonMode := fs.Bool("json", true, "Output JSON (set --json=false for text)")
+	queryExpr := stringFlag{}
+	fs.Var(&queryExpr, "query", "JMESPath expression applied to the JSON output")
 	baseURL := stringFlag{}
 	project := stringFlag{}
 	pat := stringFlag{}
 	fs.Var(&baseURL, "base-url", "Base URL")
 	fs.Var(&project, "project", "Default project")
 	fs.Var(&pat, "pat", "PAT token")
+	team := stringFlag{}
+	fs.Var(&team, "team", "Default team for sprint commands")
+	fieldAliases := stringSliceFlag{}
+	fs.Var(&fieldAliases, "field-alias", "Alias=Field.ReferenceName used by --set, --unset and --append; an empty reference removes it (repeatable)")
 	if err := fs.Parse(args); err != nil {
 		return 1
 	}
-	if !baseURL.set && !project.set && !pat.set {
-		output.WriteError(stderr, errs.New("invalid_args", "at least one of --base-url, --project, or --pat is required", nil), *jsonMode)
+	if !baseURL.set && !project.set && !pat.set && !team.set && len(fieldAliases.values) == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "at least one of --base-url, --project, --pat, --team, or --field-alias is required", nil), *jsonMode)
 		return 1
 	}
 	cfg, err := config.Load("")
@@ -1227,12 +1424,30 @@ func runConfigSet(args []string, stdout, stderr io.Writer) int {
 	if pat.set {
 		cfg.PAT = pat.value
 	}
+	if team.set {
+		cfg.Team = team.value
+	}
+	for _, assignment := range fieldAliases.values {
+		alias, ref, err := parseAssignment(assignment)
+		if err != nil {
+			output.WriteError(stderr, err, *jsonMode)
+			return 1
+		}
+		if cfg.FieldAliases == nil {
+			cfg.FieldAliases = map[string]string{}
+		}
+		if strings.TrimSpace(ref) == "" {
+			delete(cfg.FieldAliases, alias)
+			continue
+		}
+		cfg.FieldAliases[alias] = strings.TrimSpace(ref)
+	}
 	if err := config.Save("", cfg); err != nil {
 		output.WriteError(stderr, err, *jsonMode)
 		return 1
 	}
 	if *jsonMode {
-		if err := output.PrintJSON(stdout, cfg.Redacted()); err != nil {
+		if err := printConfigJSON(stdout, queryExpr, cfg.Redacted()); err != nil {
 			output.WriteError(stderr, err, *jsonMode)
 			return 1
 		}
@@ -1242,11 +1457,32 @@ func runConfigSet(args []string, stdout, stderr io.Writer) int {
 	return 0
 }
 
+// printConfigJSON prints the config commands' JSON output, which do not
+// take the global flags, applying their own --query.
+func printConfigJSON(stdout io.Writer, query stringFlag, payload interface{}) error {
+	ctx := commandContext{stdout: stdout}
+	if query.set {
+		expr, err := jmes.Compile(query.value)
+		if err != nil {
+			return errs.New("invalid_args", "invalid --query expression: "+err.Error(), query.value)
+		}
+		ctx.query = expr
+	}
+	return printJSON(ctx, payload)
+}
+
 func addGlobalFlags(fs *flag.FlagSet, flags *globalFlags) {
 	fs.Var(&flags.baseURL, "base-url", "Base URL")
 	fs.Var(&flags.project, "project", "Project name")
 	fs.Var(&flags.pat, "pat", "PAT token")
 	fs.BoolVar(&flags.json, "json", true, "Output JSON (set --json=false for text)")
+	fs.Var(&flags.format, "format", "Output format: json|table|csv|tsv|yaml|markdown|template=<go-template>")
+	// search already uses --query for its search text; --output-query works
+	// everywhere.
+	if fs.Lookup("query") == nil {
+		fs.Var(&flags.query, "query", "JMESPath expression applied to the JSON output")
+	}
+	fs.Var(&flags.query, "output-query", "JMESPath expression applied to the JSON output (same as --query)")
 	fs.BoolVar(&flags.verbose, "verbose", false, "Verbose HTTP logging")
 	fs.BoolVar(&flags.insecure, "insecure", false, "Skip TLS verification")
 }
@@ -1271,9 +1507,27 @@ func buildContext(flags globalFlags, stdout, stderr io.Writer) (commandContext,
 			cfg.BaseURL = normalized
 		}
 	}
+	jsonMode := flags.json
+	var format output.Format
+	if flags.format.set {
+		format, err = output.ParseFormat(flags.format.value)
+		if err != nil {
+			return commandContext{}, err
+		}
+		jsonMode = format.Kind != output.FormatTable
+	}
+	var query *jmes.Expression
+	if flags.query.set {
+		query, err = jmes.Compile(flags.query.value)
+		if err != nil {
+			return commandContext{}, errs.New("invalid_args", "invalid --query expression: "+err.Error(), flags.query.value)
+		}
+	}
 	return commandContext{
 		cfg:      cfg,
-		jsonMode: flags.json,
+		jsonMode: jsonMode,
+		format:   format,
+		query:    query,
 		verbose:  flags.verbose,
 		insecure: flags.insecure,
 		stdout:   stdout,
@@ -1284,41 +1538,145 @@ func buildContext(flags globalFlags, stdout, stderr io.Writer) (commandContext,
 	}, nil
 }
 
-func renderList(ctx commandContext, items []output.WorkItem) int {
+// outputFormat returns the --format in effect, falling back to JSON or
+// table according to --json.
+func (ctx commandContext) outputFormat() output.Format {
+	if ctx.format.Kind != "" {
+		return ctx.format
+	}
 	if ctx.jsonMode {
-		if err := output.PrintJSON(ctx.stdout, items); err != nil {
+		return output.Format{Kind: output.FormatJSON}
+	}
+	return output.Format{Kind: output.FormatTable}
+}
+
+// richTextMode returns the --rich-text mode in effect: Markdown for text
+// output and the stored HTML for JSON unless chosen explicitly.
+func (ctx commandContext) richTextMode() string {
+	mode, err := parseRichTextMode(ctx.richText, ctx.outputFormat().Kind != output.FormatTable)
+	if err != nil {
+		return richTextHTMLMode
+	}
+	return mode
+}
+
+// renderOutput prints payload in the selected --format. text draws the table
+// format; table supplies the rows for csv, tsv and markdown.
+func renderOutput(ctx commandContext, payload interface{}, table func() output.Table, text func()) int {
+	format := ctx.outputFormat()
+	if format.Kind == output.FormatTable {
+		text()
+		return 0
+	}
+	if ctx.query != nil {
+		result, err := applyQuery(ctx, payload)
+		if err != nil {
 			output.WriteError(ctx.stderr, err, ctx.jsonMode)
 			return 1
 		}
-		return 0
+		payload = result
+		table = func() output.Table { return output.ValueTable(result) }
+	}
+	if err := output.PrintFormatted(ctx.stdout, format, payload, table); err != nil {
+		output.WriteError(ctx.stderr, err, ctx.jsonMode)
+		return 1
 	}
-	output.PrintTable(ctx.stdout, items)
 	return 0
 }
 
+// printJSON prints payload as JSON after applying --query.
+func printJSON(ctx commandContext, payload interface{}) error {
+	result, err := applyQuery(ctx, payload)
+	if err != nil {
+		return err
+	}
+	return output.PrintJSON(ctx.stdout, result)
+}
+
+func applyQuery(ctx commandContext, payload interface{}) (interface{}, error) {
+	if ctx.query == nil {
+		return payload, nil
+	}
+	result, err := ctx.query.Search(payload)
+	if err != nil {
+		return nil, errs.New("invalid_args", "--query failed: "+err.Error(), ctx.query.String())
+	}
+	return result, nil
+}
+
+// projectRaw applies a --raw-fields projection to the raw API payload.
+func projectRaw(fields *jmes.Expression, raw interface{}) (interface{}, error) {
+	if fields == nil {
+		return raw, nil
+	}
+	result, err := fields.Search(raw)
+	if err != nil {
+		return nil, errs.New("invalid_args", "--raw-fields failed: "+err.Error(), fields.String())
+	}
+	return result, nil
+}
+
+// parseRawFields compiles a --raw-fields list; an empty value keeps the
+// whole raw payload.
+func parseRawFields(value string) (*jmes.Expression, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	fields, err := jmes.CompileList(value)
+	if err != nil {
+		return nil, errs.New("invalid_args", "invalid --raw-fields: "+err.Error(), value)
+	}
+	return fields, nil
+}
+
+func renderList(ctx commandContext, items []output.WorkItem) int {
+	return renderOutput(ctx, items, func() output.Table {
+		return output.ColumnTable(items, output.DefaultColumns())
+	}, func() {
+		output.PrintTable(ctx.stdout, items)
+	})
+}
+
+// renderColumnList prints only the chosen columns; without columns it falls
+// back to renderList.
+func renderColumnList(ctx commandContext, items []output.WorkItem, columns []output.Column) int {
+	if len(columns) == 0 {
+		return renderList(ctx, items)
+	}
+	return renderOutput(ctx, output.ColumnRows(items, columns), func() output.Table {
+		return output.ColumnTable(items, columns)
+	}, func() {
+		output.PrintColumns(ctx.stdout, items, columns)
+	})
+}
+
 func renderWorkItem(ctx commandContext, wi api.WorkItem) int {
+	wi = convertWorkItemRichText(wi, ctx.richTextMode())
 	normalized := output.NormalizeWorkItem(wi)
-	if ctx.jsonMode {
-		payload := map[string]interface{}{
-			"workItem": normalized,
-			"raw":      wi,
-		}
-		if err := output.PrintJSON(ctx.stdout, payload); err != nil {
-			output.WriteError(ctx.stderr, err, ctx.jsonMode)
-			return 1
+	payload := map[string]interface{}{
+		"workItem": normalized,
+		"raw":      wi,
+	}
+	return renderOutput(ctx, payload, func() output.Table {
+		return output.RecordTable(
+			[]string{"ID", "Type", "State", "Title", "AssignedTo", "AreaPath", "IterationPath", "Tags", "URL"},
+			normalized.ID, normalized.Type, normalized.State, normalized.Title, normalized.AssignedTo,
+			normalized.AreaPath, normalized.IterationPath, normalized.Tags, normalized.URL,
+		)
+	}, func() {
+		fmt.Fprintf(ctx.stdout, "ID: %d\n", normalized.ID)
+		fmt.Fprintf(ctx.stdout, "Type: %s\n", stringValue(normalized.Type))
+		fmt.Fprintf(ctx.stdout, "State: %s\n", stringValue(normalized.State))
+		fmt.Fprintf(ctx.stdout, "Title: %s\n", stringValue(normalized.Title))
+		fmt.Fprintf(ctx.stdout, "AssignedTo: %s\n", stringValue(normalized.AssignedTo))
+		fmt.Fprintf(ctx.stdout, "AreaPath: %s\n", stringValue(normalized.AreaPath))
+		fmt.Fprintf(ctx.stdout, "IterationPath: %s\n", stringValue(normalized.IterationPath))
+		fmt.Fprintf(ctx.stdout, "Tags: %s\n", stringValue(normalized.Tags))
+		fmt.Fprintf(ctx.stdout, "URL: %s\n", stringValue(normalized.URL))
+		for _, section := range richTextSections(wi.Fields) {
+			fmt.Fprintf(ctx.stdout, "\n%s\n", section)
 		}
-		return 0
-	}
-	fmt.Fprintf(ctx.stdout, "ID: %d\n", normalized.ID)
-	fmt.Fprintf(ctx.stdout, "Type: %s\n", stringValue(normalized.Type))
-	fmt.Fprintf(ctx.stdout, "State: %s\n", stringValue(normalized.State))
-	fmt.Fprintf(ctx.stdout, "Title: %s\n", stringValue(normalized.Title))
-	fmt.Fprintf(ctx.stdout, "AssignedTo: %s\n", stringValue(normalized.AssignedTo))
-	fmt.Fprintf(ctx.stdout, "AreaPath: %s\n", stringValue(normalized.AreaPath))
-	fmt.Fprintf(ctx.stdout, "IterationPath: %s\n", stringValue(normalized.IterationPath))
-	fmt.Fprintf(ctx.stdout, "Tags: %s\n", stringValue(normalized.Tags))
-	fmt.Fprintf(ctx.stdout, "URL: %s\n", stringValue(normalized.URL))
-	return 0
+	})
 }
 
 func renderWikiPage(ctx commandContext, locator wikiPageLocator, page api.WikiPage) int {
@@ -1331,39 +1689,37 @@ func renderWikiPage(ctx commandContext, locator wikiPageLocator, page api.WikiPa
 		pageURL = locator.SourceURL
 	}
 
-	if ctx.jsonMode {
-		payload := map[string]interface{}{
-			"wiki":            locator.WikiIdentifier,
-			"pageId":          pageID,
-			"path":            page.Path,
-			"gitItemPath":     page.GitItemPath,
-			"order":           page.Order,
-			"isParentPage":    page.IsParentPage,
-			"isNonConformant": page.IsNonConformant,
-			"url":             pageURL,
-			"apiUrl":          page.URL,
-			"sourceUrl":       locator.SourceURL,
-			"content":         page.Content,
-		}
-		if err := output.PrintJSON(ctx.stdout, payload); err != nil {
-			output.WriteError(ctx.stderr, err, ctx.jsonMode)
-			return 1
-		}
-		return 0
-	}
-
-	fmt.Fprintf(ctx.stdout, "Wiki: %s\n", locator.WikiIdentifier)
-	if pageID > 0 {
-		fmt.Fprintf(ctx.stdout, "PageID: %d\n", pageID)
-	}
-	fmt.Fprintf(ctx.stdout, "Path: %s\n", page.Path)
-	if page.GitItemPath != "" {
-		fmt.Fprintf(ctx.stdout, "GitItemPath: %s\n", page.GitItemPath)
-	}
-	fmt.Fprintf(ctx.stdout, "URL: %s\n", pageURL)
-	fmt.Fprintln(ctx.stdout, "Content:")
-	fmt.Fprintln(ctx.stdout, page.Content)
-	return 0
+	payload := map[string]interface{}{
+		"wiki":            locator.WikiIdentifier,
+		"pageId":          pageID,
+		"path":            page.Path,
+		"gitItemPath":     page.GitItemPath,
+		"order":           page.Order,
+		"isParentPage":    page.IsParentPage,
+		"isNonConformant": page.IsNonConformant,
+		"url":             pageURL,
+		"apiUrl":          page.URL,
+		"sourceUrl":       locator.SourceURL,
+		"content":         page.Content,
+	}
+	return renderOutput(ctx, payload, func() output.Table {
+		return output.RecordTable(
+			[]string{"Wiki", "PageID", "Path", "GitItemPath", "URL", "Content"},
+			locator.WikiIdentifier, pageID, page.Path, page.GitItemPath, pageURL, page.Content,
+		)
+	}, func() {
+		fmt.Fprintf(ctx.stdout, "Wiki: %s\n", locator.WikiIdentifier)
+		if pageID > 0 {
+			fmt.Fprintf(ctx.stdout, "PageID: %d\n", pageID)
+		}
+		fmt.Fprintf(ctx.stdout, "Path: %s\n", page.Path)
+		if page.GitItemPath != "" {
+			fmt.Fprintf(ctx.stdout, "GitItemPath: %s\n", page.GitItemPath)
+		}
+		fmt.Fprintf(ctx.stdout, "URL: %s\n", pageURL)
+		fmt.Fprintln(ctx.stdout, "Content:")
+		fmt.Fprintln(ctx.stdout, page.Content)
+	})
 }
 
 func renderDeletedWorkItem(ctx commandContext, id int, destroy bool, raw map[string]interface{}) int {
@@ -1374,7 +1730,7 @@ func renderDeletedWorkItem(ctx commandContext, id int, destroy bool, raw map[str
 			"destroy": destroy,
 			"raw":     raw,
 		}
-		if err := output.PrintJSON(ctx.stdout, payload); err != nil {
+		if err := printJSON(ctx, payload); err != nil {
 			output.WriteError(ctx.stderr, err, ctx.jsonMode)
 			return 1
 		}
@@ -1405,7 +1761,7 @@ func renderPullRequest(ctx commandContext, pr api.GitPullRequest) int {
 			"apiUrl":          pr.URL,
 			"raw":             pr,
 		}
-		if err := output.PrintJSON(ctx.stdout, payload); err != nil {
+		if err := printJSON(ctx, payload); err != nil {
 			output.WriteError(ctx.stderr, err, ctx.jsonMode)
 			return 1
 		}
@@ -1426,112 +1782,117 @@ func renderPullRequest(ctx commandContext, pr api.GitPullRequest) int {
 	return 0
 }
 
-func renderPullRequestDetails(ctx commandContext, pr api.GitPullRequest, workItems []output.WorkItem, threads []api.GitPullRequestThread, fileDiffs []FileDiff, showDiff bool) int {
-	if ctx.jsonMode {
-		payload := map[string]interface{}{
-			"pullRequestId":   pr.PullRequestID,
-			"status":          pr.Status,
-			"title":           pr.Title,
-			"description":     pr.Description,
-			"repository":      pr.Repository.Name,
-			"sourceRefName":   pr.SourceRefName,
-			"targetRefName":   pr.TargetRefName,
-			"isDraft":         pr.IsDraft,
-			"creationDate":    pr.CreationDate,
-			"createdBy":       identityDisplayName(pr.CreatedBy),
-			"hasAutoComplete": pr.AutoCompleteSetBy != nil && pr.AutoCompleteSetBy.ID != "",
-			"workItemIds":     resourceRefIDs(pr.WorkItemRefs),
-			"workItems":       workItems,
-			"threads":         threads,
-			"url":             pullRequestURL(pr),
-			"apiUrl":          pr.URL,
-			"raw":             pr,
-		}
-		if showDiff {
-			payload["gitDiff"] = fileDiffs
-		}
-		if err := output.PrintJSON(ctx.stdout, payload); err != nil {
-			output.WriteError(ctx.stderr, err, ctx.jsonMode)
-			return 1
-		}
-		return 0
-	}
-
-	fmt.Fprintf(ctx.stdout, "PullRequestID: %d\n", pr.PullRequestID)
-	fmt.Fprintf(ctx.stdout, "Repository: %s\n", pr.Repository.Name)
-	fmt.Fprintf(ctx.stdout, "Title: %s\n", pr.Title)
-	fmt.Fprintf(ctx.stdout, "Status: %s\n", pr.Status)
-	if author := identityDisplayName(pr.CreatedBy); author != "" {
-		fmt.Fprintf(ctx.stdout, "Author: %s\n", author)
+func renderPullRequestDetails(ctx commandContext, pr api.GitPullRequest, workItems []output.WorkItem, threads []api.GitPullRequestThread, fileDiffs []FileDiff, showDiff bool, rawFields *jmes.Expression) int {
+	raw, err := projectRaw(rawFields, pr)
+	if err != nil {
+		output.WriteError(ctx.stderr, err, ctx.jsonMode)
+		return 1
 	}
-	fmt.Fprintf(ctx.stdout, "Branches: %s -> %s\n", shortRef(pr.SourceRefName), shortRef(pr.TargetRefName))
-	fmt.Fprintf(ctx.stdout, "IsDraft: %t\n", pr.IsDraft)
-	if pr.CreationDate != "" {
-		fmt.Fprintf(ctx.stdout, "Created: %s\n", pr.CreationDate)
+	payload := map[string]interface{}{
+		"pullRequestId":   pr.PullRequestID,
+		"status":          pr.Status,
+		"title":           pr.Title,
+		"description":     pr.Description,
+		"repository":      pr.Repository.Name,
+		"sourceRefName":   pr.SourceRefName,
+		"targetRefName":   pr.TargetRefName,
+		"isDraft":         pr.IsDraft,
+		"creationDate":    pr.CreationDate,
+		"createdBy":       identityDisplayName(pr.CreatedBy),
+		"hasAutoComplete": pr.AutoCompleteSetBy != nil && pr.AutoCompleteSetBy.ID != "",
+		"workItemIds":     resourceRefIDs(pr.WorkItemRefs),
+		"workItems":       workItems,
+		"threads":         threads,
+		"url":             pullRequestURL(pr),
+		"apiUrl":          pr.URL,
+		"raw":             raw,
 	}
-	fmt.Fprintln(ctx.stdout)
-
-	if strings.TrimSpace(pr.Description) != "" {
-		fmt.Fprintln(ctx.stdout, "Description:")
-		fmt.Fprintln(ctx.stdout, pr.Description)
+	if showDiff {
+		payload["gitDiff"] = fileDiffs
+	}
+	return renderOutput(ctx, payload, func() output.Table {
+		return output.RecordTable(
+			[]string{"PullRequestID", "Repository", "Title", "Status", "Author", "Source", "Target", "IsDraft", "Created", "Description", "WorkItems", "URL"},
+			pr.PullRequestID, pr.Repository.Name, pr.Title, pr.Status, identityDisplayName(pr.CreatedBy),
+			shortRef(pr.SourceRefName), shortRef(pr.TargetRefName), pr.IsDraft, pr.CreationDate, pr.Description,
+			strings.Join(resourceRefIDs(pr.WorkItemRefs), ", "), pullRequestURL(pr),
+		)
+	}, func() {
+		fmt.Fprintf(ctx.stdout, "PullRequestID: %d\n", pr.PullRequestID)
+		fmt.Fprintf(ctx.stdout, "Repository: %s\n", pr.Repository.Name)
+		fmt.Fprintf(ctx.stdout, "Title: %s\n", pr.Title)
+		fmt.Fprintf(ctx.stdout, "Status: %s\n", pr.Status)
+		if author := identityDisplayName(pr.CreatedBy); author != "" {
+			fmt.Fprintf(ctx.stdout, "Author: %s\n", author)
+		}
+		fmt.Fprintf(ctx.stdout, "Branches: %s -> %s\n", shortRef(pr.SourceRefName), shortRef(pr.TargetRefName))
+		fmt.Fprintf(ctx.stdout, "IsDraft: %t\n", pr.IsDraft)
+		if pr.CreationDate != "" {
+			fmt.Fprintf(ctx.stdout, "Created: %s\n", pr.CreationDate)
+		}
 		fmt.Fprintln(ctx.stdout)
-	}
 
-	if len(workItems) > 0 {
-		fmt.Fprintln(ctx.stdout, "Work Items:")
-		output.PrintTable(ctx.stdout, workItems)
-		fmt.Fprintln(ctx.stdout)
-	} else {
-		fmt.Fprintln(ctx.stdout, "Work Items: none")
-		fmt.Fprintln(ctx.stdout)
-	}
+		if strings.TrimSpace(pr.Description) != "" {
+			fmt.Fprintln(ctx.stdout, "Description:")
+			fmt.Fprintln(ctx.stdout, pr.Description)
+			fmt.Fprintln(ctx.stdout)
+		}
 
-	activeThreads := 0
-	for _, thread := range threads {
-		if thread.IsDeleted {
-			continue
+		if len(workItems) > 0 {
+			fmt.Fprintln(ctx.stdout, "Work Items:")
+			output.PrintTable(ctx.stdout, workItems)
+			fmt.Fprintln(ctx.stdout)
+		} else {
+			fmt.Fprintln(ctx.stdout, "Work Items: none")
+			fmt.Fprintln(ctx.stdout)
 		}
-		activeThreads++
-	}
-	if activeThreads > 0 {
-		fmt.Fprintln(ctx.stdout, "Comments:")
+
+		activeThreads := 0
 		for _, thread := range threads {
 			if thread.IsDeleted {
 				continue
 			}
-			threadLabel := fmt.Sprintf("Thread %d", thread.ID)
-			if thread.Status != "" {
-				threadLabel += " [" + thread.Status + "]"
-			}
-			fmt.Fprintf(ctx.stdout, "  %s\n", threadLabel)
-			for _, comment := range thread.Comments {
-				if comment.IsDeleted || strings.TrimSpace(comment.Content) == "" {
+			activeThreads++
+		}
+		if activeThreads > 0 {
+			fmt.Fprintln(ctx.stdout, "Comments:")
+			for _, thread := range threads {
+				if thread.IsDeleted {
 					continue
 				}
-				author := identityDisplayName(comment.Author)
-				date := comment.PublishedDate
-				if date == "" {
-					date = comment.LastUpdatedDate
+				threadLabel := fmt.Sprintf("Thread %d", thread.ID)
+				if thread.Status != "" {
+					threadLabel += " [" + thread.Status + "]"
 				}
-				prefix := author
-				if date != "" {
-					prefix = fmt.Sprintf("%s (%s)", author, date)
+				fmt.Fprintf(ctx.stdout, "  %s\n", threadLabel)
+				for _, comment := range thread.Comments {
+					if comment.IsDeleted || strings.TrimSpace(comment.Content) == "" {
+						continue
+					}
+					author := identityDisplayName(comment.Author)
+					date := comment.PublishedDate
+					if date == "" {
+						date = comment.LastUpdatedDate
+					}
+					prefix := author
+					if date != "" {
+						prefix = fmt.Sprintf("%s (%s)", author, date)
+					}
+					fmt.Fprintf(ctx.stdout, "    %s: %s\n", prefix, comment.Content)
 				}
-				fmt.Fprintf(ctx.stdout, "    %s: %s\n", prefix, comment.Content)
 			}
+			fmt.Fprintln(ctx.stdout)
+		} else {
+			fmt.Fprintln(ctx.stdout, "Comments: none")
+			fmt.Fprintln(ctx.stdout)
 		}
-		fmt.Fprintln(ctx.stdout)
-	} else {
-		fmt.Fprintln(ctx.stdout, "Comments: none")
-		fmt.Fprintln(ctx.stdout)
-	}
 
-	if showDiff {
-		renderGitDiffText(ctx.stdout, fileDiffs)
-	}
+		if showDiff {
+			renderGitDiffText(ctx.stdout, fileDiffs)
+		}
 
-	fmt.Fprintf(ctx.stdout, "URL: %s\n", pullRequestURL(pr))
-	return 0
+		fmt.Fprintf(ctx.stdout, "URL: %s\n", pullRequestURL(pr))
+	})
 }
 
 func renderGitDiffText(w io.Writer, fileDiffs []FileDiff) {
@@ -1877,6 +2238,7 @@ func prShowValueFlags() map[string]bool {
 	flags := wiqlValueFlags()
 	flags["repository"] = true
 	flags["max-threads"] = true
+	flags["raw-fields"] = true
 	return flags
 }
 
@@ -1911,7 +2273,13 @@ func collectIDs(resp api.WiqlResponse) []int {
 	return ids
 }
 
-func fetchWorkItems(ctx context.Context, client *api.Client, ids []int) ([]output.WorkItem, error) {
+func fetchWorkItems(ctx context.Context, client *api.Client, ids []int, extraFields ...string) ([]output.WorkItem, error) {
+	return fetchWorkItemsAsOf(ctx, client, ids, time.Time{}, extraFields...)
+}
+
+// fetchWorkItemsAsOf is fetchWorkItems with the fields as they were at asOf;
+// a zero asOf means now.
+func fetchWorkItemsAsOf(ctx context.Context, client *api.Client, ids []int, asOf time.Time, extraFields ...string) ([]output.WorkItem, error) {
 	if len(ids) == 0 {
 		return []output.WorkItem{}, nil
 	}
@@ -1924,6 +2292,11 @@ func fetchWorkItems(ctx context.Context, client *api.Client, ids []int) ([]outpu
 		"System.IterationPath",
 		"System.Tags",
 	}
+	for _, extra := range extraFields {
+		if !containsFold(fields, extra) {
+			fields = append(fields, extra)
+		}
+	}
 	results := make([]output.WorkItem, 0, len(ids))
 	for i := 0; i < len(ids); i += maxBatchSize {
 		end := i + maxBatchSize
@@ -1931,7 +2304,7 @@ func fetchWorkItems(ctx context.Context, client *api.Client, ids []int) ([]outpu
 			end = len(ids)
 		}
 		chunk := ids[i:end]
-		items, err := client.GetWorkItemsBatch(ctx, chunk, fields)
+		items, err := client.GetWorkItemsBatch(ctx, chunk, fields, asOf)
 		if err != nil {
 			return nil, err
 		}
@@ -1987,14 +2360,6 @@ func buildPatch(sets []string, comment string) ([]map[string]interface{}, error)
 }
 
 func buildCreatePatch(ctx context.Context, client *api.Client, title string, assigned string, sets []string, parentID int, parentRel string) ([]map[string]interface{}, error) {
-	patch := []map[string]interface{}{}
-	patch = append(patch, map[string]interface{}{
-		"op":    "add",
-		"path":  "/fields/System.Title",
-		"value": title,
-	})
-
-	var assignedValue interface{} = ""
 	assignedString := assigned
 	remainingSets := []string{}
 	for _, set := range sets {
@@ -2010,41 +2375,63 @@ func buildCreatePatch(ctx context.Context, client *api.Client, title string, ass
 		}
 		remainingSets = append(remainingSets, set)
 	}
-	if assignedString != "" {
-		assignedValue = assignedString
+	var assignedValue interface{} = assignedString
+	if assignedString == "" {
+		resolved, err := resolveCurrentAssignee(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		assignedValue = resolved
 	}
-	if assignedValue == "" {
-		profile, err := client.ProfileMe(ctx)
-		if err == nil {
-			if profile.EmailAddress != "" && profile.DisplayName != "" {
-				assignedValue = fmt.Sprintf("%s<%s>", profile.DisplayName, profile.EmailAddress)
-			} else if profile.EmailAddress != "" {
-				assignedValue = profile.EmailAddress
-			} else if profile.DisplayName != "" {
-				assignedValue = profile.DisplayName
-			}
-		} else {
-			identity, headerErr := client.WhoamiFromHeaders(ctx)
-			if headerErr == nil {
-				if identity.ID != "" {
-					resolved, resolveErr := client.ResolveIdentityByID(ctx, identity.ID)
-					if resolveErr == nil && resolved != nil {
-						assignedValue = identityRefValue(*resolved, identity.UniqueName)
-					} else {
-						assignedValue = identityRefFallback(identity)
-					}
-				} else if identity.UniqueName != "" {
-					assignedValue = identity.UniqueName
+	return assembleCreatePatch(client, title, assignedValue, remainingSets, parentID, parentRel)
+}
+
+// resolveCurrentAssignee returns the identity behind the PAT in the form
+// System.AssignedTo accepts.
+func resolveCurrentAssignee(ctx context.Context, client *api.Client) (interface{}, error) {
+	var assignedValue interface{} = ""
+	profile, err := client.ProfileMe(ctx)
+	if err == nil {
+		if profile.EmailAddress != "" && profile.DisplayName != "" {
+			assignedValue = fmt.Sprintf("%s<%s>", profile.DisplayName, profile.EmailAddress)
+		} else if profile.EmailAddress != "" {
+			assignedValue = profile.EmailAddress
+		} else if profile.DisplayName != "" {
+			assignedValue = profile.DisplayName
+		}
+	} else {
+		identity, headerErr := client.WhoamiFromHeaders(ctx)
+		if headerErr == nil {
+			if identity.ID != "" {
+				resolved, resolveErr := client.ResolveIdentityByID(ctx, identity.ID)
+				if resolveErr == nil && resolved != nil {
+					assignedValue = identityRefValue(*resolved, identity.UniqueName)
+				} else {
+					assignedValue = identityRefFallback(identity)
 				}
+			} else if identity.UniqueName != "" {
+				assignedValue = identity.UniqueName
 			}
-			if assignedValue == "" {
-				return nil, errs.New("assigned_to_required", "assigned-to is required and could not be resolved from PAT profile", err.Error())
-			}
+		}
+		if assignedValue == "" {
+			return nil, errs.New("assigned_to_required", "assigned-to is required and could not be resolved from PAT profile", err.Error())
 		}
 	}
 	if assignedValue == "" {
 		return nil, errs.New("assigned_to_required", "assigned-to is required", nil)
 	}
+	return assignedValue, nil
+}
+
+// assembleCreatePatch builds the creation patch once the assignee is known;
+// sets must not contain System.AssignedTo.
+func assembleCreatePatch(client *api.Client, title string, assignedValue interface{}, sets []string, parentID int, parentRel string) ([]map[string]interface{}, error) {
+	patch := []map[string]interface{}{}
+	patch = append(patch, map[string]interface{}{
+		"op":    "add",
+		"path":  "/fields/System.Title",
+		"value": title,
+	})
 	patch = append(patch, map[string]interface{}{
 		"op":    "add",
 		"path":  "/fields/System.AssignedTo",
@@ -2054,20 +2441,12 @@ func buildCreatePatch(ctx context.Context, client *api.Client, title string, ass
 	if parentID > 0 {
 		relationType := parentRel
 		if relationType == "" {
-			relationType = "System.LinkTypes.Hierarchy-Reverse"
+			relationType = parentRelation
 		}
-		// TODO: confirm link relation type and URL shape for parent in your API 6.0 docs.
-		patch = append(patch, map[string]interface{}{
-			"op":   "add",
-			"path": "/relations/-",
-			"value": map[string]interface{}{
-				"rel": relationType,
-				"url": client.WorkItemURL(parentID),
-			},
-		})
+		patch = append(patch, addRelationOp(relationType, client.WorkItemURL(parentID), nil))
 	}
 
-	setPatch, err := buildPatch(remainingSets, "")
+	setPatch, err := buildPatch(sets, "")
 	if err != nil {
 		return nil, err
 	}
@@ -2075,61 +2454,6 @@ func buildCreatePatch(ctx context.Context, client *api.Client, title string, ass
 	return patch, nil
 }
 
-func buildParentPatch(ctx context.Context, client *api.Client, itemID int, parentID int, parentRel string) ([]map[string]interface{}, error) {
-	if parentID == 0 {
-		return nil, nil
-	}
-	if parentRel == "" {
-		parentRel = "System.LinkTypes.Hierarchy-Reverse"
-	}
-
-	wi, err := client.GetWorkItem(ctx, itemID, nil, "relations")
-	if err != nil {
-		return nil, err
-	}
-
-	patch := []map[string]interface{}{}
-	existingParentIndices := []int{}
-	existingParentID := 0
-	for i, raw := range wi.Relations {
-		m, ok := raw.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		rel, _ := m["rel"].(string)
-		if rel == parentRel {
-			existingParentIndices = append(existingParentIndices, i)
-			url, _ := m["url"].(string)
-			if existingParentID == 0 {
-				existingParentID = idFromURL(url)
-			}
-		}
-	}
-
-	if existingParentID == parentID && len(existingParentIndices) == 1 {
-		return nil, nil
-	}
-
-	for i := len(existingParentIndices) - 1; i >= 0; i-- {
-		idx := existingParentIndices[i]
-		patch = append(patch, map[string]interface{}{
-			"op":   "remove",
-			"path": fmt.Sprintf("/relations/%d", idx),
-		})
-	}
-
-	patch = append(patch, map[string]interface{}{
-		"op":   "add",
-		"path": "/relations/-",
-		"value": map[string]interface{}{
-			"rel": parentRel,
-			"url": client.WorkItemURL(parentID),
-		},
-	})
-
-	return patch, nil
-}
-
 func parseAssignment(input string) (string, string, error) {
 	parts := strings.SplitN(input, "=", 2)
 	if len(parts) != 2 {
@@ -2205,7 +2529,31 @@ func wiqlSearchQuery(text string) string {
 	return fmt.Sprintf("SELECT [System.Id] FROM WorkItems WHERE ([System.Title] CONTAINS '%s' OR [System.Description] CONTAINS '%s') ORDER BY [System.ChangedDate] DESC", escaped, escaped)
 }
 
-func myWiqlQuery(typeFilter string, allTypes bool, excludeState string, allStates bool) string {
+// inProgressStates returns the states of the In Progress category for
+// typeFilter, or for every work item type in use when it is empty, so the
+// default filter of tfs my works whatever the process calls its states.
+func inProgressStates(ctx context.Context, client *api.Client, typeFilter string) ([]string, error) {
+	types := []string{strings.TrimSpace(typeFilter)}
+	typeStates := client.GetWorkItemTypeStates
+	if types[0] == "" {
+		all, err := client.ListWorkItemTypes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		types = enabledTypeNames(all)
+		typeStates = listedTypeStates(all, client.GetWorkItemTypeStates)
+	}
+	states, err := statesInCategory(ctx, typeStates, types, stateCategoryInProgress)
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, errs.New("no_states", "no work item state belongs to the In Progress category", types)
+	}
+	return states, nil
+}
+
+func myWiqlQuery(typeFilter string, allTypes bool, excludeState string, allStates bool, activeStates []string) string {
 	conditions := []string{"[System.TeamProject] = @Project", "[System.AssignedTo] = @Me"}
 	if !allTypes && strings.TrimSpace(typeFilter) != "" {
 		conditions = append(conditions, fmt.Sprintf("[System.WorkItemType] = '%s'", escapeWiql(typeFilter)))
@@ -2213,7 +2561,7 @@ func myWiqlQuery(typeFilter string, allTypes bool, excludeState string, allState
 	if !allStates && strings.TrimSpace(excludeState) != "" {
 		conditions = append(conditions, fmt.Sprintf("[System.State] <> '%s'", escapeWiql(excludeState)))
 	} else if !allStates {
-		conditions = append(conditions, fmt.Sprintf("[System.State] IN (%s)", joinWiqlValues([]string{"Разработка", "Выполняется"})))
+		conditions = append(conditions, fmt.Sprintf("[System.State] IN (%s)", joinWiqlValues(activeStates)))
 	}
 	return fmt.Sprintf("SELECT [System.Id] FROM WorkItems WHERE %s ORDER BY [System.ChangedDate] DESC", strings.Join(conditions, " AND "))
 }
@@ -2285,6 +2633,8 @@ func showValueFlags() map[string]bool {
 	flags["children-rel"] = true
 	flags["max-children"] = true
 	flags["max-comments"] = true
+	flags["raw-fields"] = true
+	flags["rich-text"] = true
 	return flags
 }
 
@@ -2423,47 +2773,6 @@ func identityRefFallback(identity api.HeaderIdentity) interface{} {
 	return ""
 }
 
-func extractRelationIDs(relations []interface{}, relFilter string) []int {
-	if len(relations) == 0 {
-		return nil
-	}
-	ids := []int{}
-	seen := map[int]bool{}
-	for _, raw := range relations {
-		m, ok := raw.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		rel, _ := m["rel"].(string)
-		if relFilter != "" && rel != relFilter {
-			continue
-		}
-		url, _ := m["url"].(string)
-		id := idFromURL(url)
-		if id == 0 || seen[id] {
-			continue
-		}
-		ids = append(ids, id)
-		seen[id] = true
-	}
-	return ids
-}
-
-func idFromURL(value string) int {
-	if value == "" {
-		return 0
-	}
-	parts := strings.Split(strings.TrimRight(value, "/"), "/")
-	if len(parts) == 0 {
-		return 0
-	}
-	id, err := strconv.Atoi(parts[len(parts)-1])
-	if err != nil {
-		return 0
-	}
-	return id
-}
-
 func printWorkItemDetails(w io.Writer, wi output.WorkItem, fields map[string]interface{}, comments []api.WorkItemComment, children []output.WorkItem) {
 	fmt.Fprintf(w, "ID: %d\n", wi.ID)
 	fmt.Fprintf(w, "Title: %s\n", stringValue(wi.Title))
@@ -2472,9 +2781,8 @@ func printWorkItemDetails(w io.Writer, wi output.WorkItem, fields map[string]int
 	fmt.Fprintf(w, "AssignedTo: %s\n", stringValue(wi.AssignedTo))
 	fmt.Fprintf(w, "Tags: %s\n", stringValue(wi.Tags))
 	fmt.Fprintln(w, "")
-	if desc, ok := fields["System.Description"].(string); ok && desc != "" {
-		fmt.Fprintln(w, "Description:")
-		fmt.Fprintln(w, desc)
+	for _, section := range richTextSections(fields) {
+		fmt.Fprintln(w, section)
 		fmt.Fprintln(w, "")
 	}
 	printWorkItemComments(w, comments)
@@ -2552,18 +2860,43 @@ func splitPositional(args []string, valueFlags map[string]bool) (string, []strin
 	return positional, rest
 }
 
+// splitPositionals is splitPositional for commands that take several
+// positional arguments, such as a list of work item IDs.
+func splitPositionals(args []string, valueFlags map[string]bool) ([]string, []string) {
+	var positionals []string
+	for {
+		positional, rest := splitPositional(args, valueFlags)
+		if positional == "" {
+			return positionals, rest
+		}
+		positionals = append(positionals, positional)
+		args = rest
+	}
+}
+
 func wiqlValueFlags() map[string]bool {
 	return map[string]bool{
-		"top":      true,
-		"base-url": true,
-		"project":  true,
-		"pat":      true,
+		"top":          true,
+		"base-url":     true,
+		"project":      true,
+		"pat":          true,
+		"format":       true,
+		"query":        true,
+		"output-query": true,
 	}
 }
 
+func wiqlTreeValueFlags() map[string]bool {
+	flags := wiqlValueFlags()
+	flags["depth"] = true
+	flags["columns"] = true
+	return flags
+}
+
 func searchValueFlags() map[string]bool {
 	flags := wiqlValueFlags()
 	flags["query"] = true
+	flags["columns"] = true
 	return flags
 }
 
@@ -2571,6 +2904,7 @@ func viewValueFlags() map[string]bool {
 	flags := wiqlValueFlags()
 	flags["fields"] = true
 	flags["expand"] = true
+	flags["rich-text"] = true
 	return flags
 }
 
@@ -2580,6 +2914,12 @@ func updateValueFlags() map[string]bool {
 	flags["add-comment"] = true
 	flags["parent"] = true
 	flags["parent-rel"] = true
+	flags["if-rev"] = true
+	flags["unset"] = true
+	flags["append"] = true
+	flags["add-tag"] = true
+	flags["remove-tag"] = true
+	flags["no-validate"] = false
 	flags["yes"] = false
 	return flags
 }
@@ -2609,22 +2949,53 @@ func printUsage(w io.Writer) {
 		"tfs - CLI for TFS/Azure DevOps Server",
 		"",
 		"Usage:",
-		"  tfs wiql \"<WIQL>\" [--project P] [--top N] [--json]              Run a WIQL query and list matching items.",
-		"  tfs view <id> [--fields f1,f2,...] [--expand relations|all|none] [--json]  Show a work item by ID.",
-		"  tfs update <id> --set \"Field=Value\" ... [--add-comment \"markdown\"] [--parent <id>] [--parent-rel <rel>] [--json] [--yes]  Update fields/comments/parent; rich-text fields render Markdown as HTML.",
-		"  tfs create --type \"<WorkItemType>\" --title \"<Title>\" [--set \"Field=Value\"...] [--assigned-to \"Owner\"] [--parent <id>] [--json]  Create a work item.",
+		"  tfs wiql \"<WIQL>\" [--project P] [--top N] [--depth N] [--columns F,...] [--asof <date>] [--json]  Run a WIQL query, optionally as of a past date; tree and one-hop (link) queries print as a tree.",
+		"  tfs view <id> [--fields f1,f2,...] [--expand relations|all|none] [--rich-text markdown|html|plain] [--json]  Show a work item by ID.",
+		"  tfs update <id> [--set \"Field=Value\"...] [--unset Field...] [--append \"Field=Text\"...] [--add-tag T...] [--remove-tag T...] [--add-comment \"markdown\"] [--parent <id>] [--parent-rel <rel>] [--if-rev N] [--no-validate] [--json] [--yes]  Update fields/comments/parent; rich-text fields render Markdown as HTML; --if-rev fails with conflict if the item changed.",
+		"  tfs edit <id> [--fields f1,f2,...] [--json]                        Edit title, fields and the Markdown description in $VISUAL/$EDITOR; fails if the item changed meanwhile.",
+		"  tfs move <id...> --to <state|Proposed|InProgress|Resolved|Completed> [--reason R] [--set \"Field=Value\"...] [--add-comment \"markdown\"] [--dry-run] [--no-validate] [--json]  Change the state of items of any type; a category picks each type's own state.",
+		"  tfs create --type \"<WorkItemType>\" --title \"<Title>\" [--set \"Field=Value\"...] [--assigned-to \"Owner\"] [--parent <id>] [--no-validate] [--json]  Create a work item.",
+		"  tfs create --from <plan.yaml|plan.json|-> [--state <file>] [--rollback] [--no-validate] [--json]  Create a hierarchy of work items from a plan file; reports IDs by local name.",
 		"  tfs delete <id> --yes [--destroy] [--json]                         Delete a work item; --destroy attempts permanent removal.",
+		"  tfs bulk update --wiql \"<WIQL>\" --set \"Field=Value\" ... [--add-comment \"markdown\"] [--parent <id>] [--top N] [--dry-run] [--no-validate] [--yes] [--json]  Apply the same update to every matched work item.",
 		"  tfs pr create --repository \"<Repo>\" --source \"<Branch>\" --target \"<Branch>\" --title \"<Title>\" [--description \"<Text>\"] [--draft] [--work-item <ID> ...] [--auto-complete] [--json]  Create a pull request.",
-		"  tfs pr show <URL | ID> [--repository \"<Repo>\"] [--max-threads N] [--git-diff] [--json]  Show pull request details: repo, branches, title, work items, comments, optional git diff.",
+		"  tfs pr show <URL | ID> [--repository \"<Repo>\"] [--max-threads N] [--git-diff] [--raw-fields E,...] [--json]  Show pull request details: repo, branches, title, work items, comments, optional git diff.",
 		"  tfs pr comment <URL | ID> --content \"<text>\" [--repository \"<Repo>\"] [--status active|resolved|closed] [--json]  Post a comment thread on a pull request. Use --content - for stdin or --content-file <path> for file input.",
 		"  tfs wiki show <URL> [--json]                                      Show wiki page metadata and Markdown content by browser URL.",
-		"  tfs search --query \"<text>\" [--project P] [--top N] [--json]     Search by Title/Description.",
-		"  tfs my [--top N] [--type \"<Type>\"] [--exclude-state \"<State>\"] [--all-states] [--json]  List my items in the current project (default states: Разработка, Выполняется).",
-		"  tfs show <id> [--children-rel <rel>] [--max-children N] [--max-comments N] [--json]  Show details, comments, and child items.",
+		"  tfs search --query \"<text>\" [--project P] [--top N] [--columns F,...] [--json]  Search by Title/Description.",
+		"  tfs my [--top N] [--type \"<Type>\"] [--exclude-state \"<State>\"] [--all-states] [--columns F,...] [--json]  List my items in the current project (default: states in the In Progress category).",
+		"  tfs show <id> [--children-rel <rel>] [--max-children N] [--max-comments N] [--raw-fields E,...] [--rich-text markdown|html|plain] [--json]  Show details, comments, and child items.",
+		"  tfs attach add <id> <file> [--name \"<Name>\"] [--comment \"<text>\"] [--json]  Upload a file and attach it to a work item.",
+		"  tfs attach list <id> [--json]                                      List files attached to a work item.",
+		"  tfs attach get <id> <name|attachment-id> [-o <path>|-] [--json]   Download an attachment (default: ./<name>; '-' writes to stdout).",
+		"  tfs link add <id> <type> <target> [--comment \"<text>\"] [--repository \"<Repo>\"] [--json]  Add a link; type is an alias (related, parent, child, predecessor, successor, duplicate, duplicate-of, tested-by, tests, hyperlink, commit, pr, artifact) or a relation reference name.",
+		"  tfs link remove <id> <type> <target> [--repository \"<Repo>\"] [--json]  Remove a link.",
+		"  tfs link list <id> [--type <type>] [--json]                        List links of a work item.",
+		"  tfs history <id> [--top N] [--fields f1,f2,...] [--all-fields] [--json]  Show who changed which field at each revision.",
+		"  tfs query list [<folder>] [--depth N] [--json]                    List saved queries (My Queries, Shared Queries) and folders.",
+		"  tfs query run <path|id> [--top N] [--depth N] [--columns F,...] [--json]  Run a saved query; tree and one-hop queries print as a tree.",
+		"  tfs query save <folder/name> --wiql \"<WIQL>\" [--overwrite] [--json]  Save a query into an existing folder.",
+		"  tfs query delete <path|id> --yes [--json]                         Delete a saved query or folder.",
 		"  tfs types [--project P] [--json]                                   List work item types for the project.",
+		"  tfs sprint current [--team T] [--json]                             Show the team's current sprint and its working days left.",
+		"  tfs sprint items [--iteration <name|path|@previous|@next>] [--team T] [--flat] [--depth N] [--columns F,...] [--json]  List the sprint backlog, tasks under their backlog items.",
+		"  tfs sprint capacity [--iteration <name|path>] [--team T] [--json]  Show capacity per member against the remaining work assigned in the sprint.",
+		"  tfs backlog levels [--team T] [--json]                            List the backlog levels and their work item types.",
+		"  tfs backlog items --level <Requirements|Features|Epics> [--team T] [--columns F,...] [--json]  List a backlog level in rank order.",
+		"  tfs backlog reorder <id>... [--after <id>] [--before <id>] [--parent <id>] [--team T] [--json]  Move items to a new place on the backlog.",
+		"  tfs board show [--board <name|level>] [--team T] [--json]         Show the Kanban board columns with their cards, split columns as Doing/Done.",
+		"  tfs board move <id> --column C [--done] [--lane L] [--board B] [--team T] [--json]  Move a card to another board column or swimlane.",
+		"  tfs report burndown [--iteration <name|path|@previous>] [--team T] [--json|--format csv]  Chart the sprint's remaining work per day, replayed from revisions.",
+		"  tfs report velocity [--last N] [--team T] [--json|--format csv]  Chart the effort completed in each of the last N sprints.",
+		"  tfs diff-snapshot --wiql \"<WIQL>\" --from <date> [--to <date>] [--columns F,...] [--json|--format csv]  Compare a query's results at two times: added, removed and changed items.",
+		"  tfs watch <id>... | --wiql \"<WIQL>\" [--interval 60s] [--fields F,...] [--exec CMD]  Poll for changes and print one JSON line per event until interrupted.",
+		"  tfs areas list|tree [<path>] [--depth N] [--json]                 List area paths, flat or as a tree.",
+		"  tfs areas create <path> [--parents] | rename <path> --name N | move <path> --to <parent> | delete <path> [--reclassify <path>] --yes  Manage area paths.",
+		"  tfs iterations list|tree [<path>] [--depth N] [--json]            List iteration paths with their dates.",
+		"  tfs iterations create <path> [--start D --finish D] [--parents] | dates <path> --start D --finish D | rename | move | delete  Manage iterations.",
 		"  tfs whoami [--json]                                                Show the identity resolved from PAT.",
 		"  tfs config view [--json]                                           Show config (PAT redacted).",
-		"  tfs config set --base-url <url> [--project <name>] [--pat <token>] [--json]  Save config values.",
+		"  tfs config set --base-url <url> [--project <name>] [--pat <token>] [--team <name>] [--field-alias alias=Ref ...] [--json]  Save config values.",
 		"",
 		"WorkItemType expects the type name (value[].name). Run `tfs types` to list names for your project.",
 		"",
@@ -2633,6 +3004,8 @@ func printUsage(w io.Writer) {
 		"  --project     Project (overrides config/env)",
 		"  --pat         PAT token (overrides config/env)",
 		"  --json        Output JSON (set --json=false for text)",
+		"  --format      json|table|csv|tsv|yaml|markdown|template=<go-template> (lists, view/show, pr show, wiki show)",
+		"  --query       JMESPath expression applied to JSON output (--output-query on search)",
 		"  --verbose     Verbose HTTP logging (no tokens)",
 		"  --insecure    Skip TLS verification",
 	}
@@ -2,10 +2,24 @@ package cli
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"tfs-cli/internal/api"
+	"tfs-cli/internal/config"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/jmes"
 	"tfs-cli/internal/output"
 )
 
@@ -39,7 +53,7 @@ func TestWiqlSearchQueryEscapesQuotes(t *testing.T) {
 
 func TestMyWiqlQuery(t *testing.T) {
 	expected := "SELECT [System.Id] FROM WorkItems WHERE [System.TeamProject] = @Project AND [System.AssignedTo] = @Me AND [System.State] IN ('Разработка', 'Выполняется') ORDER BY [System.ChangedDate] DESC"
-	got := myWiqlQuery("", true, "", false)
+	got := myWiqlQuery("", true, "", false, []string{"Разработка", "Выполняется"})
 	if got != expected {
 		t.Fatalf("unexpected query: %s", got)
 	}
@@ -47,12 +61,44 @@ func TestMyWiqlQuery(t *testing.T) {
 
 func TestMyWiqlQueryExcludeStateOverridesDefault(t *testing.T) {
 	expected := "SELECT [System.Id] FROM WorkItems WHERE [System.TeamProject] = @Project AND [System.AssignedTo] = @Me AND [System.State] <> 'Выполнено' ORDER BY [System.ChangedDate] DESC"
-	got := myWiqlQuery("", true, "Выполнено", false)
+	got := myWiqlQuery("", true, "Выполнено", false, nil)
 	if got != expected {
 		t.Fatalf("unexpected query: %s", got)
 	}
 }
 
+func TestInProgressStatesUsesListedStates(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_apis/wit/workitemtypes"):
+			_, _ = w.Write([]byte(`{"count":3,"value":[{"name":"Задача","states":[{"name":"Новый","category":"Proposed"},{"name":"Выполняется","category":"InProgress"}]},{"name":"Bug"},{"name":"Old","isDisabled":true}]}`))
+		case strings.HasSuffix(r.URL.Path, "/_apis/wit/workitemtypes/Bug/states"):
+			_, _ = w.Write([]byte(`{"count":1,"value":[{"name":"Active","category":"InProgress"}]}`))
+		default:
+			t.Fatalf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	client, err := api.NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	states, err := inProgressStates(context.Background(), client, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(states, ",") != "Active,Выполняется" {
+		t.Fatalf("unexpected states: %v", states)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected the type list and one states request, got %v", requests)
+	}
+}
+
 func TestNormalizeBaseURL(t *testing.T) {
 	base, ok := normalizeBaseURL("https://tfs.example.com/DefaultCollection/MyProject", "MyProject")
 	if !ok {
@@ -442,3 +488,1833 @@ func TestPrintWorkItemDetailsIncludesAllComments(t *testing.T) {
 		t.Fatalf("latest comment should not be printed separately:\n%s", text)
 	}
 }
+
+func TestBuildHistoryEntriesSkipsBookkeepingFields(t *testing.T) {
+	updates := []api.WorkItemUpdate{
+		{
+			Rev:       1,
+			RevisedBy: map[string]interface{}{"displayName": "First User"},
+			Fields: map[string]api.WorkItemFieldUpdate{
+				"System.Rev":         {NewValue: float64(1)},
+				"System.ChangedDate": {NewValue: "2026-07-20T10:00:00Z"},
+			},
+		},
+		{
+			Rev:       2,
+			RevisedBy: map[string]interface{}{"displayName": "Second User"},
+			Fields: map[string]api.WorkItemFieldUpdate{
+				"System.ChangedDate": {OldValue: "2026-07-20T10:00:00Z", NewValue: "2026-07-21T09:00:00Z"},
+				"System.State":       {OldValue: "New", NewValue: "Active"},
+				"Microsoft.VSTS.Scheduling.RemainingWork": {OldValue: float64(8), NewValue: float64(4)},
+			},
+			Relations: &api.WorkItemRelationUpdates{
+				Added: []interface{}{map[string]interface{}{"rel": "System.LinkTypes.Related", "url": "https://tfs.example/_apis/wit/workItems/7"}},
+			},
+		},
+	}
+
+	entries := buildHistoryEntries(updates, nil, false)
+	if len(entries) != 1 {
+		t.Fatalf("expected bookkeeping-only revision to be skipped, got %#v", entries)
+	}
+	entry := entries[0]
+	if entry.Rev != 2 || entry.RevisedBy != "Second User" || entry.RevisedDate != "2026-07-21T09:00:00Z" {
+		t.Fatalf("unexpected entry metadata: %#v", entry)
+	}
+	if len(entry.Fields) != 2 || entry.Fields[0].Field != "Microsoft.VSTS.Scheduling.RemainingWork" || entry.Fields[1].Field != "System.State" {
+		t.Fatalf("unexpected field changes: %#v", entry.Fields)
+	}
+	if len(entry.Relations) != 1 || entry.Relations[0].ID != 7 || entry.Relations[0].Action != "added" {
+		t.Fatalf("unexpected relation changes: %#v", entry.Relations)
+	}
+
+	var rendered bytes.Buffer
+	printHistory(&rendered, 42, entries)
+	for _, expected := range []string{
+		"Revision 2 | Second User | 2026-07-21T09:00:00Z",
+		"System.State: New -> Active",
+		"Microsoft.VSTS.Scheduling.RemainingWork: 8 -> 4",
+		"Relation added: System.LinkTypes.Related 7",
+	} {
+		if !strings.Contains(rendered.String(), expected) {
+			t.Fatalf("rendered output missing %q:\n%s", expected, rendered.String())
+		}
+	}
+}
+
+// isolateConfig points the config file and TFS_* variables at an empty
+// temporary home so Run sees only the flags given.
+func isolateConfig(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("APPDATA", home)
+	for _, name := range []string{"TFS_BASE_URL", "TFS_PROJECT", "TFS_PAT", "TFS_TEAM"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestHistoryTopKeepsLatestRevisions(t *testing.T) {
+	isolateConfig(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_apis/wit/workItems/42/updates") {
+			t.Fatalf("unexpected request %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$skip") != "0" {
+			_, _ = w.Write([]byte(`{"count":0,"value":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"count":3,"value":[{"id":1,"rev":1,"fields":{"System.State":{"newValue":"New"}}},{"id":2,"rev":2,"fields":{"System.State":{"oldValue":"New","newValue":"Active"}}},{"id":3,"rev":3,"fields":{"System.State":{"oldValue":"Active","newValue":"Resolved"}}}]}`))
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"history", "42", "--top", "2", "--base-url", server.URL, "--project", "RND", "--pat", "test-pat"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var payload struct {
+		Revisions []historyEntry `json:"revisions"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &payload); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, stdout.String())
+	}
+	if len(payload.Revisions) != 2 || payload.Revisions[0].Rev != 2 || payload.Revisions[1].Rev != 3 {
+		t.Fatalf("expected the latest two revisions, got %s", stdout.String())
+	}
+}
+
+func TestHistoryFormatCSV(t *testing.T) {
+	isolateConfig(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$skip") != "0" {
+			_, _ = w.Write([]byte(`{"count":0,"value":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"count":1,"value":[{"id":1,"rev":2,"revisedBy":{"displayName":"Ann"},"fields":{"System.State":{"oldValue":"New","newValue":"Active"}}}]}`))
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"history", "42", "--format", "csv", "--base-url", server.URL, "--project", "RND", "--pat", "test-pat"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	expected := "Rev,RevisedBy,RevisedDate,Field,OldValue,NewValue\n2,Ann,,System.State,New,Active\n"
+	if got := strings.ReplaceAll(stdout.String(), "\r\n", "\n"); got != expected {
+		t.Fatalf("unexpected csv output:\n%s", got)
+	}
+}
+
+func TestBuildHistoryEntriesFiltersFields(t *testing.T) {
+	updates := []api.WorkItemUpdate{
+		{
+			Rev: 3,
+			Fields: map[string]api.WorkItemFieldUpdate{
+				"System.State": {OldValue: "Active", NewValue: "Closed"},
+				"System.Title": {OldValue: "Old", NewValue: "New"},
+			},
+		},
+		{
+			Rev: 4,
+			Fields: map[string]api.WorkItemFieldUpdate{
+				"System.Title": {OldValue: "New", NewValue: "Newer"},
+			},
+		},
+	}
+	entries := buildHistoryEntries(updates, []string{"system.state"}, false)
+	if len(entries) != 1 || len(entries[0].Fields) != 1 || entries[0].Fields[0].Field != "System.State" {
+		t.Fatalf("unexpected filtered entries: %#v", entries)
+	}
+}
+
+func TestExtractAttachmentsAndFind(t *testing.T) {
+	relations := []interface{}{
+		map[string]interface{}{"rel": "System.LinkTypes.Hierarchy-Reverse", "url": "https://tfs.example/_apis/wit/workItems/1"},
+		map[string]interface{}{
+			"rel": "AttachedFile",
+			"url": "https://tfs.example/_apis/wit/attachments/aaa",
+			"attributes": map[string]interface{}{
+				"name":         "build.log",
+				"resourceSize": float64(120),
+				"comment":      "CI run",
+			},
+		},
+		map[string]interface{}{
+			"rel":        "AttachedFile",
+			"url":        "https://tfs.example/_apis/wit/attachments/bbb",
+			"attributes": map[string]interface{}{"name": "shot.png"},
+		},
+		map[string]interface{}{
+			"rel":        "AttachedFile",
+			"url":        "https://tfs.example/_apis/wit/attachments/ccc",
+			"attributes": map[string]interface{}{"name": "Shot.png"},
+		},
+	}
+	attachments := extractAttachments(relations)
+	if len(attachments) != 3 {
+		t.Fatalf("unexpected attachments: %#v", attachments)
+	}
+	if attachments[0].ID != "aaa" || attachments[0].Size != 120 || attachments[0].Comment != "CI run" {
+		t.Fatalf("unexpected first attachment: %#v", attachments[0])
+	}
+
+	found, err := findAttachment(attachments, "BUILD.LOG")
+	if err != nil || found.ID != "aaa" {
+		t.Fatalf("unexpected lookup by name: %#v %v", found, err)
+	}
+	if _, err := findAttachment(attachments, "shot.png"); err == nil || !strings.Contains(err.Error(), "several attachments") {
+		t.Fatalf("expected ambiguity error, got %v", err)
+	}
+	found, err = findAttachment(attachments, "ccc")
+	if err != nil || found.Name != "Shot.png" {
+		t.Fatalf("unexpected lookup by id: %#v %v", found, err)
+	}
+	if _, err := findAttachment(attachments, "missing.txt"); err == nil {
+		t.Fatalf("expected not found error")
+	}
+}
+
+func TestDownloadAttachmentToFileIsWorldReadable(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not POSIX on Windows")
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("file content"))
+	}))
+	defer server.Close()
+	client, err := api.NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "build.log")
+	if _, err := downloadAttachmentToFile(client, attachmentInfo{ID: "6f1d", Name: "build.log"}, path); err != nil {
+		t.Fatalf("downloadAttachmentToFile: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o644 {
+		t.Fatalf("got mode %v, want 0644", mode)
+	}
+}
+
+func TestBuildAttachmentPatch(t *testing.T) {
+	patch := buildAttachmentPatch("https://tfs.example/_apis/wit/attachments/aaa", "CI run")
+	if len(patch) != 1 || patch[0]["path"] != "/relations/-" {
+		t.Fatalf("unexpected patch: %#v", patch)
+	}
+	value, _ := patch[0]["value"].(map[string]interface{})
+	if value["rel"] != "AttachedFile" || value["url"] != "https://tfs.example/_apis/wit/attachments/aaa" {
+		t.Fatalf("unexpected relation value: %#v", value)
+	}
+	attrs, _ := value["attributes"].(map[string]interface{})
+	if attrs["comment"] != "CI run" {
+		t.Fatalf("unexpected attributes: %#v", attrs)
+	}
+}
+
+func TestResolveRelationType(t *testing.T) {
+	tests := map[string]string{
+		"related":                            "System.LinkTypes.Related",
+		"Tested By":                          "Microsoft.VSTS.Common.TestedBy-Forward",
+		"duplicate_of":                       "System.LinkTypes.Duplicate-Reverse",
+		"system.linktypes.related":           "System.LinkTypes.Related",
+		"Custom.LinkTypes.Blocks":            "Custom.LinkTypes.Blocks",
+		"hyperlink":                          "Hyperlink",
+		"PR":                                 "ArtifactLink",
+		"System.LinkTypes.Hierarchy-Reverse": "System.LinkTypes.Hierarchy-Reverse",
+	}
+	for input, expected := range tests {
+		got, err := resolveRelationType(input)
+		if err != nil {
+			t.Fatalf("resolveRelationType(%q) returned error: %v", input, err)
+		}
+		if got != expected {
+			t.Fatalf("resolveRelationType(%q) = %q, want %q", input, got, expected)
+		}
+	}
+	if _, err := resolveRelationType("blocks"); err == nil {
+		t.Fatalf("expected error for unknown alias")
+	}
+}
+
+func TestBuildAddLinkPatchRefusesDuplicates(t *testing.T) {
+	existing := []interface{}{
+		map[string]interface{}{"rel": "System.LinkTypes.Related", "url": "https://tfs.example/DefaultCollection/_apis/wit/workItems/456"},
+		map[string]interface{}{"rel": "Hyperlink", "url": "https://wiki.example.com/design"},
+	}
+	related := linkTarget{Rel: "System.LinkTypes.Related", URL: "https://tfs.example/_apis/wit/workItems/456", WorkItemID: 456}
+	if _, err := buildAddLinkPatch(existing, related, ""); err == nil || !strings.Contains(err.Error(), "already has this link") {
+		t.Fatalf("expected duplicate work item link error, got %v", err)
+	}
+	hyperlink := linkTarget{Rel: "Hyperlink", URL: "https://WIKI.example.com/design"}
+	if _, err := buildAddLinkPatch(existing, hyperlink, ""); err == nil {
+		t.Fatalf("expected duplicate hyperlink error")
+	}
+
+	successor := linkTarget{Rel: "System.LinkTypes.Dependency-Forward", URL: "https://tfs.example/_apis/wit/workItems/789", WorkItemID: 789}
+	patch, err := buildAddLinkPatch(existing, successor, "blocked")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	value, _ := patch[0]["value"].(map[string]interface{})
+	attrs, _ := value["attributes"].(map[string]interface{})
+	if patch[0]["op"] != "add" || value["rel"] != "System.LinkTypes.Dependency-Forward" || attrs["comment"] != "blocked" {
+		t.Fatalf("unexpected patch: %#v", patch)
+	}
+}
+
+func TestBuildRemoveLinkPatchRemovesFromHighestIndex(t *testing.T) {
+	existing := []interface{}{
+		map[string]interface{}{"rel": "System.LinkTypes.Related", "url": "https://tfs.example/_apis/wit/workItems/456"},
+		map[string]interface{}{"rel": "System.LinkTypes.Hierarchy-Reverse", "url": "https://tfs.example/_apis/wit/workItems/1"},
+		map[string]interface{}{"rel": "System.LinkTypes.Related", "url": "https://tfs.example/_apis/wit/workItems/456/"},
+	}
+	target := linkTarget{Rel: "System.LinkTypes.Related", WorkItemID: 456}
+	patch, err := buildRemoveLinkPatch(existing, target)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(patch) != 2 || patch[0]["path"] != "/relations/2" || patch[1]["path"] != "/relations/0" {
+		t.Fatalf("unexpected patch: %#v", patch)
+	}
+	if _, err := buildRemoveLinkPatch(existing, linkTarget{Rel: "System.LinkTypes.Related", WorkItemID: 999}); err == nil {
+		t.Fatalf("expected link_not_found error")
+	}
+}
+
+func TestGitArtifactURL(t *testing.T) {
+	got := gitArtifactURL("Commit", "proj-id", "repo-id", "abc123")
+	if got != "vstfs:///Git/Commit/proj-id%2Frepo-id%2Fabc123" {
+		t.Fatalf("unexpected artifact URL: %s", got)
+	}
+}
+
+func TestApplyBulkPatchReportsPerItemResults(t *testing.T) {
+	patch := []map[string]interface{}{{"op": "add", "path": "/fields/System.State", "value": "Active"}}
+	items := planBulkUpdate([]int{10, 11, 12, 13}, nil, patch, nil, 0, "", "")
+	items[3].Patch = nil
+	calls := 0
+	batch := func(_ context.Context, ops []api.WorkItemBatchOperation) ([]api.WorkItemBatchResult, error) {
+		calls++
+		if len(ops) != 3 {
+			t.Fatalf("unchanged item sent to the server: %#v", ops)
+		}
+		results := make([]api.WorkItemBatchResult, len(ops))
+		for i, op := range ops {
+			results[i] = api.WorkItemBatchResult{ID: op.ID, WorkItem: api.WorkItem{ID: op.ID, Rev: op.ID + 100}}
+			if op.ID == 12 {
+				results[i].Err = errs.New("http_error", "request failed with status 400", "TF401320")
+			}
+		}
+		return results, nil
+	}
+	var progress bytes.Buffer
+	summary := applyBulkPatch(context.Background(), items, batch, &progress)
+	if calls != 1 {
+		t.Fatalf("expected one batch request, got %d", calls)
+	}
+	if summary.Total != 4 || summary.Succeeded != 3 || summary.Failed != 1 {
+		t.Fatalf("unexpected totals: %#v", summary)
+	}
+	if summary.Results[0].Rev != 110 || !summary.Results[3].Unchanged {
+		t.Fatalf("unexpected results: %#v", summary.Results)
+	}
+	failed := summary.Results[2]
+	if failed.OK || failed.Error == nil || failed.Error.Code != "http_error" {
+		t.Fatalf("unexpected failed result: %#v", failed)
+	}
+	if !strings.Contains(progress.String(), "[4/4] updated 3, failed 1") {
+		t.Fatalf("unexpected progress: %s", progress.String())
+	}
+}
+
+func TestPlanBulkUpdateReparents(t *testing.T) {
+	current := map[int]api.WorkItem{
+		1: {ID: 1, Fields: map[string]interface{}{"System.Title": "Moved"}, Relations: []interface{}{
+			map[string]interface{}{"rel": "System.LinkTypes.Hierarchy-Reverse", "url": "https://tfs/_apis/wit/workItems/5"},
+		}},
+		2: {ID: 2, Fields: map[string]interface{}{"System.Title": "Already there"}, Relations: []interface{}{
+			map[string]interface{}{"rel": "System.LinkTypes.Hierarchy-Reverse", "url": "https://tfs/_apis/wit/workItems/9"},
+		}},
+	}
+	items := planBulkUpdate([]int{1, 2}, current, nil, nil, 9, parentRelation, "https://tfs/_apis/wit/workItems/9")
+	if len(items[0].Patch) != 2 || items[0].Patch[0]["op"] != "remove" || items[0].Patch[1]["op"] != "add" {
+		t.Fatalf("unexpected reparent patch: %#v", items[0].Patch)
+	}
+	if change := items[0].Changes[0]; change.Field != "Parent" || change.Current != 5 || change.New != 9 {
+		t.Fatalf("unexpected preview: %#v", items[0].Changes)
+	}
+	if len(items[1].Patch) != 0 || len(items[1].Changes) != 0 {
+		t.Fatalf("item already under parent should not change: %#v", items[1])
+	}
+}
+
+func TestBulkUpdateRequiresWiqlAndChanges(t *testing.T) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := Run([]string{"bulk", "update", "--set", "System.State=Active"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "--wiql is required") {
+		t.Fatalf("unexpected error: %s", stderr.String())
+	}
+	stderr.Reset()
+	if code := Run([]string{"bulk", "update", "--wiql", "SELECT [System.Id] FROM WorkItems"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "invalid_args") {
+		t.Fatalf("unexpected error: %s", stderr.String())
+	}
+}
+
+func TestFlattenPlanOrdersParentsFirst(t *testing.T) {
+	data := []byte(`
+defaults:
+  type: Task
+  set:
+    System.IterationPath: Project\Sprint 12
+items:
+  - name: cleanup
+    title: Remove old endpoints
+    parent: login
+  - name: login
+    type: Product Backlog Item
+    title: Login page
+    set:
+      Microsoft.VSTS.Scheduling.Effort: 5
+    children:
+      - title: Build form
+        set:
+          Microsoft.VSTS.Scheduling.RemainingWork: 2.5
+          Microsoft.VSTS.Common.Activity: Development
+  - title: Follow-up
+    parent: {id: 4242}
+  - title: Follow-up check
+    parent: 3
+  - title: Hotfix
+    parent: "#4243"
+`)
+	plan, err := parseWorkItemPlan(data, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	nodes, err := flattenPlan(plan)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := []string{}
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	if strings.Join(names, ",") != "login,cleanup,login/1,3,4,5" {
+		t.Fatalf("unexpected order: %v", names)
+	}
+	child := nodes[2]
+	if child.ParentName != "login" || child.Type != "Task" {
+		t.Fatalf("unexpected child: %#v", child)
+	}
+	expectedSets := "Microsoft.VSTS.Common.Activity=Development,Microsoft.VSTS.Scheduling.RemainingWork=2.5,System.IterationPath=Project\\Sprint 12"
+	if strings.Join(child.Sets, ",") != expectedSets {
+		t.Fatalf("unexpected sets: %v", child.Sets)
+	}
+	if nodes[3].ParentID != 4242 || nodes[3].ParentName != "" {
+		t.Fatalf("unexpected existing parent: %#v", nodes[3])
+	}
+	if nodes[4].ParentName != "3" || nodes[4].ParentID != 0 {
+		t.Fatalf("expected a numeric parent to name a plan item: %#v", nodes[4])
+	}
+	if nodes[5].ParentID != 4243 || nodes[5].ParentName != "" {
+		t.Fatalf("unexpected existing parent: %#v", nodes[5])
+	}
+}
+
+func TestFlattenPlanRejectsInvalidPlans(t *testing.T) {
+	cases := map[string]string{
+		"unknown parent": `{"items":[{"name":"a","type":"Task","title":"A","parent":"missing"}]}`,
+		"cycle":          `{"items":[{"name":"a","type":"Task","title":"A","parent":"b"},{"name":"b","type":"Task","title":"B","parent":"a"}]}`,
+		"duplicate":      `{"items":[{"name":"a","type":"Task","title":"A"},{"name":"a","type":"Task","title":"B"}]}`,
+		"missing type":   `{"items":[{"name":"a","title":"A"}]}`,
+		"bad parent id":  `{"items":[{"name":"a","type":"Task","title":"A","parent":"#abc"}]}`,
+		"bad parent":     `{"items":[{"name":"a","type":"Task","title":"A","parent":{"name":"b"}}]}`,
+	}
+	for name, doc := range cases {
+		plan, err := parseWorkItemPlan([]byte(doc), true)
+		if err == nil {
+			_, err = flattenPlan(plan)
+		}
+		if err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestExecutePlanResumesAndRollsBack(t *testing.T) {
+	nodes := []planNode{
+		{Name: "story", Type: "Product Backlog Item", Title: "Story"},
+		{Name: "task1", Type: "Task", Title: "One", ParentName: "story"},
+		{Name: "task2", Type: "Task", Title: "Two", ParentName: "story"},
+		{Name: "other", Type: "Task", Title: "Other"},
+	}
+	waves := [][]string{}
+	parents := map[string]int{}
+	nextID := 100
+	runner := planRunner{
+		create: func(_ context.Context, wave []planNode, parentIDs []int) ([]int, []error) {
+			names := []string{}
+			ids := make([]int, len(wave))
+			failures := make([]error, len(wave))
+			for index, node := range wave {
+				names = append(names, node.Name)
+				if node.Name == "task2" {
+					failures[index] = errs.New("http_error", "boom", nil)
+					continue
+				}
+				parents[node.Name] = parentIDs[index]
+				nextID++
+				ids[index] = nextID
+			}
+			waves = append(waves, names)
+			return ids, failures
+		},
+	}
+
+	outcome := executePlan(context.Background(), nodes, map[string]int{"story": 50}, runner)
+	if len(outcome.Failures) != 1 || outcome.Failures[0].Name != "task2" {
+		t.Fatalf("expected task2 failure, got %#v", outcome.Failures)
+	}
+	if len(waves) != 1 || strings.Join(waves[0], ",") != "task1,task2,other" {
+		t.Fatalf("existing parent should let children go in the first wave: %v", waves)
+	}
+	if parents["task1"] != 50 || outcome.Created["task1"] != 101 {
+		t.Fatalf("unexpected outcome: %#v parents=%v", outcome.Created, parents)
+	}
+	if !outcome.Results[0].Existing || outcome.Results[0].Name != "story" {
+		t.Fatalf("unexpected results: %#v", outcome.Results)
+	}
+
+	waves = nil
+	removed := []int{}
+	runner.rollback = true
+	runner.remove = func(_ context.Context, id int) error {
+		removed = append(removed, id)
+		return nil
+	}
+	outcome = executePlan(context.Background(), nodes, nil, runner)
+	if len(waves) != 2 || strings.Join(waves[0], ",") != "story,other" {
+		t.Fatalf("unexpected waves: %v", waves)
+	}
+	if len(removed) != 3 || removed[0] != 105 || removed[2] != 103 {
+		t.Fatalf("expected reverse rollback, got %v", removed)
+	}
+	if len(outcome.Created) != 0 || len(outcome.Results) != 0 {
+		t.Fatalf("rolled back items still reported: %v", outcome.Created)
+	}
+}
+
+func TestBuildWorkItemTree(t *testing.T) {
+	links := []api.WorkItemLink{
+		{Target: api.WorkItemReference{ID: 1}},
+		{Rel: "System.LinkTypes.Hierarchy-Forward", Source: api.WorkItemReference{ID: 1}, Target: api.WorkItemReference{ID: 2}},
+		{Rel: "System.LinkTypes.Hierarchy-Forward", Source: api.WorkItemReference{ID: 2}, Target: api.WorkItemReference{ID: 3}},
+		{Rel: "System.LinkTypes.Hierarchy-Forward", Source: api.WorkItemReference{ID: 1}, Target: api.WorkItemReference{ID: 4}},
+		{Target: api.WorkItemReference{ID: 5}},
+	}
+	title := "Epic"
+	roots := buildWorkItemTree(links, 0)
+	if ids := workItemTreeIDs(roots); len(ids) != 5 {
+		t.Fatalf("unexpected tree ids: %v", ids)
+	}
+	attachTreeItems(roots, []output.WorkItem{{ID: 1, Title: &title}})
+	if len(roots) != 2 || roots[0].ID != 1 || roots[1].ID != 5 {
+		t.Fatalf("unexpected roots: %#v", roots)
+	}
+	if stringValue(roots[0].Title) != "Epic" {
+		t.Fatalf("item fields not attached: %#v", roots[0])
+	}
+	if len(roots[0].Children) != 2 || roots[0].Children[0].ID != 2 || roots[0].Children[1].ID != 4 {
+		t.Fatalf("unexpected children: %#v", roots[0].Children)
+	}
+	grandchild := roots[0].Children[0].Children[0]
+	if grandchild.ID != 3 || grandchild.Rel != "System.LinkTypes.Hierarchy-Forward" {
+		t.Fatalf("unexpected grandchild: %#v", grandchild)
+	}
+}
+
+func TestBuildWorkItemTreeStopsCyclesAndDepth(t *testing.T) {
+	links := []api.WorkItemLink{
+		{Target: api.WorkItemReference{ID: 1}},
+		{Rel: "System.LinkTypes.Related", Source: api.WorkItemReference{ID: 1}, Target: api.WorkItemReference{ID: 2}},
+		{Rel: "System.LinkTypes.Related", Source: api.WorkItemReference{ID: 2}, Target: api.WorkItemReference{ID: 1}},
+		{Rel: "System.LinkTypes.Related", Source: api.WorkItemReference{ID: 2}, Target: api.WorkItemReference{ID: 3}},
+	}
+	roots := buildWorkItemTree(links, 0)
+	second := roots[0].Children[0]
+	if len(second.Children) != 2 || !second.Children[0].Cycle || len(second.Children[0].Children) != 0 {
+		t.Fatalf("cycle not cut: %#v", second.Children)
+	}
+	if second.Children[1].ID != 3 || second.Children[1].Cycle {
+		t.Fatalf("unexpected sibling: %#v", second.Children[1])
+	}
+
+	limited := buildWorkItemTree(links, 2)
+	second = limited[0].Children[0]
+	if len(second.Children) != 0 || second.Truncated != 2 {
+		t.Fatalf("depth limit not applied: %#v", second)
+	}
+	if ids := workItemTreeIDs(limited); len(ids) != 2 {
+		t.Fatalf("hidden items should not be fetched: %v", ids)
+	}
+	var out bytes.Buffer
+	output.PrintTree(&out, limited)
+	if !strings.Contains(out.String(), "(+2 more)") {
+		t.Fatalf("truncation not shown: %s", out.String())
+	}
+}
+
+func TestSplitQueryPath(t *testing.T) {
+	folder, name := splitQueryPath("/Shared Queries/Team/Active bugs/")
+	if folder != "Shared Queries/Team" || name != "Active bugs" {
+		t.Fatalf("unexpected split: %q %q", folder, name)
+	}
+	if folder, _ := splitQueryPath("Active bugs"); folder != "" {
+		t.Fatalf("expected no folder, got %q", folder)
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	columns, err := parseColumns("title, Remaining,Custom.Team,System.Title,id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := []string{}
+	for _, column := range columns {
+		got = append(got, column.Key+"="+column.Field+"/"+column.Header)
+	}
+	want := []string{
+		"id=System.Id/ID",
+		"title=System.Title/TITLE",
+		"Remaining=Microsoft.VSTS.Scheduling.RemainingWork/REMAINING",
+		"Custom.Team=Custom.Team/TEAM",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected columns: %v", got)
+	}
+	if fields := columnFields(columns); len(fields) != 3 || fields[0] != "System.Title" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+
+	if columns, err := parseColumns(""); err != nil || columns != nil {
+		t.Fatalf("expected no columns, got %v, %v", columns, err)
+	}
+	_, err = parseColumns("title,estimate")
+	appErr, ok := err.(errs.AppError)
+	if !ok || appErr.Code != "invalid_args" {
+		t.Fatalf("expected invalid_args, got %v", err)
+	}
+}
+
+func TestRenderWorkItemCSV(t *testing.T) {
+	var stdout bytes.Buffer
+	ctx := commandContext{jsonMode: true, format: output.Format{Kind: output.FormatCSV}, stdout: &stdout, stderr: &bytes.Buffer{}}
+	wi := api.WorkItem{ID: 5, Fields: map[string]interface{}{
+		"System.WorkItemType": "Bug",
+		"System.Title":        "Crash, then hang",
+	}}
+	if code := renderWorkItem(ctx, wi); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	want := "ID,Type,State,Title,AssignedTo,AreaPath,IterationPath,Tags,URL\n5,Bug,,\"Crash, then hang\",,,,,\n"
+	if stdout.String() != want {
+		t.Fatalf("unexpected CSV: %q", stdout.String())
+	}
+}
+
+func TestRenderListAppliesQuery(t *testing.T) {
+	expr, err := jmes.Compile(`[?state == 'Active'].{id: id, title: title}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active, closed, first, second := "Active", "Closed", "First", "Second"
+	items := []output.WorkItem{{ID: 1, State: &active, Title: &first}, {ID: 2, State: &closed, Title: &second}}
+
+	var stdout bytes.Buffer
+	ctx := commandContext{jsonMode: true, query: expr, stdout: &stdout, stderr: &bytes.Buffer{}}
+	if code := renderList(ctx, items); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != `[{"id":1,"title":"First"}]` {
+		t.Fatalf("unexpected output: %s", got)
+	}
+
+	stdout.Reset()
+	ctx.format = output.Format{Kind: output.FormatCSV}
+	if code := renderList(ctx, items); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	if stdout.String() != "id,title\n1,First\n" {
+		t.Fatalf("unexpected CSV: %q", stdout.String())
+	}
+}
+
+func TestRenderPullRequestDetailsProjectsRaw(t *testing.T) {
+	rawFields, err := parseRawFields(`pullRequestId, repository.name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	ctx := commandContext{jsonMode: true, stdout: &stdout, stderr: &bytes.Buffer{}}
+	pr := api.GitPullRequest{PullRequestID: 7, Title: "Add export", Repository: api.GitRepository{Name: "app"}}
+	if code := renderPullRequestDetails(ctx, pr, nil, nil, nil, false, rawFields); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	if !strings.Contains(stdout.String(), `"raw":{"pullRequestId":7,"repository.name":"app"}`) {
+		t.Fatalf("raw payload was not projected: %s", stdout.String())
+	}
+}
+
+func TestTypesPrintsJSONWithOutputQuery(t *testing.T) {
+	isolateConfig(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_apis/wit/workitemtypes") {
+			t.Fatalf("unexpected request %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"count":2,"value":[{"name":"Bug","referenceName":"Microsoft.VSTS.WorkItemTypes.Bug"},{"name":"Task","referenceName":"Microsoft.VSTS.WorkItemTypes.Task"}]}`))
+	}))
+	defer server.Close()
+	base := []string{"types", "--base-url", server.URL, "--project", "RND", "--pat", "test-pat"}
+
+	var stdout, stderr bytes.Buffer
+	if code := Run(base, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"name":"Bug"`) || !strings.Contains(stdout.String(), `"name":"Task"`) {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := Run(append(base, "--output-query", "[].name"), &stdout, &stderr); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if strings.Join(strings.Fields(stdout.String()), "") != `["Bug","Task"]` {
+		t.Fatalf("unexpected query output: %s", stdout.String())
+	}
+}
+
+func TestConfigViewPrintsJSON(t *testing.T) {
+	isolateConfig(t)
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"config", "view", "--query", "keys(@)"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(strings.TrimSpace(stdout.String()), "[") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+}
+
+func TestSearchKeepsQueryAsSearchText(t *testing.T) {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	text := fs.String("query", "", "Search text")
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	if err := fs.Parse([]string{"--query", "export", "--output-query", "[0].id"}); err != nil {
+		t.Fatal(err)
+	}
+	if *text != "export" || flags.query.value != "[0].id" {
+		t.Fatalf("unexpected flags: text=%q query=%q", *text, flags.query.value)
+	}
+}
+
+func TestRenderWorkItemConvertsRichText(t *testing.T) {
+	wi := api.WorkItem{ID: 9, Fields: map[string]interface{}{
+		"System.Title":       "Export",
+		"System.Description": "<div>Steps:<br></div><ul><li><b>Open</b> page</li></ul>",
+	}}
+
+	var text bytes.Buffer
+	ctx := commandContext{stdout: &text, stderr: &bytes.Buffer{}}
+	if code := renderWorkItem(ctx, wi); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	if !strings.Contains(text.String(), "\nDescription:\nSteps:\n\n- **Open** page\n") {
+		t.Fatalf("description was not converted to Markdown:\n%s", text.String())
+	}
+
+	var jsonOut bytes.Buffer
+	ctx = commandContext{jsonMode: true, stdout: &jsonOut, stderr: &bytes.Buffer{}}
+	if code := renderWorkItem(ctx, wi); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	if !strings.Contains(jsonOut.String(), `\u003cdiv\u003eSteps:`) {
+		t.Fatalf("JSON should keep HTML by default: %s", jsonOut.String())
+	}
+
+	jsonOut.Reset()
+	ctx.richText = "plain"
+	if code := renderWorkItem(ctx, wi); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	if !strings.Contains(jsonOut.String(), `"System.Description":"Steps:\n\n- Open page"`) {
+		t.Fatalf("JSON should hold plain text: %s", jsonOut.String())
+	}
+	if wi.Fields["System.Description"] != "<div>Steps:<br></div><ul><li><b>Open</b> page</li></ul>" {
+		t.Fatal("conversion must not modify the caller's fields")
+	}
+}
+
+func TestEditWithoutChangesPrintsJSON(t *testing.T) {
+	isolateConfig(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/_apis/wit/workitems/42") {
+			_, _ = w.Write([]byte(`{"id":42,"rev":7,"fields":{"System.WorkItemType":"Bug","System.Title":"Crash","System.State":"Active"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"count":0,"value":[]}`))
+	}))
+	defer server.Close()
+	saved := runEditor
+	runEditor = func(string, io.Writer) error { return nil }
+	defer func() { runEditor = saved }()
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"edit", "42", "--base-url", server.URL, "--project", "RND", "--pat", "test-pat"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != `{"changed":false,"id":42,"rev":7}` {
+		t.Fatalf("unexpected output: %s", got)
+	}
+}
+
+func TestEditPatch(t *testing.T) {
+	wi := api.WorkItem{ID: 42, Rev: 7, Fields: map[string]interface{}{
+		"System.WorkItemType": "Task",
+		"System.Title":        "Fix: export",
+		"System.State":        "Active",
+		"System.AssignedTo":   map[string]interface{}{"displayName": "Pat Owner", "uniqueName": "pat@example.com"},
+		"System.Tags":         "export; ui",
+		"System.Description":  "<p>Old <strong>text</strong></p>",
+		"Microsoft.VSTS.Scheduling.RemainingWork": float64(4),
+	}}
+	doc, err := buildEditDocument(wi, append(editDefaultFields, "remaining"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := doc.Marshal()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := string(content)
+	for _, want := range []string{"id: 42\n", "rev: 7\n", "title: 'Fix: export'\n", "assigned: Pat Owner <pat@example.com>\n", "remaining: 4\n", "---\n\nOld **text**\n"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in document:\n%s", want, text)
+		}
+	}
+
+	unchanged, err := editPatch(doc, content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(unchanged) != 1 || unchanged[0]["op"] != "test" || unchanged[0]["value"] != 7 {
+		t.Fatalf("expected only the rev test, got %#v", unchanged)
+	}
+
+	edited := strings.NewReplacer(
+		"title: 'Fix: export'\n", "title: 'Fix: CSV export'\n",
+		"tags: export; ui\n", "",
+		"iteration: \"\"\n", "",
+		"area: \"\"\n", "area:\n",
+		"remaining: 4\n", "remaining: 2.5\n",
+		"Old **text**", "New text",
+	).Replace(text)
+	patch, err := editPatch(doc, []byte(strings.ReplaceAll(edited, "\n", "\r\n")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []map[string]interface{}{
+		{"op": "test", "path": "/rev", "value": 7},
+		{"op": "add", "path": "/fields/System.Title", "value": "Fix: CSV export"},
+		{"op": "add", "path": "/fields/Microsoft.VSTS.Scheduling.RemainingWork", "value": 2.5},
+		{"op": "add", "path": "/fields/System.Description", "value": "<p>New text</p>"},
+	}
+	if len(patch) != len(want) {
+		t.Fatalf("unexpected patch: %#v", patch)
+	}
+	for index := range want {
+		for key, value := range want[index] {
+			if patch[index][key] != value {
+				t.Fatalf("op %d: expected %s=%v, got %#v", index, key, value, patch[index])
+			}
+		}
+	}
+
+	_, err = editPatch(doc, []byte(strings.Replace(text, "rev: 7\n", "rev: 8\n", 1)))
+	appErr, ok := err.(errs.AppError)
+	if !ok || appErr.Code != "invalid_edit" {
+		t.Fatalf("expected invalid_edit for a changed rev, got %v", err)
+	}
+}
+
+func TestWithRevisionTest(t *testing.T) {
+	patch := withRevisionTest([]map[string]interface{}{{"op": "add", "path": "/fields/System.Title", "value": "x"}}, 5)
+	if len(patch) != 2 || patch[0]["op"] != "test" || patch[0]["path"] != "/rev" || patch[0]["value"] != 5 {
+		t.Fatalf("expected a leading rev test, got %#v", patch)
+	}
+	if again := withRevisionTest(patch, 6); len(again) != 2 || again[0]["value"] != 5 {
+		t.Fatalf("existing rev test should be kept, got %#v", again)
+	}
+}
+
+func TestExplainConflictOnlyWhenRevisionMoved(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/RND/_apis/wit/workitems/42":
+			_, _ = w.Write([]byte(`{"id":42,"rev":5,"fields":{"System.State":"Resolved"}}`))
+		case "/RND/_apis/wit/workItems/42/revisions/4":
+			_, _ = w.Write([]byte(`{"id":42,"rev":4,"fields":{"System.State":"Active"}}`))
+		default:
+			t.Fatalf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	client, err := api.NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	conflict := errs.New("conflict", "request failed with status 409", "other cause")
+
+	if got := explainConflict(context.Background(), client, 42, 0, conflict); got != error(conflict) || requests != 0 {
+		t.Fatalf("expected the original error without a revision test, got %v after %d requests", got, requests)
+	}
+	if got := explainConflict(context.Background(), client, 42, 5, conflict); got != error(conflict) {
+		t.Fatalf("expected the original error when the revision matches, got %v", got)
+	}
+	got, ok := explainConflict(context.Background(), client, 42, 4, conflict).(errs.AppError)
+	if !ok || !strings.Contains(got.Message, "revision 5, not 4") {
+		t.Fatalf("expected an explained conflict, got %#v", got)
+	}
+	if details, ok := got.Details.(conflictDetails); !ok || len(details.Fields) != 1 || details.Fields[0].Field != "System.State" {
+		t.Fatalf("unexpected details: %#v", got.Details)
+	}
+}
+
+func TestChangedFieldsSkipsBookkeeping(t *testing.T) {
+	before := map[string]interface{}{"System.Title": "Old", "System.State": "Active", "System.Rev": float64(3), "System.Tags": "a"}
+	after := map[string]interface{}{"System.Title": "New", "System.State": "Active", "System.Rev": float64(5)}
+	changes := changedFields(before, after)
+	if len(changes) != 2 || changes[0].Field != "System.Tags" || changes[0].NewValue != nil || changes[1].Field != "System.Title" || changes[1].OldValue != "Old" {
+		t.Fatalf("unexpected changes: %#v", changes)
+	}
+}
+
+func TestEditTagsMatchesCaseInsensitively(t *testing.T) {
+	got := editTags("Backend; UI;export; ops; Docs", []string{"ui", "Urgent; backend"}, []string{"EXPORT"})
+	if got != "Backend; UI; ops; Docs; Urgent" {
+		t.Fatalf("unexpected tags: %q", got)
+	}
+}
+
+func TestBuildFieldEditPatch(t *testing.T) {
+	current := api.WorkItem{Rev: 4, Fields: map[string]interface{}{
+		"System.Title":       "Export",
+		"System.Description": "<p>First</p>",
+		"System.Tags":        "a; B",
+	}}
+	patch, err := buildFieldEditPatch(current, fieldEdits{
+		Unset:      []string{"System.AssignedTo"},
+		Append:     []string{"System.Title=(v2)", "system.description=**Second**"},
+		RemoveTags: []string{"b"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []map[string]interface{}{
+		{"op": "remove", "path": "/fields/System.AssignedTo"},
+		{"op": "add", "path": "/fields/System.Title", "value": "Export (v2)"},
+		{"op": "add", "path": "/fields/system.description", "value": "<p>First</p><p><strong>Second</strong></p>"},
+		{"op": "add", "path": "/fields/System.Tags", "value": "a"},
+	}
+	if len(patch) != len(want) {
+		t.Fatalf("unexpected patch: %#v", patch)
+	}
+	for index := range want {
+		for key, value := range want[index] {
+			if patch[index][key] != value {
+				t.Fatalf("op %d: expected %s=%v, got %#v", index, key, value, patch[index])
+			}
+		}
+	}
+
+	unchanged, err := buildFieldEditPatch(current, fieldEdits{AddTags: []string{"A"}})
+	if err != nil || len(unchanged) != 0 {
+		t.Fatalf("adding an existing tag should not change anything: %#v %v", unchanged, err)
+	}
+}
+
+func TestValidateFieldEditsRejectsConflicts(t *testing.T) {
+	err := validateFieldEdits([]string{"System.AssignedTo=Pat"}, fieldEdits{Unset: []string{"system.assignedto"}})
+	if appErr, ok := err.(errs.AppError); !ok || appErr.Code != "invalid_args" {
+		t.Fatalf("expected invalid_args, got %v", err)
+	}
+	err = validateFieldEdits([]string{"System.Tags=a"}, fieldEdits{AddTags: []string{"b"}})
+	if err == nil {
+		t.Fatal("expected --set System.Tags with --add-tag to fail")
+	}
+	if err := validateFieldEdits(nil, fieldEdits{Append: []string{"System.Title"}}); err == nil {
+		t.Fatal("expected --append without = to fail")
+	}
+}
+
+func testFieldValidator() *fieldValidator {
+	return &fieldValidator{
+		listFields: func(context.Context) ([]api.FieldDefinition, error) {
+			return []api.FieldDefinition{
+				{ReferenceName: "System.Title", Type: "string"},
+				{ReferenceName: "System.State", Type: "string"},
+				{ReferenceName: "System.AssignedTo", Type: "string", IsIdentity: true},
+				{ReferenceName: "Microsoft.VSTS.Common.Priority", Type: "integer"},
+				{Name: "Remaining Work", ReferenceName: "Microsoft.VSTS.Scheduling.RemainingWork", Type: "double"},
+				{Name: "Activity", ReferenceName: "Microsoft.VSTS.Common.Activity", Type: "string"},
+				{ReferenceName: "Microsoft.VSTS.Scheduling.DueDate", Type: "dateTime"},
+				{ReferenceName: "Microsoft.VSTS.Common.Severity", Type: "string"},
+			}, nil
+		},
+		typeFields: func(_ context.Context, typeName string) ([]api.WorkItemTypeField, error) {
+			return []api.WorkItemTypeField{
+				{ReferenceName: "System.Title"},
+				{ReferenceName: "System.State", AllowedValues: []interface{}{"New", "Active", "Closed"}},
+				{ReferenceName: "Microsoft.VSTS.Common.Priority", AllowedValues: []interface{}{"1", "2", "3", "4"}},
+				{ReferenceName: "Microsoft.VSTS.Scheduling.RemainingWork"},
+				{ReferenceName: "Microsoft.VSTS.Scheduling.DueDate"},
+				{ReferenceName: "Microsoft.VSTS.Common.Activity", AllowedValues: []interface{}{"Разработка", "Тестирование"}},
+			}, nil
+		},
+		typeStates: func(context.Context, string) ([]api.WorkItemTypeState, error) {
+			return []api.WorkItemTypeState{
+				{Name: "New", Category: stateCategoryProposed},
+				{Name: "Active", Category: stateCategoryInProgress},
+				{Name: "Closed", Category: stateCategoryCompleted},
+			}, nil
+		},
+		searchIdentities: func(_ context.Context, filter string) ([]api.Identity, error) {
+			pat := api.Identity{ProviderDisplayName: "Pat Owner", Properties: map[string]interface{}{"Mail": map[string]interface{}{"$value": "pat@example.com"}}}
+			patrick := api.Identity{ProviderDisplayName: "Patrick Doe", Properties: map[string]interface{}{"Mail": map[string]interface{}{"$value": "patrick@example.com"}}}
+			switch filter {
+			case "pat@example.com":
+				return []api.Identity{pat}, nil
+			case "Pat":
+				return []api.Identity{pat, patrick}, nil
+			}
+			return nil, nil
+		},
+		currentUser: func(context.Context) (interface{}, error) { return "Me <me@example.com>", nil },
+	}
+}
+
+func TestFieldValidatorConvertsValues(t *testing.T) {
+	patch := []map[string]interface{}{
+		{"op": "add", "path": "/fields/microsoft.vsts.scheduling.remainingwork", "value": "2,5"},
+		{"op": "add", "path": "/fields/Microsoft.VSTS.Common.Priority", "value": "2"},
+		{"op": "add", "path": "/fields/System.State", "value": "active"},
+		{"op": "add", "path": "/fields/Microsoft.VSTS.Scheduling.DueDate", "value": "2024-05-31T17:00:00+03:00"},
+		{"op": "add", "path": "/fields/System.AssignedTo", "value": "pat@example.com"},
+		{"op": "remove", "path": "/fields/system.title"},
+	}
+	if err := testFieldValidator().apply(context.Background(), "Task", patch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []map[string]interface{}{
+		{"path": "/fields/Microsoft.VSTS.Scheduling.RemainingWork", "value": 2.5},
+		{"path": "/fields/Microsoft.VSTS.Common.Priority", "value": 2},
+		{"path": "/fields/System.State", "value": "Active"},
+		{"path": "/fields/Microsoft.VSTS.Scheduling.DueDate", "value": "2024-05-31T14:00:00Z"},
+		{"path": "/fields/System.AssignedTo", "value": "Pat Owner <pat@example.com>"},
+		{"path": "/fields/System.Title"},
+	}
+	for index := range want {
+		for key, value := range want[index] {
+			if patch[index][key] != value {
+				t.Fatalf("op %d: expected %s=%v, got %#v", index, key, value, patch[index])
+			}
+		}
+	}
+}
+
+func TestValidateBulkPatchConvertsPerType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_apis/wit/workitemsbatch") {
+			t.Fatalf("unexpected request %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"count":3,"value":[{"id":1,"fields":{"System.WorkItemType":"Task"}},{"id":2,"fields":{"System.WorkItemType":"Bug"}},{"id":3,"fields":{"System.WorkItemType":"Task"}}]}`))
+	}))
+	defer server.Close()
+	client, err := api.NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	validator := testFieldValidator()
+	taskFields := validator.typeFields
+	validator.typeFields = func(ctx context.Context, typeName string) ([]api.WorkItemTypeField, error) {
+		if typeName == "Bug" {
+			return []api.WorkItemTypeField{{ReferenceName: "System.State", AllowedValues: []interface{}{"New", "ACTIVE"}}}, nil
+		}
+		return taskFields(ctx, typeName)
+	}
+	patch := []map[string]interface{}{{"op": "add", "path": "/fields/System.State", "value": "active"}}
+
+	typed, err := validateBulkPatch(context.Background(), client, validator, []int{1, 2, 3}, patch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	items := planBulkUpdate([]int{1, 2, 3}, nil, patch, typed, 0, "", "")
+	for index, want := range []string{"Active", "ACTIVE", "Active"} {
+		if got := items[index].Patch[0]["value"]; got != want {
+			t.Fatalf("item %d: expected %q, got %#v", items[index].ID, want, got)
+		}
+	}
+	if patch[0]["value"] != "active" {
+		t.Fatalf("expected the original patch to be left alone, got %#v", patch)
+	}
+}
+
+func TestFieldValidatorIdentitySearchErrors(t *testing.T) {
+	patch := func() []map[string]interface{} {
+		return []map[string]interface{}{{"op": "add", "path": "/fields/System.AssignedTo", "value": "Pat"}}
+	}
+	validator := testFieldValidator()
+	validator.searchIdentities = func(context.Context, string) ([]api.Identity, error) {
+		return nil, errs.New("http_error", "request failed with status 404", nil)
+	}
+	unavailable := patch()
+	if err := validator.apply(context.Background(), "Task", unavailable); err != nil || unavailable[0]["value"] != "Pat" {
+		t.Fatalf("expected the name to be kept when search is not available, got %v %#v", err, unavailable)
+	}
+
+	validator = testFieldValidator()
+	validator.searchIdentities = func(context.Context, string) ([]api.Identity, error) {
+		return nil, errs.New("http_error", "request failed with status 401", nil)
+	}
+	err := validator.apply(context.Background(), "Task", patch())
+	if appErr, ok := err.(errs.AppError); !ok || appErr.Message != "request failed with status 401" {
+		t.Fatalf("expected the search error, got %v", err)
+	}
+}
+
+func TestFieldValidatorRejectsInvalidValues(t *testing.T) {
+	cases := []struct {
+		name string
+		op   map[string]interface{}
+		code string
+	}{
+		{"picklist", map[string]interface{}{"op": "add", "path": "/fields/System.State", "value": "Blocked"}, "invalid_value"},
+		{"number", map[string]interface{}{"op": "add", "path": "/fields/Microsoft.VSTS.Common.Priority", "value": "high"}, "invalid_value"},
+		{"date", map[string]interface{}{"op": "add", "path": "/fields/Microsoft.VSTS.Scheduling.DueDate", "value": "tomorrow"}, "invalid_value"},
+		{"unknown", map[string]interface{}{"op": "add", "path": "/fields/Custom.Missing", "value": "x"}, "unknown_field"},
+		{"not on type", map[string]interface{}{"op": "add", "path": "/fields/Microsoft.VSTS.Common.Severity", "value": "1"}, "invalid_field"},
+		{"ambiguous identity", map[string]interface{}{"op": "add", "path": "/fields/System.AssignedTo", "value": "Pat"}, "ambiguous_identity"},
+		{"unknown identity", map[string]interface{}{"op": "add", "path": "/fields/System.AssignedTo", "value": "nobody"}, "identity_not_found"},
+	}
+	for _, tc := range cases {
+		err := testFieldValidator().apply(context.Background(), "Task", []map[string]interface{}{tc.op})
+		appErr, ok := err.(errs.AppError)
+		if !ok || appErr.Code != tc.code {
+			t.Fatalf("%s: expected %s, got %v", tc.name, tc.code, err)
+		}
+		if tc.name == "picklist" {
+			details, _ := appErr.Details.(map[string]interface{})
+			allowed, _ := details["allowedValues"].([]string)
+			if strings.Join(allowed, ",") != "New,Active,Closed" {
+				t.Fatalf("expected allowed values in details, got %#v", appErr.Details)
+			}
+		}
+	}
+}
+
+func TestFieldValidatorResolvesNamesAndLocalizedValues(t *testing.T) {
+	patch := []map[string]interface{}{
+		{"op": "add", "path": "/fields/Remaining Work", "value": "4"},
+		{"op": "add", "path": "/fields/Microsoft.VSTS.Common.Activity", "value": "Development"},
+		{"op": "add", "path": "/fields/System.State", "value": "done"},
+	}
+	if err := testFieldValidator().apply(context.Background(), "Task", patch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []map[string]interface{}{
+		{"path": "/fields/Microsoft.VSTS.Scheduling.RemainingWork", "value": 4.0},
+		{"path": "/fields/Microsoft.VSTS.Common.Activity", "value": "Разработка"},
+		{"path": "/fields/System.State", "value": "Closed"},
+	}
+	for index := range want {
+		for key, value := range want[index] {
+			if patch[index][key] != value {
+				t.Fatalf("op %d: expected %s=%v, got %#v", index, key, value, patch[index])
+			}
+		}
+	}
+}
+
+func TestResolveFieldAliasPrefersConfig(t *testing.T) {
+	aliases := map[string]string{"Remaining": "Custom.Remaining", "sp": "Microsoft.VSTS.Scheduling.StoryPoints"}
+	cases := map[string]string{
+		"remaining":         "Custom.Remaining",
+		"SP":                "Microsoft.VSTS.Scheduling.StoryPoints",
+		"activity":          "Microsoft.VSTS.Common.Activity",
+		"System.Title":      "System.Title",
+		"Оставшаяся работа": "Оставшаяся работа",
+	}
+	for name, want := range cases {
+		if got := resolveFieldAlias(aliases, name); got != want {
+			t.Fatalf("%s: expected %s, got %s", name, want, got)
+		}
+	}
+	sets := aliasAssignments(nil, []string{"remaining=4", "broken"})
+	if strings.Join(sets, "|") != "Microsoft.VSTS.Scheduling.RemainingWork=4|broken" {
+		t.Fatalf("unexpected assignments: %v", sets)
+	}
+}
+
+func TestApplyValueAliases(t *testing.T) {
+	cfg := config.Config{
+		FieldAliases: map[string]string{"env": "Custom.Environment"},
+		ValueAliases: map[string]map[string]string{"env": {"prod": "Продуктив"}},
+	}
+	patch := []map[string]interface{}{
+		{"op": "add", "path": "/fields/Custom.Environment", "value": "PROD"},
+		{"op": "add", "path": "/fields/System.Title", "value": "prod"},
+	}
+	applyValueAliases(cfg, patch)
+	if patch[0]["value"] != "Продуктив" || patch[1]["value"] != "prod" {
+		t.Fatalf("unexpected patch: %#v", patch)
+	}
+}
+
+func TestStatesInCategory(t *testing.T) {
+	states := map[string][]api.WorkItemTypeState{
+		"Task": {{Name: "Новый", Category: "Proposed"}, {Name: "Разработка", Category: "InProgress"}},
+		"Bug":  {{Name: "Выполняется", Category: "InProgress"}, {Name: "Разработка", Category: "InProgress"}},
+	}
+	lookup := func(_ context.Context, typeName string) ([]api.WorkItemTypeState, error) {
+		return states[typeName], nil
+	}
+	got, err := statesInCategory(context.Background(), lookup, []string{"Task", "Bug"}, stateCategoryInProgress)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(got, ",") != "Разработка,Выполняется" {
+		t.Fatalf("unexpected states: %v", got)
+	}
+	if stateCategory("In Progress") != stateCategoryInProgress || stateCategory("Разработка") != "" {
+		t.Fatal("unexpected state category lookup")
+	}
+}
+
+func TestSplitPositionalsCollectsEveryID(t *testing.T) {
+	ids, rest := splitPositionals([]string{"12", "--to", "Done", "13", "--dry-run", "14"}, moveValueFlags())
+	if strings.Join(ids, ",") != "12,13,14" || strings.Join(rest, " ") != "--to Done --dry-run" {
+		t.Fatalf("unexpected split: %v %v", ids, rest)
+	}
+}
+
+func TestPlanMovePicksStateOfCategoryPerType(t *testing.T) {
+	states := []api.WorkItemTypeState{
+		{Name: "Новый", Category: stateCategoryProposed},
+		{Name: "Разработка", Category: stateCategoryInProgress},
+		{Name: "Закрыт", Category: stateCategoryCompleted},
+	}
+	transitions := map[string][]api.WorkItemStateTransition{
+		"Новый":      {{To: "Разработка"}},
+		"Разработка": {{To: "Новый"}, {To: "Закрыт"}},
+	}
+	fields := map[string]api.WorkItemTypeField{
+		"system.title": {ReferenceName: "System.Title", AlwaysRequired: true},
+		"microsoft.vsts.scheduling.remainingwork": {ReferenceName: "Microsoft.VSTS.Scheduling.RemainingWork"},
+	}
+	task := api.WorkItem{ID: 7, Rev: 3, Fields: map[string]interface{}{
+		"System.WorkItemType": "Task",
+		"System.State":        "Разработка",
+		"System.Title":        "Fix",
+		"Microsoft.VSTS.Scheduling.RemainingWork": 2.5,
+	}}
+
+	plan, err := planMove(task, "Completed", "Fixed", states, transitions, fields, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan.State != "Закрыт" || len(plan.Patch) != 4 {
+		t.Fatalf("unexpected plan: %#v", plan)
+	}
+	if plan.Patch[0]["op"] != "test" || plan.Patch[0]["value"] != 3 || plan.Patch[1]["value"] != "Закрыт" || plan.Patch[2]["value"] != "Fixed" || plan.Patch[3]["value"] != 0 {
+		t.Fatalf("unexpected patch: %#v", plan.Patch)
+	}
+
+	if plan, err := planMove(task, "разработка", "", states, transitions, fields, nil); err != nil || plan.Patch != nil {
+		t.Fatalf("expected no change, got %#v, %v", plan, err)
+	}
+
+	task.Fields["System.State"] = "РАЗРАБОТКА"
+	if plan, err := planMove(task, "Закрыт", "", states, transitions, fields, nil); err != nil || plan.State != "Закрыт" {
+		t.Fatalf("expected transitions to match the state in any case, got %#v, %v", plan, err)
+	}
+
+	task.Fields["System.State"] = "Новый"
+	_, err = planMove(task, "Done", "", states, transitions, fields, nil)
+	if appErr, ok := err.(errs.AppError); !ok || appErr.Code != "invalid_transition" {
+		t.Fatalf("expected invalid_transition, got %v", err)
+	}
+	_, err = planMove(task, "Resolved", "", states, transitions, fields, nil)
+	if appErr, ok := err.(errs.AppError); !ok || appErr.Code != "invalid_state" {
+		t.Fatalf("expected invalid_state, got %v", err)
+	}
+
+	delete(task.Fields, "System.Title")
+	_, err = planMove(task, "InProgress", "", states, transitions, fields, nil)
+	if appErr, ok := err.(errs.AppError); !ok || appErr.Code != "missing_fields" {
+		t.Fatalf("expected missing_fields, got %v", err)
+	}
+	extra := []map[string]interface{}{{"op": "add", "path": "/fields/System.Title", "value": "Fix"}}
+	if _, err := planMove(task, "InProgress", "", states, transitions, fields, extra); err != nil {
+		t.Fatalf("expected --set to supply the required field, got %v", err)
+	}
+}
+
+func TestClassificationPaths(t *testing.T) {
+	cases := map[string]string{
+		`RND\Team A\Sprint 1`: "Team A/Sprint 1",
+		"rnd/Team A":          "Team A",
+		"Team A/":             "Team A",
+		"RND":                 "",
+		"":                    "",
+	}
+	for input, want := range cases {
+		if got := nodeRelativePath("RND", input); got != want {
+			t.Fatalf("%q: expected %q, got %q", input, want, got)
+		}
+	}
+	if got := nodeFieldPath("RND", "Team A/Sprint 1"); got != `RND\Team A\Sprint 1` {
+		t.Fatalf("unexpected field path: %s", got)
+	}
+	node := api.ClassificationNode{Name: "Sprint 1", Path: `\RND\Iteration\Release 5\Sprint 1`}
+	if got := classificationFieldPath(node, ""); got != `RND\Release 5\Sprint 1` {
+		t.Fatalf("unexpected path from server: %s", got)
+	}
+}
+
+func TestIterationAttributes(t *testing.T) {
+	attributes, err := iterationAttributes(stringFlag{value: "2025-01-06", set: true}, stringFlag{value: "17.01.2025", set: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attributes.StartDate != "2025-01-06T00:00:00Z" || attributes.FinishDate != "2025-01-17T00:00:00Z" {
+		t.Fatalf("unexpected attributes: %#v", attributes)
+	}
+	if _, err := iterationAttributes(stringFlag{value: "2025-01-17", set: true}, stringFlag{value: "2025-01-06", set: true}); err == nil {
+		t.Fatal("expected an error for a finish before the start")
+	}
+	if _, err := iterationAttributes(stringFlag{value: "2025-01-06", set: true}, stringFlag{}); err == nil {
+		t.Fatal("expected an error for a start without a finish")
+	}
+}
+
+func TestFieldValidatorChecksClassificationPaths(t *testing.T) {
+	validator := testFieldValidator()
+	requests := 0
+	validator.node = func(_ context.Context, group, path string, _ int) (api.ClassificationNode, error) {
+		requests++
+		if group == api.IterationNodes && strings.EqualFold(path, "release 5/sprint 1") {
+			return api.ClassificationNode{Name: "Sprint 1", Path: `\RND\Iteration\Release 5\Sprint 1`}, nil
+		}
+		return api.ClassificationNode{}, errs.New("http_error", "request failed with status 404", nil)
+	}
+	validator.definitions = nil
+	listFields := validator.listFields
+	validator.listFields = func(ctx context.Context) ([]api.FieldDefinition, error) {
+		fields, err := listFields(ctx)
+		return append(fields, api.FieldDefinition{ReferenceName: "System.IterationPath", Type: "treePath"}), err
+	}
+	patch := []map[string]interface{}{{"op": "add", "path": "/fields/System.IterationPath", "value": `rnd\release 5\sprint 1`}}
+	if err := validator.apply(context.Background(), "", patch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patch[0]["value"] != `RND\Release 5\Sprint 1` {
+		t.Fatalf("expected the server's spelling, got %#v", patch[0]["value"])
+	}
+	again := []map[string]interface{}{{"op": "add", "path": "/fields/System.IterationPath", "value": `RND\Release 5\Sprint 1`}}
+	if err := validator.apply(context.Background(), "", again); err != nil || requests != 1 {
+		t.Fatalf("unexpected result: %v after %d requests", err, requests)
+	}
+	missing := []map[string]interface{}{{"op": "add", "path": "/fields/System.IterationPath", "value": `RND\Release 9`}}
+	err := validator.apply(context.Background(), "", missing)
+	if appErr, ok := err.(errs.AppError); !ok || appErr.Code != "invalid_path" {
+		t.Fatalf("expected invalid_path, got %v", err)
+	}
+}
+
+func TestCountWorkingDaysSkipsWeekendsAndDaysOff(t *testing.T) {
+	start, _ := sprintDate("2025-01-06T00:00:00Z")
+	finish, _ := sprintDate("2025-01-17T00:00:00Z")
+	working := workingWeekdays(nil)
+	if got := countWorkingDays(start, finish, working, nil); got != 10 {
+		t.Fatalf("expected 10 working days, got %d", got)
+	}
+	off := []api.DateRange{{Start: "2025-01-09T00:00:00Z", End: "2025-01-13T00:00:00Z"}}
+	if got := countWorkingDays(start, finish, working, off); got != 7 {
+		t.Fatalf("expected 7 working days, got %d", got)
+	}
+	sixDays := workingWeekdays([]string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday"})
+	if got := countWorkingDays(start, finish, sixDays, nil); got != 11 {
+		t.Fatalf("expected 11 working days, got %d", got)
+	}
+}
+
+func TestFindTeamIteration(t *testing.T) {
+	iterations := []api.TeamSettingsIteration{
+		{ID: "a", Name: "Sprint 1", Path: `RND\Release 5\Sprint 1`, Attributes: api.TeamIterationAttributes{TimeFrame: "past"}},
+		{ID: "b", Name: "Sprint 2", Path: `RND\Release 5\Sprint 2`, Attributes: api.TeamIterationAttributes{TimeFrame: "current"}},
+		{ID: "c", Name: "Sprint 3", Path: `RND\Release 5\Sprint 3`, Attributes: api.TeamIterationAttributes{TimeFrame: "future"}},
+	}
+	cases := map[string]string{
+		"sprint 1":               "a",
+		"Release 5/Sprint 3":     "c",
+		`RND\Release 5\Sprint 2`: "b",
+		"@previous":              "a",
+		"@next":                  "c",
+	}
+	for name, want := range cases {
+		iteration, ok, err := findTeamIteration(iterations, name)
+		if err != nil || !ok || iteration.ID != want {
+			t.Fatalf("%s: expected %s, got %#v %v %v", name, want, iteration, ok, err)
+		}
+	}
+	if _, ok, _ := findTeamIteration(iterations, "Sprint 9"); ok {
+		t.Fatal("expected no match for an unknown sprint")
+	}
+	if _, _, err := findTeamIteration(iterations[1:], "@previous"); err == nil {
+		t.Fatal("expected an error when there is no previous sprint")
+	}
+}
+
+func TestFindBacklogLevel(t *testing.T) {
+	config := api.BacklogConfiguration{
+		PortfolioBacklogs: []api.BacklogLevel{
+			{ID: "Microsoft.FeatureCategory", Name: "Features", Rank: 2, Type: "portfolio"},
+			{ID: "Microsoft.EpicCategory", Name: "Epics", Rank: 3, Type: "portfolio"},
+		},
+		RequirementBacklog: api.BacklogLevel{ID: "Microsoft.RequirementCategory", Name: "Stories", Rank: 1, Type: "requirement"},
+		TaskBacklog:        api.BacklogLevel{ID: "Microsoft.TaskCategory", Name: "Tasks", Type: "task"},
+	}
+	levels := backlogLevels(config)
+	if levels[0].Name != "Epics" || levels[3].Name != "Tasks" {
+		t.Fatalf("unexpected level order: %#v", levels)
+	}
+	cases := map[string]string{
+		"Requirements":           "Microsoft.RequirementCategory",
+		"Stories":                "Microsoft.RequirementCategory",
+		"features":               "Microsoft.FeatureCategory",
+		"Epic":                   "Microsoft.EpicCategory",
+		"Microsoft.TaskCategory": "Microsoft.TaskCategory",
+	}
+	for name, want := range cases {
+		level, err := findBacklogLevel(levels, name)
+		if err != nil || level.ID != want {
+			t.Fatalf("%s: expected %s, got %#v %v", name, want, level, err)
+		}
+	}
+	if _, err := findBacklogLevel(levels, "Initiatives"); err == nil {
+		t.Fatal("expected an error for an unknown level")
+	}
+}
+
+func TestSortByRankPutsUnrankedLast(t *testing.T) {
+	const rank = "Microsoft.VSTS.Common.StackRank"
+	items := []output.WorkItem{
+		{ID: 1, Fields: map[string]interface{}{}},
+		{ID: 2, Fields: map[string]interface{}{rank: 2000.5}},
+		{ID: 3, Fields: map[string]interface{}{rank: 1000.0}},
+	}
+	sortByRank(items, rank)
+	if items[0].ID != 3 || items[1].ID != 2 || items[2].ID != 1 {
+		t.Fatalf("unexpected order: %d %d %d", items[0].ID, items[1].ID, items[2].ID)
+	}
+	if _, err := reorderOperation([]int{5}, 0, 0, 0); err == nil {
+		t.Fatal("expected an error without --after or --before")
+	}
+	if _, err := reorderOperation([]int{5}, 5, 0, 0); err == nil {
+		t.Fatal("expected an error when an item is its own neighbour")
+	}
+}
+
+func testBoard() api.Board {
+	return api.Board{
+		Name: "Stories",
+		Columns: []api.BoardColumn{
+			{Name: "New", ColumnType: "incoming", StateMappings: map[string]string{"User Story": "New"}},
+			{Name: "Code Review", ColumnType: "inProgress", IsSplit: true, ItemLimit: 3, StateMappings: map[string]string{"User Story": "Active"}},
+			{Name: "Closed", ColumnType: "outgoing", StateMappings: map[string]string{"User Story": "Closed"}},
+		},
+		Rows: []api.BoardRow{{ID: "r0"}, {ID: "r1", Name: "Expedite"}},
+		Fields: api.BoardFields{
+			ColumnField: api.FieldReference{ReferenceName: "WEF_1_Kanban.Column"},
+			DoneField:   api.FieldReference{ReferenceName: "WEF_1_Kanban.Column.Done"},
+			RowField:    api.FieldReference{ReferenceName: "WEF_1_Kanban.Lane"},
+		},
+	}
+}
+
+func TestBuildBoardViewSplitsDoingAndDone(t *testing.T) {
+	story, newState, active := "User Story", "New", "Active"
+	items := []output.WorkItem{
+		{ID: 1, Type: &story, State: &active, Fields: map[string]interface{}{"WEF_1_Kanban.Column": "Code Review", "WEF_1_Kanban.Column.Done": false}},
+		{ID: 2, Type: &story, State: &active, Fields: map[string]interface{}{"WEF_1_Kanban.Column": "code review", "WEF_1_Kanban.Column.Done": true}},
+		{ID: 3, Type: &story, State: &newState, Fields: map[string]interface{}{}},
+	}
+	view := buildBoardView(testBoard(), items)
+	if len(view.Lanes) != 2 || view.Lanes[1] != "Expedite" {
+		t.Fatalf("unexpected lanes: %#v", view.Lanes)
+	}
+	if len(view.Columns[0].Items) != 1 || view.Columns[0].Items[0].ID != 3 {
+		t.Fatalf("expected the unplaced item in New by its state: %#v", view.Columns[0])
+	}
+	review := view.Columns[1]
+	if len(review.Items) != 1 || review.Items[0].ID != 1 || len(review.Done) != 1 || review.Done[0].ID != 2 {
+		t.Fatalf("unexpected Code Review column: %#v", review)
+	}
+}
+
+func TestPlanBoardMoveSetsColumnStateAndLane(t *testing.T) {
+	wi := api.WorkItem{ID: 7, Rev: 4, Fields: map[string]interface{}{"System.WorkItemType": "User Story", "System.State": "New"}}
+	patch, result, err := planBoardMove(testBoard(), wi, "code review", true, "expedite", true)
+	if err != nil {
+		t.Fatalf("planBoardMove returned error: %v", err)
+	}
+	if result.Column != "Code Review" || !result.Done || result.State != "Active" || result.Lane != "Expedite" {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+	want := map[string]interface{}{
+		"/rev":                             4,
+		"/fields/WEF_1_Kanban.Column":      "Code Review",
+		"/fields/WEF_1_Kanban.Column.Done": true,
+		"/fields/System.State":             "Active",
+		"/fields/WEF_1_Kanban.Lane":        "Expedite",
+	}
+	if len(patch) != len(want) {
+		t.Fatalf("unexpected patch: %#v", patch)
+	}
+	for _, op := range patch {
+		if value, ok := want[op["path"].(string)]; !ok || value != op["value"] {
+			t.Fatalf("unexpected op: %#v", op)
+		}
+	}
+	if _, _, err := planBoardMove(testBoard(), wi, "New", true, "", false); err == nil {
+		t.Fatal("expected an error for --done on a column that is not split")
+	}
+	if _, _, err := planBoardMove(testBoard(), wi, "Testing", false, "", false); err == nil {
+		t.Fatal("expected an error for an unknown column")
+	}
+}
+
+func testRevision(id, rev int, changed, iteration, state string, fields map[string]interface{}) api.WorkItem {
+	values := map[string]interface{}{
+		"System.WorkItemType":  "Task",
+		"System.ChangedDate":   changed,
+		"System.IterationPath": iteration,
+		"System.State":         state,
+	}
+	for name, value := range fields {
+		values[name] = value
+	}
+	return api.WorkItem{ID: id, Rev: rev, Fields: values}
+}
+
+func TestComputeBurndownReplaysRevisions(t *testing.T) {
+	const sprint = `RND\Sprint 1`
+	categories := map[string]string{"task|to do": stateCategoryProposed, "task|done": stateCategoryCompleted}
+	revisions := map[int][]api.WorkItem{
+		1: {
+			testRevision(1, 1, "2025-01-06T09:00:00Z", sprint, "To Do", map[string]interface{}{remainingWorkField: 8.0}),
+			testRevision(1, 2, "2025-01-07T15:00:00Z", sprint, "To Do", map[string]interface{}{remainingWorkField: 3.0}),
+			testRevision(1, 3, "2025-01-08T10:00:00Z", sprint, "Done", map[string]interface{}{remainingWorkField: 3.0}),
+		},
+		2: {
+			testRevision(2, 1, "2025-01-06T11:00:00Z", `RND\Backlog`, "To Do", map[string]interface{}{remainingWorkField: 4.0}),
+			testRevision(2, 2, "2025-01-07T08:00:00Z", sprint, "To Do", map[string]interface{}{remainingWorkField: 4.0}),
+		},
+	}
+	days := []time.Time{}
+	for _, value := range []string{"2025-01-06", "2025-01-07", "2025-01-08", "2025-01-09", "2025-01-10"} {
+		day, _ := sprintDate(value)
+		days = append(days, day)
+	}
+	series := computeBurndown(revisions, sprint, days, days[2], categories)
+	if len(series) != 3 {
+		t.Fatalf("expected points up to today, got %#v", series)
+	}
+	want := []burndownPoint{
+		{Date: "2025-01-06", Remaining: 8, Ideal: 8, OpenItems: 1},
+		{Date: "2025-01-07", Remaining: 7, Ideal: 6, OpenItems: 2},
+		{Date: "2025-01-08", Remaining: 4, Ideal: 4, OpenItems: 1},
+	}
+	for index, point := range want {
+		if series[index] != point {
+			t.Fatalf("day %d: expected %#v, got %#v", index, point, series[index])
+		}
+	}
+}
+
+func TestIterationRevisionsKeepsItemsMovedOut(t *testing.T) {
+	const sprint, next = `RND\Sprint 1`, `RND\Sprint 2`
+	const effort = "Microsoft.VSTS.Scheduling.StoryPoints"
+	fields := func(points, remaining float64) map[string]interface{} {
+		return map[string]interface{}{effort: points, remainingWorkField: remaining}
+	}
+	revisions := map[int][]api.WorkItem{
+		// Done in the sprint.
+		1: {
+			testRevision(1, 1, "2025-01-03T10:00:00Z", sprint, "Active", fields(5, 1)),
+			testRevision(1, 2, "2025-01-16T10:00:00Z", sprint, "Done", fields(5, 0)),
+		},
+		// Moved out mid-sprint.
+		2: {
+			testRevision(2, 1, "2025-01-03T10:00:00Z", sprint, "Active", fields(8, 4)),
+			testRevision(2, 2, "2025-01-08T09:00:00Z", next, "Active", fields(8, 4)),
+		},
+		// Carried over to the next sprint after the finish.
+		3: {
+			testRevision(3, 1, "2025-01-03T10:00:00Z", sprint, "Active", fields(3, 2)),
+			testRevision(3, 2, "2025-01-20T10:00:00Z", next, "Active", fields(3, 2)),
+		},
+	}
+	queries := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/_apis/wit/wiql") {
+			var body struct{ Query string }
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			queries = append(queries, body.Query)
+			ids := "1"
+			switch {
+			case strings.Contains(body.Query, "ASOF '2025-01-06"):
+				ids = "1,2,3"
+			case strings.Contains(body.Query, "ASOF '2025-01-18"):
+				ids = "1,3"
+			}
+			refs := []string{}
+			for _, id := range strings.Split(ids, ",") {
+				refs = append(refs, `{"id":`+id+`}`)
+			}
+			fmt.Fprintf(w, `{"workItems":[%s]}`, strings.Join(refs, ","))
+			return
+		}
+		var id int
+		if _, err := fmt.Sscanf(r.URL.Path, "/RND/_apis/wit/workItems/%d/revisions", &id); err != nil {
+			t.Fatalf("unexpected request %s", r.URL.Path)
+		}
+		payload, _ := json.Marshal(map[string]interface{}{"count": len(revisions[id]), "value": revisions[id]})
+		_, _ = w.Write(payload)
+	}))
+	defer server.Close()
+	client, err := api.NewClient(server.URL, "RND", "test-pat", false, false, nil)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	start, _ := sprintDate("2025-01-06")
+	finish, _ := sprintDate("2025-01-17")
+	times := sprintSelectionTimes(start, finish, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	got, err := iterationRevisions(context.Background(), client, sprint, nil, times)
+	if err != nil {
+		t.Fatalf("iterationRevisions: %v", err)
+	}
+	if len(queries) != 3 || len(got) != 3 {
+		t.Fatalf("expected three selections of three items, got %d queries and %d items", len(queries), len(got))
+	}
+
+	categories := map[string]string{"task|active": stateCategoryInProgress, "task|done": stateCategoryCompleted}
+	completed, late, incomplete := computeVelocity(got, sprint, effort, finish, categories)
+	if completed != 5 || late != 0 || incomplete != 3 {
+		t.Fatalf("unexpected velocity: completed %v, late %v, incomplete %v", completed, late, incomplete)
+	}
+	days := []time.Time{start, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2)}
+	series := computeBurndown(got, sprint, days, days[2], categories)
+	if series[0].Remaining != 7 || series[1].Remaining != 7 || series[2].Remaining != 3 {
+		t.Fatalf("unexpected burndown: %#v", series)
+	}
+}
+
+func TestComputeVelocitySeparatesLateWork(t *testing.T) {
+	const sprint = `RND\Sprint 1`
+	const effort = "Microsoft.VSTS.Scheduling.StoryPoints"
+	categories := map[string]string{"task|active": stateCategoryInProgress, "task|done": stateCategoryCompleted, "task|removed": stateCategoryRemoved}
+	revisions := map[int][]api.WorkItem{
+		1: {testRevision(1, 1, "2025-01-16T10:00:00Z", sprint, "Done", map[string]interface{}{effort: 5.0})},
+		2: {
+			testRevision(2, 1, "2025-01-10T10:00:00Z", sprint, "Active", map[string]interface{}{effort: 3.0}),
+			testRevision(2, 2, "2025-01-20T10:00:00Z", sprint, "Done", map[string]interface{}{effort: 3.0}),
+		},
+		3: {testRevision(3, 1, "2025-01-10T10:00:00Z", sprint, "Active", map[string]interface{}{effort: 2.0})},
+		4: {testRevision(4, 1, "2025-01-10T10:00:00Z", sprint, "Removed", map[string]interface{}{effort: 8.0})},
+	}
+	finish, _ := sprintDate("2025-01-17")
+	completed, late, incomplete := computeVelocity(revisions, sprint, effort, finish, categories)
+	if completed != 5 || late != 3 || incomplete != 2 {
+		t.Fatalf("unexpected velocity: completed %v, late %v, incomplete %v", completed, late, incomplete)
+	}
+	var out bytes.Buffer
+	printBarChart(&out, []chartRow{{Label: "Sprint 1", Value: 5, Mark: 10, HasMark: true}, {Label: "Sprint 2", Value: 10}})
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if !strings.HasPrefix(lines[0], "Sprint 1  "+strings.Repeat("#", 20)+strings.Repeat(" ", 20)+"|") || !strings.Contains(lines[1], strings.Repeat("#", chartWidth)+"  10") {
+		t.Fatalf("unexpected chart:\n%s", out.String())
+	}
+}
+
+func TestAsOfFlagsRejectQueriesWithOwnAsOf(t *testing.T) {
+	query := "SELECT [System.Id] FROM WorkItems ASOF '2024-12-01'"
+	for _, args := range [][]string{
+		{"wiql", query, "--asof", "2025-01-06"},
+		{"diff-snapshot", "--wiql", query, "--from", "2025-01-06"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := Run(args, &stdout, &stderr); code != 1 {
+			t.Fatalf("%s: expected exit code 1, got %d", args[0], code)
+		}
+		if !strings.Contains(stderr.String(), "invalid_args") || !strings.Contains(stderr.String(), "ASOF") {
+			t.Fatalf("%s: unexpected error: %s", args[0], stderr.String())
+		}
+	}
+}
+
+func TestDiffSnapshotsReportsAddedRemovedAndChanged(t *testing.T) {
+	item := func(id int, title, state string, assigned interface{}) api.WorkItem {
+		return api.WorkItem{ID: id, Fields: map[string]interface{}{"System.WorkItemType": "Bug", "System.Title": title, "System.State": state, "System.AssignedTo": assigned}}
+	}
+	ann := map[string]interface{}{"displayName": "Ann", "uniqueName": "ann@example.com"}
+	bob := map[string]interface{}{"displayName": "Bob", "uniqueName": "bob@example.com", "imageUrl": "x"}
+	from := map[int]api.WorkItem{1: item(1, "Crash", "Active", ann), 2: item(2, "Typo", "Active", nil), 3: item(3, "Slow", "New", ann)}
+	to := map[int]api.WorkItem{1: item(1, "Crash", "Resolved", bob), 3: item(3, "Slow", "New", map[string]interface{}{"displayName": "Ann"}), 4: item(4, "Leak", "New", nil)}
+	result := diffSnapshots([]int{1, 2, 3}, from, []int{4, 1, 3}, to, []string{"System.State", "System.AssignedTo"})
+	if len(result.Added) != 1 || result.Added[0].ID != 4 || len(result.Removed) != 1 || result.Removed[0].Title != "Typo" {
+		t.Fatalf("unexpected added/removed: %#v %#v", result.Added, result.Removed)
+	}
+	if result.Unchanged != 1 || len(result.Changed) != 1 || len(result.Changed[0].Fields) != 2 {
+		t.Fatalf("unexpected changes: %#v", result)
+	}
+	if change := result.Changed[0].Fields[0]; change.Field != "System.State" || change.OldValue != "Active" || change.NewValue != "Resolved" {
+		t.Fatalf("unexpected state change: %#v", change)
+	}
+	if _, err := parseAsOf("asof", "yesterday"); err == nil {
+		t.Fatal("expected an error for an unparseable date")
+	}
+}
+
+func TestWorkItemWatcherReportsChangesAfterBaseline(t *testing.T) {
+	item := func(id, rev int, state string, assigned interface{}, comments float64) api.WorkItem {
+		return api.WorkItem{ID: id, Rev: rev, Fields: map[string]interface{}{"System.WorkItemType": "Bug", "System.Title": "Crash", "System.State": state,
+			"System.AssignedTo": assigned, "System.CommentCount": comments, "System.ChangedBy": map[string]interface{}{"displayName": "Bob"}}}
+	}
+	ann := map[string]interface{}{"displayName": "Ann", "uniqueName": "ann@example.com"}
+	bob := map[string]interface{}{"displayName": "Bob", "uniqueName": "bob@example.com"}
+	rounds := [][]api.WorkItem{
+		{item(1, 3, "Active", ann, 1), item(2, 1, "New", nil, 0)},
+		{item(1, 5, "Active", bob, 2), item(3, 1, "New", nil, 0)},
+		{item(1, 5, "Active", bob, 2), item(3, 1, "New", nil, 0)},
+	}
+	round := 0
+	watcher := &workItemWatcher{
+		ids: func(context.Context) ([]int, error) {
+			ids := []int{}
+			for _, wi := range rounds[round] {
+				ids = append(ids, wi.ID)
+			}
+			return ids, nil
+		},
+		fetch: func(_ context.Context, ids []int, fields []string) ([]api.WorkItem, error) {
+			if !containsFold(fields, "System.CommentCount") {
+				t.Fatalf("expected the comment count to be fetched: %v", fields)
+			}
+			return rounds[round], nil
+		},
+		comments: func(_ context.Context, id int) ([]api.WorkItemComment, error) {
+			return []api.WorkItemComment{{Revision: 1, Text: "old"}, {Revision: 2, Text: "reassigning", RevisedBy: bob}}, nil
+		},
+		fields: []string{"System.State", "System.AssignedTo"},
+		now:    func() time.Time { return time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC) },
+	}
+	events, err := watcher.poll(context.Background())
+	if err != nil || len(events) != 0 {
+		t.Fatalf("expected no events on the first poll, got %#v %v", events, err)
+	}
+	round++
+	events, err = watcher.poll(context.Background())
+	if err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	kinds := []string{}
+	for _, event := range events {
+		kinds = append(kinds, fmt.Sprintf("%s:%d", event.Event, event.ID))
+	}
+	if strings.Join(kinds, ",") != "field_changed:1,new_comment:1,new_item:3,removed:2" {
+		t.Fatalf("unexpected events: %v", kinds)
+	}
+	if change := events[0]; change.Field != "System.AssignedTo" || change.ChangedBy != "Bob" || change.Rev != 5 || change.Time != "2025-01-06T09:00:00Z" {
+		t.Fatalf("unexpected change event: %#v", change)
+	}
+	if comment := events[1].Comment; comment == nil || comment.Text != "reassigning" || comment.Author != "Bob" {
+		t.Fatalf("unexpected comment event: %#v", events[1])
+	}
+	round++
+	if events, err = watcher.poll(context.Background()); err != nil || len(events) != 0 {
+		t.Fatalf("expected no events for unchanged revisions, got %#v %v", events, err)
+	}
+}
+
+func TestWorkItemWatcherRetriesCommentsWithoutRepeatingChanges(t *testing.T) {
+	item := func(rev int, state string, comments float64) api.WorkItem {
+		return api.WorkItem{ID: 1, Rev: rev, Fields: map[string]interface{}{"System.WorkItemType": "Bug", "System.Title": "Crash", "System.State": state, "System.CommentCount": comments}}
+	}
+	current := item(3, "Active", 0)
+	failComments := true
+	watcher := &workItemWatcher{
+		ids: func(context.Context) ([]int, error) { return []int{1}, nil },
+		fetch: func(context.Context, []int, []string) ([]api.WorkItem, error) {
+			return []api.WorkItem{current}, nil
+		},
+		comments: func(context.Context, int) ([]api.WorkItemComment, error) {
+			if failComments {
+				failComments = false
+				return nil, errs.New("http_error", "request failed with status 503", nil)
+			}
+			return []api.WorkItemComment{{Revision: 1, Text: "looking"}}, nil
+		},
+		fields: []string{"System.State"},
+		now:    time.Now,
+	}
+	if _, err := watcher.poll(context.Background()); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	current = item(5, "Resolved", 1)
+	events, err := watcher.poll(context.Background())
+	if err == nil || len(events) != 1 || events[0].Event != watchFieldChanged {
+		t.Fatalf("expected the state change and the comment error, got %#v %v", events, err)
+	}
+	events, err = watcher.poll(context.Background())
+	if err != nil || len(events) != 1 || events[0].Event != watchNewComment || events[0].Comment.Text != "looking" {
+		t.Fatalf("expected only the retried comment, got %#v %v", events, err)
+	}
+	if events, err = watcher.poll(context.Background()); err != nil || len(events) != 0 {
+		t.Fatalf("expected no more events, got %#v %v", events, err)
+	}
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"sort"
+	"strings"
+
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+// columnAliases maps the short names accepted by --columns to field
+// reference names. Anything containing a dot is taken as a reference name.
+var columnAliases = map[string]string{
+	"id":            "System.Id",
+	"type":          "System.WorkItemType",
+	"state":         "System.State",
+	"reason":        "System.Reason",
+	"title":         "System.Title",
+	"assigned":      "System.AssignedTo",
+	"assignedto":    "System.AssignedTo",
+	"area":          "System.AreaPath",
+	"iteration":     "System.IterationPath",
+	"tags":          "System.Tags",
+	"created":       "System.CreatedDate",
+	"createdby":     "System.CreatedBy",
+	"changed":       "System.ChangedDate",
+	"changedby":     "System.ChangedBy",
+	"rev":           "System.Rev",
+	"parent":        "System.Parent",
+	"priority":      "Microsoft.VSTS.Common.Priority",
+	"severity":      "Microsoft.VSTS.Common.Severity",
+	"activity":      "Microsoft.VSTS.Common.Activity",
+	"remaining":     "Microsoft.VSTS.Scheduling.RemainingWork",
+	"original":      "Microsoft.VSTS.Scheduling.OriginalEstimate",
+	"completed":     "Microsoft.VSTS.Scheduling.CompletedWork",
+	"effort":        "Microsoft.VSTS.Scheduling.Effort",
+	"storypoints":   "Microsoft.VSTS.Scheduling.StoryPoints",
+	"closed":        "Microsoft.VSTS.Common.ClosedDate",
+	"stackrank":     "Microsoft.VSTS.Common.StackRank",
+	"valuearea":     "Microsoft.VSTS.Common.ValueArea",
+	"businessvalue": "Microsoft.VSTS.Common.BusinessValue",
+}
+
+// parseColumns turns a --columns value into list columns. The ID column is
+// always first; an empty value returns nil so callers keep their default
+// output.
+func parseColumns(value string) ([]output.Column, error) {
+	names := splitCSV(value)
+	if len(names) == 0 {
+		return nil, nil
+	}
+	columns := []output.Column{{Key: "id", Header: "ID", Field: "System.Id"}}
+	seen := map[string]bool{"system.id": true}
+	for _, name := range names {
+		field := name
+		if !strings.Contains(name, ".") {
+			ref, ok := columnAliases[strings.ToLower(name)]
+			if !ok {
+				return nil, errs.New("invalid_args", "unknown column "+name+"; use a field reference name or an alias", columnAliasNames())
+			}
+			field = ref
+		}
+		if seen[strings.ToLower(field)] {
+			continue
+		}
+		seen[strings.ToLower(field)] = true
+		columns = append(columns, output.Column{
+			Key:    name,
+			Header: strings.ToUpper(columnHeader(name)),
+			Field:  field,
+		})
+	}
+	return columns, nil
+}
+
+// columnHeader shortens a reference name to its last segment.
+func columnHeader(name string) string {
+	if index := strings.LastIndex(name, "."); index >= 0 {
+		return name[index+1:]
+	}
+	return name
+}
+
+// columnFields lists the fields to request for columns, without System.Id
+// which the batch API always returns.
+func columnFields(columns []output.Column) []string {
+	fields := []string{}
+	for _, column := range columns {
+		if !strings.EqualFold(column.Field, "System.Id") {
+			fields = append(fields, column.Field)
+		}
+	}
+	return fields
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func columnAliasNames() []string {
+	names := make([]string, 0, len(columnAliases))
+	for name := range columnAliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
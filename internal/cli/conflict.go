@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+)
+
+// conflictDetails is reported with a "conflict" error: the revision the
+// update was tested against, the one the item is at now and the fields
+// changed in between.
+type conflictDetails struct {
+	ID          int                  `json:"id"`
+	ExpectedRev int                  `json:"expectedRev"`
+	CurrentRev  int                  `json:"currentRev"`
+	Fields      []historyFieldChange `json:"fields"`
+}
+
+// revisionTestOp makes an update fail with a conflict unless the work item
+// is still at rev.
+func revisionTestOp(rev int) map[string]interface{} {
+	return map[string]interface{}{"op": "test", "path": "/rev", "value": rev}
+}
+
+// withRevisionTest prepends a test of rev to patch unless it already tests
+// /rev.
+func withRevisionTest(patch []map[string]interface{}, rev int) []map[string]interface{} {
+	for _, op := range patch {
+		if op["op"] == "test" && op["path"] == "/rev" {
+			return patch
+		}
+	}
+	return append([]map[string]interface{}{revisionTestOp(rev)}, patch...)
+}
+
+// explainConflict adds the current revision and the fields changed since
+// expectedRev to a conflict returned by an update of work item id. Other
+// errors are returned unchanged, and so is the conflict when no revision was
+// tested, when the item is still at expectedRev (the conflict had another
+// cause) or when the current state cannot be read.
+func explainConflict(ctx context.Context, client *api.Client, id, expectedRev int, err error) error {
+	appErr, ok := err.(errs.AppError)
+	if !ok || appErr.Code != "conflict" || expectedRev <= 0 {
+		return err
+	}
+	current, getErr := client.GetWorkItem(ctx, id, nil, "None")
+	if getErr != nil || current.Rev == expectedRev {
+		return err
+	}
+	details := conflictDetails{ID: id, ExpectedRev: expectedRev, CurrentRev: current.Rev, Fields: []historyFieldChange{}}
+	if expectedRev < current.Rev {
+		if base, baseErr := client.GetWorkItemRevision(ctx, id, expectedRev); baseErr == nil {
+			details.Fields = changedFields(base.Fields, current.Fields)
+		}
+	}
+	return errs.New("conflict", fmt.Sprintf("work item %d is at revision %d, not %d; it was changed by someone else", id, current.Rev, expectedRev), details)
+}
+
+// changedFields lists the fields whose values differ between two revisions,
+// leaving out the bookkeeping fields that change on every save.
+func changedFields(before, after map[string]interface{}) []historyFieldChange {
+	names := map[string]bool{}
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		if _, noisy := historyBookkeepingFields[strings.ToLower(name)]; !noisy {
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+	changes := []historyFieldChange{}
+	for _, name := range sorted {
+		if reflect.DeepEqual(before[name], after[name]) {
+			continue
+		}
+		changes = append(changes, historyFieldChange{Field: name, OldValue: before[name], NewValue: after[name]})
+	}
+	return changes
+}
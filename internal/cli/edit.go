@@ -0,0 +1,442 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+// editDefaultFields are the front-matter keys written for every item; --fields
+// adds more by alias or reference name.
+var editDefaultFields = []string{"title", "state", "assigned", "area", "iteration", "tags"}
+
+// editReadOnlyKeys identify the item in the front matter and cannot be edited.
+var editReadOnlyKeys = []string{"id", "rev", "type"}
+
+const editHeader = "Edit the fields and the Markdown description below the closing ---.\n" +
+	"Remove a line to leave that field unchanged; leave a value empty to clear it.\n" +
+	"id, rev and type are read-only."
+
+// editField is one editable front-matter entry.
+type editField struct {
+	Key   string
+	Field string
+	Value interface{}
+}
+
+// editDocument is the content of the file opened in the editor.
+type editDocument struct {
+	ID          int
+	Rev         int
+	Type        string
+	Fields      []editField
+	Description string
+}
+
+// runEditor opens path in $VISUAL or $EDITOR and waits for it to exit. Tests
+// replace it.
+var runEditor = func(path string, stderr io.Writer) error {
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+func runEdit(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("edit", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	fieldsCSV := fs.String("fields", "", "Extra comma-separated fields to edit (aliases or reference names)")
+	idArg, rest := splitPositional(args, editValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if idArg == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id is required", nil), flags.json)
+		return 1
+	}
+	id, err := strconv.Atoi(idArg)
+	if err != nil || id <= 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id must be a positive number", nil), flags.json)
+		return 1
+	}
+	keys := append(append([]string{}, editDefaultFields...), splitCSV(*fieldsCSV)...)
+	if _, err := editFieldRefs(keys); err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	ctx, err := buildContext(flags, stdout, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	if ctx.project == "" {
+		output.WriteError(stderr, errs.New("config_missing", "project is required", nil), flags.json)
+		return 1
+	}
+	client, err := api.NewClient(ctx.baseURL, ctx.project, ctx.pat, ctx.insecure, ctx.verbose, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+
+	wi, err := client.GetWorkItem(context.Background(), id, nil, "None")
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	original, err := buildEditDocument(wi, keys)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	content, err := original.Marshal()
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	file, err := os.CreateTemp("", fmt.Sprintf("tfs-edit-%d-*.md", id))
+	if err != nil {
+		output.WriteError(stderr, errs.New("edit_failed", "failed to create temp file", err.Error()), ctx.jsonMode)
+		return 1
+	}
+	path := file.Name()
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		output.WriteError(stderr, errs.New("edit_failed", "failed to write temp file", err.Error()), ctx.jsonMode)
+		return 1
+	}
+	if err := runEditor(path, stderr); err != nil {
+		os.Remove(path)
+		output.WriteError(stderr, errs.New("editor_failed", "editor exited with an error", err.Error()), ctx.jsonMode)
+		return 1
+	}
+
+	// From here on the edited file is kept whenever the update does not go
+	// through, so the changes are not lost.
+	keep := func(err error) int {
+		appErr, ok := err.(errs.AppError)
+		if !ok {
+			appErr = errs.AppError{Code: "edit_failed", Message: err.Error()}
+		}
+		output.WriteError(stderr, errs.New(appErr.Code, appErr.Message, map[string]interface{}{
+			"details":  appErr.Details,
+			"editFile": path,
+		}), ctx.jsonMode)
+		return 1
+	}
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return keep(errs.New("edit_failed", "failed to read edited file", err.Error()))
+	}
+	patch, err := editPatch(original, edited)
+	if err != nil {
+		return keep(err)
+	}
+	if err := newFieldValidator(client).apply(context.Background(), original.Type, patch); err != nil {
+		return keep(err)
+	}
+	if len(patch) == 1 {
+		os.Remove(path)
+		return renderOutput(ctx, map[string]interface{}{"id": id, "rev": original.Rev, "changed": false}, func() output.Table {
+			return output.RecordTable([]string{"ID", "Rev", "Changed"}, id, original.Rev, false)
+		}, func() {
+			fmt.Fprintf(stdout, "No changes to work item %d.\n", id)
+		})
+	}
+	updated, err := client.UpdateWorkItem(context.Background(), id, patch)
+	if err != nil {
+		return keep(explainConflict(context.Background(), client, id, original.Rev, err))
+	}
+	os.Remove(path)
+	return renderWorkItem(ctx, updated)
+}
+
+// editFieldRefs resolves front-matter keys to field reference names. Keys
+// containing a dot are reference names; the rest are --columns aliases.
+func editFieldRefs(keys []string) ([]string, error) {
+	refs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		ref, err := editFieldRef(key)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+func editFieldRef(key string) (string, error) {
+	if strings.Contains(key, ".") {
+		return key, nil
+	}
+	ref, ok := columnAliases[strings.ToLower(key)]
+	if !ok {
+		return "", errs.New("invalid_args", "unknown field "+key+"; use a field reference name or an alias", columnAliasNames())
+	}
+	return ref, nil
+}
+
+// buildEditDocument takes the editable values of keys from wi. The
+// description is converted to Markdown.
+func buildEditDocument(wi api.WorkItem, keys []string) (editDocument, error) {
+	doc := editDocument{ID: wi.ID, Rev: wi.Rev}
+	if s, ok := wi.Fields["System.WorkItemType"].(string); ok {
+		doc.Type = s
+	}
+	if html, ok := wi.Fields["System.Description"].(string); ok {
+		doc.Description = htmlToMarkdown(html)
+	}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		ref, err := editFieldRef(key)
+		if err != nil {
+			return editDocument{}, err
+		}
+		lower := strings.ToLower(ref)
+		if seen[lower] || lower == "system.description" || isEditReadOnlyField(lower) {
+			continue
+		}
+		seen[lower] = true
+		doc.Fields = append(doc.Fields, editField{Key: key, Field: ref, Value: editValue(wi.Fields[ref])})
+	}
+	return doc, nil
+}
+
+func isEditReadOnlyField(ref string) bool {
+	switch strings.ToLower(ref) {
+	case "system.id", "system.rev", "system.workitemtype":
+		return true
+	}
+	return false
+}
+
+// editValue returns the front-matter form of a field value. Identities become
+// "Display Name <unique name>", which the server accepts back.
+func editValue(value interface{}) interface{} {
+	switch val := value.(type) {
+	case map[string]interface{}:
+		name := identityDisplayName(val)
+		if unique, ok := val["uniqueName"].(string); ok && unique != "" && unique != name {
+			return name + " <" + unique + ">"
+		}
+		return name
+	case float64:
+		if val == float64(int64(val)) {
+			return int64(val)
+		}
+	}
+	return value
+}
+
+// Marshal renders the document as YAML front matter followed by the
+// description.
+func (d editDocument) Marshal() ([]byte, error) {
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+	add := func(key string, value interface{}) error {
+		valueNode := &yaml.Node{}
+		if err := valueNode.Encode(value); err != nil {
+			return err
+		}
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, valueNode)
+		return nil
+	}
+	for _, entry := range []struct {
+		key   string
+		value interface{}
+	}{{"id", d.ID}, {"rev", d.Rev}, {"type", d.Type}} {
+		if err := add(entry.key, entry.value); err != nil {
+			return nil, err
+		}
+	}
+	mapping.Content[0].HeadComment = editHeader
+	for _, field := range d.Fields {
+		if err := add(field.Key, field.Value); err != nil {
+			return nil, errs.New("edit_failed", "failed to encode field "+field.Field, err.Error())
+		}
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(mapping); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	var doc bytes.Buffer
+	doc.WriteString("---\n")
+	doc.Write(buf.Bytes())
+	doc.WriteString("---\n\n")
+	if d.Description != "" {
+		doc.WriteString(d.Description)
+		doc.WriteString("\n")
+	}
+	return doc.Bytes(), nil
+}
+
+// splitEditDocument separates the front matter from the description.
+func splitEditDocument(content []byte) (string, string, error) {
+	text := strings.TrimPrefix(string(content), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if !strings.HasPrefix(text, "---\n") {
+		return "", "", errs.New("invalid_edit", "the edited file must start with a --- front-matter line", nil)
+	}
+	text = strings.TrimPrefix(text, "---\n")
+	if strings.HasPrefix(text, "---\n") || text == "---" {
+		return "", strings.TrimPrefix(text, "---"), nil
+	}
+	end := strings.Index(text, "\n---\n")
+	if end < 0 {
+		if !strings.HasSuffix(text, "\n---") {
+			return "", "", errs.New("invalid_edit", "the front matter must end with a --- line", nil)
+		}
+		return strings.TrimSuffix(text, "\n---"), "", nil
+	}
+	return text[:end], text[end+len("\n---\n"):], nil
+}
+
+// editPatch compares the edited file with the original document and returns
+// the JSON-patch for what changed. The patch always starts with a test of
+// /rev, so an edit made elsewhere in the meantime fails the update instead of
+// being overwritten.
+func editPatch(original editDocument, content []byte) ([]map[string]interface{}, error) {
+	frontMatter, body, err := splitEditDocument(content)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(frontMatter), &root); err != nil {
+		return nil, errs.New("invalid_edit", "front matter is not valid YAML", err.Error())
+	}
+	patch := []map[string]interface{}{revisionTestOp(original.Rev)}
+	pairs := []*yaml.Node{}
+	if len(root.Content) > 0 {
+		if root.Content[0].Kind != yaml.MappingNode {
+			return nil, errs.New("invalid_edit", "front matter must be a list of key: value lines", nil)
+		}
+		pairs = root.Content[0].Content
+	}
+
+	originals := map[string]editField{}
+	for _, field := range original.Fields {
+		originals[strings.ToLower(field.Field)] = field
+	}
+	readOnly := map[string]string{
+		"id":   strconv.Itoa(original.ID),
+		"rev":  strconv.Itoa(original.Rev),
+		"type": original.Type,
+	}
+	seen := map[string]bool{}
+	for index := 0; index+1 < len(pairs); index += 2 {
+		key, valueNode := pairs[index].Value, pairs[index+1]
+		if want, ok := readOnly[strings.ToLower(key)]; ok {
+			if valueNode.Kind != yaml.ScalarNode || valueNode.Value != want {
+				return nil, errs.New("invalid_edit", key+" is read-only", editReadOnlyKeys)
+			}
+			continue
+		}
+		ref, err := editFieldRef(key)
+		if err != nil {
+			return nil, errs.New("invalid_edit", "unknown field "+key+"; use a field reference name or an alias", columnAliasNames())
+		}
+		lower := strings.ToLower(ref)
+		if isEditReadOnlyField(lower) || lower == "system.description" {
+			return nil, errs.New("invalid_edit", key+" cannot be edited in the front matter", nil)
+		}
+		if seen[lower] {
+			return nil, errs.New("invalid_edit", "field "+key+" appears more than once", nil)
+		}
+		seen[lower] = true
+		if valueNode.Kind != yaml.ScalarNode {
+			return nil, errs.New("invalid_edit", "field "+key+" must have a single value", nil)
+		}
+		var before interface{}
+		if field, ok := originals[lower]; ok {
+			before = field.Value
+		}
+		if valueNode.Tag == "!!null" {
+			if editScalar(before) != "" {
+				patch = append(patch, map[string]interface{}{"op": "remove", "path": "/fields/" + ref})
+			}
+			continue
+		}
+		if valueNode.Value == editScalar(before) {
+			continue
+		}
+		var value interface{} = valueNode.Value
+		switch valueNode.Tag {
+		case "!!int", "!!float", "!!bool":
+			if err := valueNode.Decode(&value); err != nil {
+				return nil, errs.New("invalid_edit", "invalid value for "+key, err.Error())
+			}
+		default:
+			if value, err = normalizeWorkItemFieldValue(ref, valueNode.Value); err != nil {
+				return nil, err
+			}
+		}
+		patch = append(patch, map[string]interface{}{"op": "add", "path": "/fields/" + ref, "value": value})
+	}
+
+	description := strings.TrimSpace(body)
+	if description != strings.TrimSpace(original.Description) {
+		if description == "" {
+			patch = append(patch, map[string]interface{}{"op": "remove", "path": "/fields/System.Description"})
+		} else {
+			rendered, err := renderRichText(description)
+			if err != nil {
+				return nil, err
+			}
+			patch = append(patch, map[string]interface{}{"op": "add", "path": "/fields/System.Description", "value": rendered})
+		}
+	}
+	return patch, nil
+}
+
+// editScalar is the YAML scalar text of an original value, for comparison
+// with what the editor saved.
+func editScalar(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func editValueFlags() map[string]bool {
+	flags := wiqlValueFlags()
+	flags["fields"] = true
+	return flags
+}
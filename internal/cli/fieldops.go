@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"strings"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+)
+
+// tagSeparator joins System.Tags the way the web UI stores them.
+const tagSeparator = "; "
+
+// fieldEdits are the update operations that change part of a field value
+// instead of replacing it.
+type fieldEdits struct {
+	Unset      []string
+	Append     []string
+	AddTags    []string
+	RemoveTags []string
+}
+
+// needsCurrent reports whether the edits depend on the current field values.
+func (e fieldEdits) needsCurrent() bool {
+	return len(e.Append) > 0 || len(e.AddTags) > 0 || len(e.RemoveTags) > 0
+}
+
+func (e fieldEdits) count() int {
+	count := len(e.Unset) + len(e.Append)
+	if len(e.AddTags) > 0 || len(e.RemoveTags) > 0 {
+		count++
+	}
+	return count
+}
+
+// validateFieldEdits rejects edits that touch the same field twice, such as
+// --set and --unset of one field, or --set System.Tags with tag operations.
+func validateFieldEdits(sets []string, edits fieldEdits) error {
+	seen := map[string]string{}
+	claim := func(field, flagName string) error {
+		lower := strings.ToLower(field)
+		if previous, ok := seen[lower]; ok {
+			return errs.New("invalid_args", field+" is changed by both "+previous+" and "+flagName, nil)
+		}
+		seen[lower] = flagName
+		return nil
+	}
+	for _, set := range sets {
+		field, _, err := parseAssignment(set)
+		if err != nil {
+			return err
+		}
+		if err := claim(field, "--set"); err != nil {
+			return err
+		}
+	}
+	for _, field := range edits.Unset {
+		if strings.TrimSpace(field) == "" {
+			return errs.New("invalid_args", "--unset requires a field name", nil)
+		}
+		if err := claim(strings.TrimSpace(field), "--unset"); err != nil {
+			return err
+		}
+	}
+	for _, appendArg := range edits.Append {
+		if !strings.Contains(appendArg, "=") {
+			return errs.New("invalid_args", "invalid --append format, expected Field=Text", appendArg)
+		}
+		field, _, err := parseAssignment(appendArg)
+		if err != nil {
+			return err
+		}
+		if err := claim(field, "--append"); err != nil {
+			return err
+		}
+	}
+	if len(edits.AddTags) > 0 || len(edits.RemoveTags) > 0 {
+		if err := claim("System.Tags", "--add-tag/--remove-tag"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildFieldEditPatch turns edits into JSON-patch operations. current is the
+// work item the appends and tag changes are applied to; it is only read when
+// edits.needsCurrent().
+func buildFieldEditPatch(current api.WorkItem, edits fieldEdits) ([]map[string]interface{}, error) {
+	patch := []map[string]interface{}{}
+	for _, field := range edits.Unset {
+		patch = append(patch, map[string]interface{}{
+			"op":   "remove",
+			"path": "/fields/" + strings.TrimSpace(field),
+		})
+	}
+	for _, appendArg := range edits.Append {
+		field, text, err := parseAssignment(appendArg)
+		if err != nil {
+			return nil, err
+		}
+		value, err := appendFieldValue(field, currentFieldValue(current.Fields, field), text)
+		if err != nil {
+			return nil, err
+		}
+		patch = append(patch, map[string]interface{}{
+			"op":    "add",
+			"path":  "/fields/" + field,
+			"value": value,
+		})
+	}
+	if len(edits.AddTags) > 0 || len(edits.RemoveTags) > 0 {
+		existing, _ := currentFieldValue(current.Fields, "System.Tags").(string)
+		tags := editTags(existing, edits.AddTags, edits.RemoveTags)
+		if tags != strings.Join(splitTags(existing), tagSeparator) {
+			patch = append(patch, map[string]interface{}{
+				"op":    "add",
+				"path":  "/fields/System.Tags",
+				"value": tags,
+			})
+		}
+	}
+	return patch, nil
+}
+
+// currentFieldValue looks field up case-insensitively, since reference names
+// typed on the command line do not always match the server's casing.
+func currentFieldValue(fields map[string]interface{}, field string) interface{} {
+	if value, ok := fields[field]; ok {
+		return value
+	}
+	for name, value := range fields {
+		if strings.EqualFold(name, field) {
+			return value
+		}
+	}
+	return nil
+}
+
+// appendFieldValue adds text to the end of existing. Rich-text fields get the
+// text as new Markdown-rendered blocks; other fields get it after a space.
+func appendFieldValue(field string, existing interface{}, text string) (interface{}, error) {
+	rendered, err := normalizeWorkItemFieldValue(field, text)
+	if err != nil {
+		return nil, err
+	}
+	current := ""
+	if existing != nil {
+		current = editScalar(existing)
+	}
+	if current == "" {
+		return rendered, nil
+	}
+	if rendered == "" {
+		return current, nil
+	}
+	if _, rich := richTextFields[strings.ToLower(field)]; rich {
+		return current + rendered, nil
+	}
+	return current + " " + rendered, nil
+}
+
+// splitTags splits a System.Tags value or a tag argument on semicolons and
+// commas, dropping empty entries.
+func splitTags(value string) []string {
+	tags := []string{}
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' }) {
+		if tag := strings.TrimSpace(part); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// editTags removes and then adds tags, matching case-insensitively the way
+// TFS does. Existing tags keep their order and casing; new ones go last.
+func editTags(existing string, add, remove []string) string {
+	removed := map[string]bool{}
+	for _, arg := range remove {
+		for _, tag := range splitTags(arg) {
+			removed[strings.ToLower(tag)] = true
+		}
+	}
+	tags := []string{}
+	present := map[string]bool{}
+	for _, tag := range splitTags(existing) {
+		lower := strings.ToLower(tag)
+		if removed[lower] || present[lower] {
+			continue
+		}
+		present[lower] = true
+		tags = append(tags, tag)
+	}
+	for _, arg := range add {
+		for _, tag := range splitTags(arg) {
+			lower := strings.ToLower(tag)
+			if present[lower] {
+				continue
+			}
+			present[lower] = true
+			tags = append(tags, tag)
+		}
+	}
+	return strings.Join(tags, tagSeparator)
+}
@@ -0,0 +1,433 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+)
+
+// fieldDateLayouts are the date formats accepted for dateTime fields, tried in
+// order. Values without a zone are taken in local time.
+var fieldDateLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+// fieldValidator checks JSON-patch field values against the field
+// definitions of the collection and the fields of the target work item type,
+// and converts the strings given on the command line to the values the
+// server expects. Metadata is fetched once per validator and type.
+type fieldValidator struct {
+	listFields       func(context.Context) ([]api.FieldDefinition, error)
+	typeFields       func(context.Context, string) ([]api.WorkItemTypeField, error)
+	typeStates       func(context.Context, string) ([]api.WorkItemTypeState, error)
+	node             func(ctx context.Context, group, path string, depth int) (api.ClassificationNode, error)
+	searchIdentities func(context.Context, string) ([]api.Identity, error)
+	currentUser      func(context.Context) (interface{}, error)
+
+	definitions map[string]api.FieldDefinition
+	types       map[string]map[string]api.WorkItemTypeField
+	states      map[string][]api.WorkItemTypeState
+	nodePaths   map[string]string
+	identities  map[string]interface{}
+}
+
+func newFieldValidator(client *api.Client) *fieldValidator {
+	return &fieldValidator{
+		listFields:       client.ListFields,
+		typeFields:       client.GetWorkItemTypeFields,
+		typeStates:       client.GetWorkItemTypeStates,
+		node:             client.GetClassificationNode,
+		searchIdentities: client.SearchIdentities,
+		currentUser: func(ctx context.Context) (interface{}, error) {
+			return resolveCurrentAssignee(ctx, client)
+		},
+	}
+}
+
+// apply validates and converts the /fields/ operations of patch in place.
+// Reference names are corrected to the server's casing, and names without a
+// dot are looked up by display name, so "Remaining Work" or a localized name
+// works too. With an empty typeName only the collection-wide definitions are
+// checked.
+func (v *fieldValidator) apply(ctx context.Context, typeName string, patch []map[string]interface{}) error {
+	definitions, err := v.loadDefinitions(ctx)
+	if err != nil {
+		return err
+	}
+	var typeFields map[string]api.WorkItemTypeField
+	if typeName != "" {
+		if typeFields, err = v.loadType(ctx, typeName); err != nil {
+			return err
+		}
+	}
+	for _, op := range patch {
+		path, _ := op["path"].(string)
+		if !strings.HasPrefix(path, "/fields/") {
+			continue
+		}
+		name := strings.TrimPrefix(path, "/fields/")
+		lower := strings.ToLower(name)
+		definition, ok := definitions[lower]
+		if !ok && !strings.Contains(name, ".") {
+			if definition, err = definitionByName(definitions, name); err != nil {
+				return err
+			}
+			ok = definition.ReferenceName != ""
+			lower = strings.ToLower(definition.ReferenceName)
+		}
+		if !ok {
+			return errs.New("unknown_field", "field "+name+" does not exist; use a field reference name such as System.Title", name)
+		}
+		op["path"] = "/fields/" + definition.ReferenceName
+		typeField, onType := typeFields[lower]
+		// System fields exist on every type even when the type does not list
+		// them, so only other fields are checked for membership.
+		if typeFields != nil && !onType && !strings.HasPrefix(lower, "system.") {
+			return errs.New("invalid_field", fmt.Sprintf("field %s is not part of work item type %s", definition.ReferenceName, typeName), typeFieldNames(typeFields))
+		}
+		if op["op"] != "add" && op["op"] != "replace" {
+			continue
+		}
+		text, ok := op["value"].(string)
+		if !ok || strings.TrimSpace(text) == "" {
+			continue
+		}
+		value, err := v.convert(ctx, definition, text)
+		if err != nil {
+			return err
+		}
+		if g, isTree := classificationGroupOf(definition.ReferenceName); isTree && v.node != nil {
+			if value, err = v.classificationPath(ctx, g, text); err != nil {
+				return err
+			}
+		}
+		if onType && len(typeField.AllowedValues) > 0 && !isIdentityField(definition) {
+			if value, err = v.allowedValue(ctx, typeName, definition, typeField, value); err != nil {
+				return err
+			}
+		}
+		op["value"] = value
+	}
+	return nil
+}
+
+func (v *fieldValidator) loadDefinitions(ctx context.Context) (map[string]api.FieldDefinition, error) {
+	if v.definitions != nil {
+		return v.definitions, nil
+	}
+	list, err := v.listFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.definitions = make(map[string]api.FieldDefinition, len(list))
+	for _, definition := range list {
+		v.definitions[strings.ToLower(definition.ReferenceName)] = definition
+	}
+	return v.definitions, nil
+}
+
+func (v *fieldValidator) loadType(ctx context.Context, typeName string) (map[string]api.WorkItemTypeField, error) {
+	key := strings.ToLower(typeName)
+	if fields, ok := v.types[key]; ok {
+		return fields, nil
+	}
+	list, err := v.typeFields(ctx, typeName)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]api.WorkItemTypeField, len(list))
+	for _, field := range list {
+		fields[strings.ToLower(field.ReferenceName)] = field
+	}
+	if v.types == nil {
+		v.types = map[string]map[string]api.WorkItemTypeField{}
+	}
+	v.types[key] = fields
+	return fields, nil
+}
+
+func (v *fieldValidator) loadStates(ctx context.Context, typeName string) ([]api.WorkItemTypeState, error) {
+	key := strings.ToLower(typeName)
+	if states, ok := v.states[key]; ok {
+		return states, nil
+	}
+	states, err := v.typeStates(ctx, typeName)
+	if err != nil {
+		return nil, err
+	}
+	if v.states == nil {
+		v.states = map[string][]api.WorkItemTypeState{}
+	}
+	v.states[key] = states
+	return states, nil
+}
+
+// classificationPath checks that an area or iteration path exists and
+// returns it in the server's spelling.
+func (v *fieldValidator) classificationPath(ctx context.Context, g classificationGroup, value string) (string, error) {
+	key := g.group + "|" + strings.ToLower(strings.TrimSpace(value))
+	if path, ok := v.nodePaths[key]; ok {
+		return path, nil
+	}
+	missing := errs.New("invalid_path", fmt.Sprintf("%s %q does not exist; see tfs %s list", g.noun, value, g.command), map[string]interface{}{"field": g.field, "value": value})
+	segments := strings.FieldsFunc(value, func(r rune) bool { return r == '/' || r == '\\' })
+	if len(segments) == 0 {
+		return "", missing
+	}
+	project := strings.TrimSpace(segments[0])
+	relative := nodeRelativePath(project, value)
+	wanted := nodeFieldPath(project, relative)
+	node, err := v.node(ctx, g.group, relative, 0)
+	if api.IsStatus(err, http.StatusNotFound) {
+		return "", missing
+	}
+	if err != nil {
+		return "", err
+	}
+	path := classificationFieldPath(node, wanted)
+	if !strings.EqualFold(path, wanted) {
+		return "", missing
+	}
+	if v.nodePaths == nil {
+		v.nodePaths = map[string]string{}
+	}
+	v.nodePaths[key] = path
+	return path, nil
+}
+
+// definitionByName finds the field whose display name, or the last part of
+// whose reference name, matches name ignoring case, spaces, dashes and
+// underscores. No match returns an empty definition.
+func definitionByName(definitions map[string]api.FieldDefinition, name string) (api.FieldDefinition, error) {
+	normalize := strings.NewReplacer(" ", "", "-", "", "_", "")
+	key := strings.ToLower(normalize.Replace(name))
+	matches := []api.FieldDefinition{}
+	for _, definition := range definitions {
+		short := definition.ReferenceName[strings.LastIndex(definition.ReferenceName, ".")+1:]
+		if strings.ToLower(normalize.Replace(definition.Name)) == key || strings.ToLower(short) == key {
+			matches = append(matches, definition)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return api.FieldDefinition{}, nil
+	case 1:
+		return matches[0], nil
+	}
+	refs := make([]string, 0, len(matches))
+	for _, match := range matches {
+		refs = append(refs, match.ReferenceName)
+	}
+	sort.Strings(refs)
+	return api.FieldDefinition{}, errs.New("ambiguous_field", "field name "+name+" matches several fields; use a reference name", refs)
+}
+
+// convert turns text into the JSON value for a field of definition's type.
+func (v *fieldValidator) convert(ctx context.Context, definition api.FieldDefinition, text string) (interface{}, error) {
+	trimmed := strings.TrimSpace(text)
+	invalid := func(expected string) error {
+		return errs.New("invalid_value", fmt.Sprintf("%s expects %s, got %q", definition.ReferenceName, expected, trimmed), map[string]interface{}{
+			"field": definition.ReferenceName,
+			"type":  definition.Type,
+			"value": trimmed,
+		})
+	}
+	if isIdentityField(definition) {
+		return v.resolveIdentity(ctx, trimmed)
+	}
+	switch definition.Type {
+	case "integer", "picklistInteger":
+		n, err := strconv.Atoi(trimmed)
+		if err != nil {
+			return nil, invalid("a whole number")
+		}
+		return n, nil
+	case "double", "picklistDouble":
+		n, err := strconv.ParseFloat(strings.Replace(trimmed, ",", ".", 1), 64)
+		if err != nil {
+			return nil, invalid("a number")
+		}
+		return n, nil
+	case "dateTime":
+		date, ok := parseFieldDate(trimmed)
+		if !ok {
+			return nil, invalid("a date such as 2024-05-31 or 2024-05-31T17:00:00Z")
+		}
+		return date.UTC().Format(time.RFC3339), nil
+	case "boolean":
+		switch strings.ToLower(trimmed) {
+		case "true", "yes", "1":
+			return true, nil
+		case "false", "no", "0":
+			return false, nil
+		}
+		return nil, invalid("true or false")
+	}
+	return text, nil
+}
+
+func isIdentityField(definition api.FieldDefinition) bool {
+	return definition.IsIdentity || definition.Type == "identity"
+}
+
+func parseFieldDate(value string) (time.Time, bool) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, true
+	}
+	for _, layout := range fieldDateLayouts {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// allowedValue checks value against the picklist of a type field, ignoring
+// case, and returns it in the server's spelling. A value the picklist does not
+// have is translated when it is a known English name of a localized value or,
+// for System.State, a state category.
+func (v *fieldValidator) allowedValue(ctx context.Context, typeName string, definition api.FieldDefinition, field api.WorkItemTypeField, value interface{}) (interface{}, error) {
+	text := editScalar(value)
+	allowed := make([]string, 0, len(field.AllowedValues))
+	for _, candidate := range field.AllowedValues {
+		candidateText := editScalar(candidate)
+		if strings.EqualFold(candidateText, text) {
+			if _, isString := value.(string); isString {
+				return candidateText, nil
+			}
+			return value, nil
+		}
+		allowed = append(allowed, candidateText)
+	}
+	ref := strings.ToLower(definition.ReferenceName)
+	for _, localized := range defaultValueAliases[ref][strings.ToLower(text)] {
+		if containsFold(allowed, localized) {
+			return localized, nil
+		}
+	}
+	if ref == "system.state" && typeName != "" && v.typeStates != nil {
+		if category := stateCategory(text); category != "" {
+			states, err := statesInCategory(ctx, v.loadStates, []string{typeName}, category)
+			if err != nil {
+				return nil, err
+			}
+			if len(states) > 0 {
+				return states[0], nil
+			}
+		}
+	}
+	return nil, errs.New("invalid_value", fmt.Sprintf("%q is not an allowed value of %s", text, definition.ReferenceName), map[string]interface{}{
+		"field":         definition.ReferenceName,
+		"value":         text,
+		"allowedValues": allowed,
+	})
+}
+
+// resolveIdentity turns a name, account or email into the "Display Name
+// <unique name>" form the server resolves without ambiguity. @me is the PAT
+// owner. Values already in that form are kept, and so is the text when the
+// identity search itself is not available.
+func (v *fieldValidator) resolveIdentity(ctx context.Context, value string) (interface{}, error) {
+	if strings.EqualFold(value, "@me") {
+		return v.currentUser(ctx)
+	}
+	if strings.HasSuffix(value, ">") && strings.Contains(value, "<") {
+		return value, nil
+	}
+	key := strings.ToLower(value)
+	if resolved, ok := v.identities[key]; ok {
+		return resolved, nil
+	}
+	matches, err := v.searchIdentities(ctx, value)
+	if api.IsStatus(err, http.StatusNotFound) || api.IsStatus(err, http.StatusNotImplemented) {
+		return value, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) > 1 {
+		exact := []api.Identity{}
+		for _, identity := range matches {
+			if identityMatches(identity, value) {
+				exact = append(exact, identity)
+			}
+		}
+		if len(exact) > 0 {
+			matches = exact
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, errs.New("identity_not_found", "no user or group matches "+value, value)
+	case 1:
+	default:
+		candidates := make([]string, 0, len(matches))
+		for _, identity := range matches {
+			candidates = append(candidates, identityPatchValue(identity))
+		}
+		return nil, errs.New("ambiguous_identity", fmt.Sprintf("%q matches %d users; use the email or account name", value, len(matches)), candidates)
+	}
+	resolved := identityPatchValue(matches[0])
+	if v.identities == nil {
+		v.identities = map[string]interface{}{}
+	}
+	v.identities[key] = resolved
+	return resolved, nil
+}
+
+func identityMatches(identity api.Identity, value string) bool {
+	account := identityProperty(identity, "Account")
+	for _, candidate := range []string{
+		identity.ProviderDisplayName,
+		identityProperty(identity, "Mail"),
+		account,
+		identityProperty(identity, "Domain") + `\` + account,
+	} {
+		if candidate != "" && strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func identityPatchValue(identity api.Identity) string {
+	unique := identityProperty(identity, "Mail")
+	if unique == "" {
+		unique = identityProperty(identity, "Account")
+		if domain := identityProperty(identity, "Domain"); domain != "" && unique != "" {
+			unique = domain + `\` + unique
+		}
+	}
+	if unique == "" || unique == identity.ProviderDisplayName {
+		return identity.ProviderDisplayName
+	}
+	return identity.ProviderDisplayName + " <" + unique + ">"
+}
+
+func typeFieldNames(fields map[string]api.WorkItemTypeField) []string {
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		names = append(names, field.ReferenceName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// workItemType returns the System.WorkItemType of wi.
+func workItemType(wi api.WorkItem) string {
+	typeName, _ := wi.Fields["System.WorkItemType"].(string)
+	return typeName
+}
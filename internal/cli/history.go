@@ -0,0 +1,277 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+// historyBookkeepingFields change on every revision and only add noise to the
+// field-level history; --all-fields brings them back.
+var historyBookkeepingFields = map[string]struct{}{
+	"system.rev":            {},
+	"system.revisedby":      {},
+	"system.reviseddate":    {},
+	"system.changedby":      {},
+	"system.changeddate":    {},
+	"system.authorizedas":   {},
+	"system.authorizeddate": {},
+	"system.personid":       {},
+	"system.watermark":      {},
+}
+
+type historyFieldChange struct {
+	Field    string      `json:"field"`
+	OldValue interface{} `json:"oldValue"`
+	NewValue interface{} `json:"newValue"`
+}
+
+type historyRelationChange struct {
+	Action string `json:"action"`
+	Rel    string `json:"rel"`
+	URL    string `json:"url"`
+	ID     int    `json:"id,omitempty"`
+}
+
+type historyEntry struct {
+	Rev         int                     `json:"rev"`
+	RevisedBy   string                  `json:"revisedBy"`
+	RevisedDate string                  `json:"revisedDate,omitempty"`
+	Fields      []historyFieldChange    `json:"fields"`
+	Relations   []historyRelationChange `json:"relations,omitempty"`
+}
+
+func runHistory(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	var top int
+	var fieldsCSV string
+	fs.IntVar(&top, "top", 0, "Show only the latest N revisions (0 = all)")
+	fs.StringVar(&fieldsCSV, "fields", "", "Only show changes to these comma-separated fields")
+	allFields := fs.Bool("all-fields", false, "Include bookkeeping fields such as System.ChangedDate and System.Rev")
+	idArg, rest := splitPositional(args, historyValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if idArg == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id is required", nil), flags.json)
+		return 1
+	}
+	id, err := strconv.Atoi(idArg)
+	if err != nil || id <= 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id must be a positive number", nil), flags.json)
+		return 1
+	}
+	if top < 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "top must not be negative", top), flags.json)
+		return 1
+	}
+	ctx, err := buildContext(flags, stdout, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	if ctx.project == "" {
+		output.WriteError(stderr, errs.New("config_missing", "project is required", nil), flags.json)
+		return 1
+	}
+	client, err := api.NewClient(ctx.baseURL, ctx.project, ctx.pat, ctx.insecure, ctx.verbose, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	// The updates are listed oldest first, so the latest N are only known
+	// once all of them are fetched.
+	updates, err := client.GetWorkItemUpdates(context.Background(), id, 0)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	if top > 0 && len(updates) > top {
+		updates = updates[len(updates)-top:]
+	}
+	entries := buildHistoryEntries(updates, splitCSV(fieldsCSV), *allFields)
+	return renderHistory(ctx, id, entries)
+}
+
+func buildHistoryEntries(updates []api.WorkItemUpdate, fieldFilter []string, allFields bool) []historyEntry {
+	filter := map[string]bool{}
+	for _, field := range fieldFilter {
+		filter[strings.ToLower(field)] = true
+	}
+	entries := make([]historyEntry, 0, len(updates))
+	for _, update := range updates {
+		entry := historyEntry{
+			Rev:         update.Rev,
+			RevisedBy:   identityDisplayName(update.RevisedBy),
+			RevisedDate: historyRevisedDate(update),
+			Fields:      []historyFieldChange{},
+		}
+		names := make([]string, 0, len(update.Fields))
+		for name := range update.Fields {
+			lower := strings.ToLower(name)
+			if len(filter) > 0 && !filter[lower] {
+				continue
+			}
+			if _, noisy := historyBookkeepingFields[lower]; noisy && !allFields && !filter[lower] {
+				continue
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			change := update.Fields[name]
+			entry.Fields = append(entry.Fields, historyFieldChange{
+				Field:    name,
+				OldValue: change.OldValue,
+				NewValue: change.NewValue,
+			})
+		}
+		if update.Relations != nil && len(filter) == 0 {
+			entry.Relations = append(entry.Relations, historyRelationChanges("added", update.Relations.Added)...)
+			entry.Relations = append(entry.Relations, historyRelationChanges("removed", update.Relations.Removed)...)
+			entry.Relations = append(entry.Relations, historyRelationChanges("updated", update.Relations.Updated)...)
+		}
+		if len(entry.Fields) == 0 && len(entry.Relations) == 0 {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func historyRevisedDate(update api.WorkItemUpdate) string {
+	if change, ok := update.Fields["System.ChangedDate"]; ok {
+		if value, ok := change.NewValue.(string); ok && value != "" {
+			return value
+		}
+	}
+	// The updates API reports 9999-01-01 for the latest revision.
+	if strings.HasPrefix(update.RevisedDate, "9999-") {
+		return ""
+	}
+	return update.RevisedDate
+}
+
+func historyRelationChanges(action string, relations []interface{}) []historyRelationChange {
+	changes := []historyRelationChange{}
+	for _, raw := range relations {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rel, _ := m["rel"].(string)
+		url, _ := m["url"].(string)
+		changes = append(changes, historyRelationChange{
+			Action: action,
+			Rel:    rel,
+			URL:    url,
+			ID:     idFromURL(url),
+		})
+	}
+	return changes
+}
+
+func renderHistory(ctx commandContext, id int, entries []historyEntry) int {
+	payload := map[string]interface{}{
+		"id":        id,
+		"revisions": entries,
+	}
+	return renderOutput(ctx, payload, func() output.Table {
+		return historyTable(entries)
+	}, func() {
+		printHistory(ctx.stdout, id, entries)
+	})
+}
+
+// historyTable has one row per changed field or relation of each revision.
+func historyTable(entries []historyEntry) output.Table {
+	table := output.Table{Headers: []string{"Rev", "RevisedBy", "RevisedDate", "Field", "OldValue", "NewValue"}}
+	for _, entry := range entries {
+		prefix := []string{strconv.Itoa(entry.Rev), entry.RevisedBy, entry.RevisedDate}
+		for _, change := range entry.Fields {
+			table.Rows = append(table.Rows, append(append([]string{}, prefix...), change.Field,
+				output.FormatColumnValue(change.OldValue), output.FormatColumnValue(change.NewValue)))
+		}
+		for _, change := range entry.Relations {
+			target := change.URL
+			if change.ID > 0 {
+				target = strconv.Itoa(change.ID)
+			}
+			oldValue, newValue := "", target
+			if change.Action == "removed" {
+				oldValue, newValue = target, ""
+			}
+			table.Rows = append(table.Rows, append(append([]string{}, prefix...), "Relation "+change.Rel, oldValue, newValue))
+		}
+	}
+	return table
+}
+
+func printHistory(w io.Writer, id int, entries []historyEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintf(w, "No changes recorded for work item %d\n", id)
+		return
+	}
+	for index, entry := range entries {
+		if index > 0 {
+			fmt.Fprintln(w, "")
+		}
+		metadata := []string{fmt.Sprintf("Revision %d", entry.Rev)}
+		if entry.RevisedBy != "" {
+			metadata = append(metadata, entry.RevisedBy)
+		}
+		if entry.RevisedDate != "" {
+			metadata = append(metadata, entry.RevisedDate)
+		}
+		fmt.Fprintln(w, strings.Join(metadata, " | "))
+		for _, change := range entry.Fields {
+			fmt.Fprintf(w, "  %s: %s -> %s\n", change.Field, formatHistoryValue(change.OldValue), formatHistoryValue(change.NewValue))
+		}
+		for _, change := range entry.Relations {
+			target := change.URL
+			if change.ID > 0 {
+				target = strconv.Itoa(change.ID)
+			}
+			fmt.Fprintf(w, "  Relation %s: %s %s\n", change.Action, change.Rel, target)
+		}
+	}
+}
+
+func formatHistoryValue(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return "(empty)"
+	case string:
+		if val == "" {
+			return "(empty)"
+		}
+		return strings.ReplaceAll(val, "\n", `\n`)
+	case map[string]interface{}:
+		if name := identityDisplayName(val); name != "" {
+			return name
+		}
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func historyValueFlags() map[string]bool {
+	flags := wiqlValueFlags()
+	flags["fields"] = true
+	return flags
+}
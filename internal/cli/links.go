@@ -0,0 +1,524 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+const (
+	parentRelation    = "System.LinkTypes.Hierarchy-Reverse"
+	childRelation     = "System.LinkTypes.Hierarchy-Forward"
+	hyperlinkRelation = "Hyperlink"
+	artifactRelation  = "ArtifactLink"
+)
+
+var relationAliases = map[string]string{
+	"parent":       parentRelation,
+	"child":        childRelation,
+	"related":      "System.LinkTypes.Related",
+	"predecessor":  "System.LinkTypes.Dependency-Reverse",
+	"successor":    "System.LinkTypes.Dependency-Forward",
+	"duplicate":    "System.LinkTypes.Duplicate-Forward",
+	"duplicate-of": "System.LinkTypes.Duplicate-Reverse",
+	"tested-by":    "Microsoft.VSTS.Common.TestedBy-Forward",
+	"tests":        "Microsoft.VSTS.Common.TestedBy-Reverse",
+	"hyperlink":    hyperlinkRelation,
+	"artifact":     artifactRelation,
+	"commit":       artifactRelation,
+	"pr":           artifactRelation,
+	"pull-request": artifactRelation,
+}
+
+var relationNames = map[string]string{
+	parentRelation:                           "parent",
+	childRelation:                            "child",
+	"System.LinkTypes.Related":               "related",
+	"System.LinkTypes.Dependency-Reverse":    "predecessor",
+	"System.LinkTypes.Dependency-Forward":    "successor",
+	"System.LinkTypes.Duplicate-Forward":     "duplicate",
+	"System.LinkTypes.Duplicate-Reverse":     "duplicate-of",
+	"Microsoft.VSTS.Common.TestedBy-Forward": "tested-by",
+	"Microsoft.VSTS.Common.TestedBy-Reverse": "tests",
+	hyperlinkRelation:                        "hyperlink",
+	artifactRelation:                         "artifact",
+	attachedFileRel:                          "attachment",
+}
+
+type workItemRelation struct {
+	Index      int                    `json:"-"`
+	Type       string                 `json:"type"`
+	Rel        string                 `json:"rel"`
+	URL        string                 `json:"url"`
+	ID         int                    `json:"id,omitempty"`
+	Name       string                 `json:"name,omitempty"`
+	Comment    string                 `json:"comment,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type linkTarget struct {
+	Rel        string
+	URL        string
+	WorkItemID int
+	Name       string
+}
+
+func runLink(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "link subcommand is required", nil), true)
+		return 1
+	}
+	switch args[0] {
+	case "add":
+		return runLinkChange(args[1:], stdout, stderr, true)
+	case "remove":
+		return runLinkChange(args[1:], stdout, stderr, false)
+	case "list":
+		return runLinkList(args[1:], stdout, stderr)
+	default:
+		output.WriteError(stderr, errs.New("unknown_command", "unknown link subcommand", args[0]), true)
+		return 1
+	}
+}
+
+func runLinkChange(args []string, stdout, stderr io.Writer, add bool) int {
+	name := "link remove"
+	if add {
+		name = "link add"
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	comment := fs.String("comment", "", "Link comment")
+	repository := fs.String("repository", "", "Repository name or ID (commit and pr links)")
+	idArg, rest := splitPositional(args, linkValueFlags())
+	typeArg, rest := splitPositional(rest, linkValueFlags())
+	targetArg, rest := splitPositional(rest, linkValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if idArg == "" || typeArg == "" || targetArg == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id, link type, and target are required", nil), flags.json)
+		return 1
+	}
+	id, err := strconv.Atoi(idArg)
+	if err != nil || id <= 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id must be a positive number", nil), flags.json)
+		return 1
+	}
+	ctx, err := buildContext(flags, stdout, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	if ctx.project == "" {
+		output.WriteError(stderr, errs.New("config_missing", "project is required", nil), flags.json)
+		return 1
+	}
+	client, err := api.NewClient(ctx.baseURL, ctx.project, ctx.pat, ctx.insecure, ctx.verbose, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	target, err := resolveLinkTarget(context.Background(), client, typeArg, targetArg, *repository)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	wi, err := client.GetWorkItem(context.Background(), id, nil, "relations")
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	var patch []map[string]interface{}
+	if add {
+		patch, err = buildAddLinkPatch(wi.Relations, target, *comment)
+	} else {
+		patch, err = buildRemoveLinkPatch(wi.Relations, target)
+	}
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	if wi.Rev > 0 {
+		patch = withRevisionTest(patch, wi.Rev)
+	}
+	updated, err := client.UpdateWorkItem(context.Background(), id, patch)
+	if err != nil {
+		output.WriteError(stderr, explainConflict(context.Background(), client, id, wi.Rev, err), ctx.jsonMode)
+		return 1
+	}
+	if !ctx.jsonMode {
+		verb := "Removed"
+		if add {
+			verb = "Added"
+		}
+		fmt.Fprintf(ctx.stdout, "%s %s link on work item %d: %s\n", verb, relationDisplayName(target.Rel), id, linkTargetLabel(target))
+		return 0
+	}
+	return renderWorkItem(ctx, updated)
+}
+
+func runLinkList(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("link list", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	typeFilter := fs.String("type", "", "Only list links of this type (alias or reference name)")
+	idArg, rest := splitPositional(args, linkValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if idArg == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id is required", nil), flags.json)
+		return 1
+	}
+	id, err := strconv.Atoi(idArg)
+	if err != nil || id <= 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "work item id must be a positive number", nil), flags.json)
+		return 1
+	}
+	relFilter := ""
+	if strings.TrimSpace(*typeFilter) != "" {
+		relFilter, err = resolveRelationType(*typeFilter)
+		if err != nil {
+			output.WriteError(stderr, err, flags.json)
+			return 1
+		}
+	}
+	ctx, err := buildContext(flags, stdout, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	if ctx.project == "" {
+		output.WriteError(stderr, errs.New("config_missing", "project is required", nil), flags.json)
+		return 1
+	}
+	client, err := api.NewClient(ctx.baseURL, ctx.project, ctx.pat, ctx.insecure, ctx.verbose, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	wi, err := client.GetWorkItem(context.Background(), id, nil, "relations")
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	relations := []workItemRelation{}
+	for _, relation := range parseRelations(wi.Relations) {
+		if relFilter != "" && !strings.EqualFold(relation.Rel, relFilter) {
+			continue
+		}
+		relations = append(relations, relation)
+	}
+	return renderOutput(ctx, relations, func() output.Table {
+		table := output.Table{Headers: []string{"Type", "Rel", "ID", "URL", "Name", "Comment"}}
+		for _, relation := range relations {
+			id := ""
+			if relation.ID > 0 {
+				id = strconv.Itoa(relation.ID)
+			}
+			table.Rows = append(table.Rows, []string{relation.Type, relation.Rel, id, relation.URL, relation.Name, relation.Comment})
+		}
+		return table
+	}, func() {
+		printRelationTable(ctx.stdout, relations)
+	})
+}
+
+func resolveRelationType(value string) (string, error) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return "", errs.New("invalid_args", "link type is required", nil)
+	}
+	key := strings.ToLower(strings.NewReplacer(" ", "-", "_", "-").Replace(trimmed))
+	if rel, ok := relationAliases[key]; ok {
+		return rel, nil
+	}
+	for rel := range relationNames {
+		if strings.EqualFold(rel, trimmed) {
+			return rel, nil
+		}
+	}
+	if strings.Contains(trimmed, ".") {
+		return trimmed, nil
+	}
+	return "", errs.New("invalid_args", "unknown link type; use an alias such as related, parent, child, predecessor, successor, duplicate, tested-by, hyperlink, commit, pr, or a relation reference name", value)
+}
+
+func resolveLinkTarget(ctx context.Context, client *api.Client, linkType, target, repository string) (linkTarget, error) {
+	rel, err := resolveRelationType(linkType)
+	if err != nil {
+		return linkTarget{}, err
+	}
+	target = strings.TrimSpace(target)
+	kind := strings.ToLower(strings.NewReplacer(" ", "-", "_", "-").Replace(strings.TrimSpace(linkType)))
+	switch {
+	case rel == hyperlinkRelation:
+		if !isURL(target) {
+			return linkTarget{}, errs.New("invalid_args", "hyperlink target must be an http(s) URL", target)
+		}
+		return linkTarget{Rel: rel, URL: target}, nil
+	case kind == "commit" || kind == "pr" || kind == "pull-request":
+		if strings.TrimSpace(repository) == "" {
+			return linkTarget{}, errs.New("invalid_args", "--repository is required for commit and pr links", nil)
+		}
+		repo, err := client.GetRepository(ctx, strings.TrimSpace(repository))
+		if err != nil {
+			return linkTarget{}, err
+		}
+		if repo.Project == nil || repo.Project.ID == "" || repo.ID == "" {
+			return linkTarget{}, errs.New("invalid_response", "repository response is missing project or repository id", repository)
+		}
+		if kind == "commit" {
+			return linkTarget{Rel: rel, URL: gitArtifactURL("Commit", repo.Project.ID, repo.ID, target), Name: "Fixed in Commit"}, nil
+		}
+		prID, err := strconv.Atoi(target)
+		if err != nil || prID <= 0 {
+			return linkTarget{}, errs.New("invalid_args", "pull request id must be a positive number", target)
+		}
+		return linkTarget{Rel: rel, URL: gitArtifactURL("PullRequestId", repo.Project.ID, repo.ID, target), Name: "Pull Request"}, nil
+	case rel == artifactRelation:
+		if !strings.HasPrefix(strings.ToLower(target), "vstfs:///") {
+			return linkTarget{}, errs.New("invalid_args", "artifact target must be a vstfs:/// URI", target)
+		}
+		return linkTarget{Rel: rel, URL: target}, nil
+	default:
+		id, err := strconv.Atoi(target)
+		if err != nil || id <= 0 {
+			return linkTarget{}, errs.New("invalid_args", "link target must be a positive work item id", target)
+		}
+		return linkTarget{Rel: rel, URL: client.WorkItemURL(id), WorkItemID: id}, nil
+	}
+}
+
+func gitArtifactURL(kind, projectID, repositoryID, value string) string {
+	return "vstfs:///Git/" + kind + "/" + url.PathEscape(projectID+"/"+repositoryID+"/"+value)
+}
+
+func buildAddLinkPatch(existing []interface{}, target linkTarget, comment string) ([]map[string]interface{}, error) {
+	if matches := findRelations(parseRelations(existing), target); len(matches) > 0 {
+		return nil, errs.New("link_exists", "work item already has this link", map[string]interface{}{
+			"rel": target.Rel,
+			"url": matches[0].URL,
+		})
+	}
+	attributes := map[string]interface{}{}
+	if target.Name != "" {
+		attributes["name"] = target.Name
+	}
+	if strings.TrimSpace(comment) != "" {
+		attributes["comment"] = comment
+	}
+	return []map[string]interface{}{addRelationOp(target.Rel, target.URL, attributes)}, nil
+}
+
+func buildRemoveLinkPatch(existing []interface{}, target linkTarget) ([]map[string]interface{}, error) {
+	matches := findRelations(parseRelations(existing), target)
+	if len(matches) == 0 {
+		return nil, errs.New("link_not_found", "work item has no such link", map[string]interface{}{
+			"rel": target.Rel,
+			"url": target.URL,
+		})
+	}
+	indices := make([]int, 0, len(matches))
+	for _, match := range matches {
+		indices = append(indices, match.Index)
+	}
+	return removeRelationOps(indices), nil
+}
+
+// reparentPatch replaces every parentRel relation with one pointing at
+// parentID. It returns a nil patch when the item already has exactly that
+// parent, along with the current parent ID (0 when there is none).
+func reparentPatch(relations []interface{}, parentID int, parentRel, parentURL string) ([]map[string]interface{}, int) {
+	if parentRel == "" {
+		parentRel = parentRelation
+	}
+	existingParentIndices := []int{}
+	existingParentID := 0
+	for _, relation := range parseRelations(relations) {
+		if relation.Rel != parentRel {
+			continue
+		}
+		existingParentIndices = append(existingParentIndices, relation.Index)
+		if existingParentID == 0 {
+			existingParentID = relation.ID
+		}
+	}
+
+	if existingParentID == parentID && len(existingParentIndices) == 1 {
+		return nil, existingParentID
+	}
+
+	patch := removeRelationOps(existingParentIndices)
+	patch = append(patch, addRelationOp(parentRel, parentURL, nil))
+	return patch, existingParentID
+}
+
+func parseRelations(relations []interface{}) []workItemRelation {
+	parsed := []workItemRelation{}
+	for index, raw := range relations {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rel, _ := m["rel"].(string)
+		url, _ := m["url"].(string)
+		relation := workItemRelation{
+			Index: index,
+			Type:  relationDisplayName(rel),
+			Rel:   rel,
+			URL:   url,
+		}
+		if isWorkItemRelation(rel) {
+			relation.ID = idFromURL(url)
+		}
+		if attrs, ok := m["attributes"].(map[string]interface{}); ok && len(attrs) > 0 {
+			relation.Attributes = attrs
+			relation.Name, _ = attrs["name"].(string)
+			relation.Comment, _ = attrs["comment"].(string)
+		}
+		parsed = append(parsed, relation)
+	}
+	return parsed
+}
+
+func findRelations(relations []workItemRelation, target linkTarget) []workItemRelation {
+	matches := []workItemRelation{}
+	for _, relation := range relations {
+		if !strings.EqualFold(relation.Rel, target.Rel) {
+			continue
+		}
+		if target.WorkItemID > 0 {
+			if relation.ID == target.WorkItemID {
+				matches = append(matches, relation)
+			}
+			continue
+		}
+		if strings.EqualFold(relation.URL, target.URL) {
+			matches = append(matches, relation)
+		}
+	}
+	return matches
+}
+
+func addRelationOp(rel, url string, attributes map[string]interface{}) map[string]interface{} {
+	value := map[string]interface{}{
+		"rel": rel,
+		"url": url,
+	}
+	if len(attributes) > 0 {
+		value["attributes"] = attributes
+	}
+	return map[string]interface{}{
+		"op":    "add",
+		"path":  "/relations/-",
+		"value": value,
+	}
+}
+
+// removeRelationOps emits removals from the highest index down so earlier
+// removals do not shift the positions of later ones.
+func removeRelationOps(indices []int) []map[string]interface{} {
+	sorted := append([]int(nil), indices...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	patch := []map[string]interface{}{}
+	for _, idx := range sorted {
+		patch = append(patch, map[string]interface{}{
+			"op":   "remove",
+			"path": fmt.Sprintf("/relations/%d", idx),
+		})
+	}
+	return patch
+}
+
+func isWorkItemRelation(rel string) bool {
+	switch rel {
+	case hyperlinkRelation, artifactRelation, attachedFileRel:
+		return false
+	}
+	return rel != ""
+}
+
+func relationDisplayName(rel string) string {
+	if name, ok := relationNames[rel]; ok {
+		return name
+	}
+	return rel
+}
+
+func linkTargetLabel(target linkTarget) string {
+	if target.WorkItemID > 0 {
+		return strconv.Itoa(target.WorkItemID)
+	}
+	return target.URL
+}
+
+func extractRelationIDs(relations []interface{}, relFilter string) []int {
+	if len(relations) == 0 {
+		return nil
+	}
+	ids := []int{}
+	seen := map[int]bool{}
+	for _, relation := range parseRelations(relations) {
+		if relFilter != "" && relation.Rel != relFilter {
+			continue
+		}
+		id := idFromURL(relation.URL)
+		if id == 0 || seen[id] {
+			continue
+		}
+		ids = append(ids, id)
+		seen[id] = true
+	}
+	return ids
+}
+
+func idFromURL(value string) int {
+	if value == "" {
+		return 0
+	}
+	parts := strings.Split(strings.TrimRight(value, "/"), "/")
+	if len(parts) == 0 {
+		return 0
+	}
+	id, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+func printRelationTable(w io.Writer, relations []workItemRelation) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tTARGET\tNAME\tCOMMENT")
+	for _, relation := range relations {
+		target := relation.URL
+		if relation.ID > 0 {
+			target = strconv.Itoa(relation.ID)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", relation.Type, target, relation.Name, relation.Comment)
+	}
+	_ = tw.Flush()
+}
+
+func linkValueFlags() map[string]bool {
+	flags := wiqlValueFlags()
+	flags["comment"] = true
+	flags["repository"] = true
+	flags["type"] = true
+	return flags
+}
@@ -0,0 +1,358 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+// moveResult is the outcome of moving one work item. To is the target state
+// in the item's own process, which differs between types for a category.
+type moveResult struct {
+	ID        int                 `json:"id"`
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	From      string              `json:"from"`
+	To        string              `json:"to,omitempty"`
+	OK        bool                `json:"ok"`
+	Rev       int                 `json:"rev,omitempty"`
+	Unchanged bool                `json:"unchanged,omitempty"`
+	Error     *output.ErrorDetail `json:"error,omitempty"`
+}
+
+type moveSummary struct {
+	Target    string       `json:"target"`
+	DryRun    bool         `json:"dryRun,omitempty"`
+	Total     int          `json:"total"`
+	Moved     int          `json:"moved"`
+	Unchanged int          `json:"unchanged"`
+	Failed    int          `json:"failed"`
+	Results   []moveResult `json:"results"`
+}
+
+// movePlan is what planMove decides for one item: the state to move to and
+// the patch doing it. A nil patch means the item is already there.
+type movePlan struct {
+	State string
+	Patch []map[string]interface{}
+}
+
+func runMove(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("move", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	to := fs.String("to", "", "Target state, or state category: Proposed, InProgress, Resolved, Completed")
+	reason := fs.String("reason", "", "Reason for the transition (default: the process default)")
+	sets := stringSliceFlag{}
+	fs.Var(&sets, "set", "Field=Value also set on every item, e.g. fields the target state requires (repeatable)")
+	comment := fs.String("add-comment", "", "Add a Markdown comment to System.History on every item")
+	dryRun := fs.Bool("dry-run", false, "Show the target states without changing anything")
+	noValidate := fs.Bool("no-validate", false, "Send values as given without checking them against the field definitions")
+	idArgs, rest := splitPositionals(args, moveValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if len(idArgs) == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "at least one work item id is required", nil), flags.json)
+		return 1
+	}
+	if strings.TrimSpace(*to) == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "--to is required", nil), flags.json)
+		return 1
+	}
+	ids, err := parseWorkItemIDs(idArgs)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	ctx, err := buildContext(flags, stdout, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	if ctx.project == "" {
+		output.WriteError(stderr, errs.New("config_missing", "project is required", nil), flags.json)
+		return 1
+	}
+	extra, err := buildPatch(aliasAssignments(ctx.cfg.FieldAliases, sets.values), *comment)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	applyValueAliases(ctx.cfg, extra)
+	client, err := api.NewClient(ctx.baseURL, ctx.project, ctx.pat, ctx.insecure, ctx.verbose, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+
+	reqCtx := context.Background()
+	items := make([]api.WorkItem, 0, len(ids))
+	for i := 0; i < len(ids); i += maxBatchSize {
+		end := i + maxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch, err := client.GetWorkItemsBatch(reqCtx, ids[i:end], nil, time.Time{})
+		if err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			return 1
+		}
+		items = append(items, batch...)
+	}
+	types, err := client.ListWorkItemTypes(reqCtx)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	transitions := map[string]map[string][]api.WorkItemStateTransition{}
+	for _, item := range types {
+		transitions[strings.ToLower(item.Name)] = item.Transitions
+	}
+	validator := newFieldValidator(client)
+
+	summary := moveSummary{Target: strings.TrimSpace(*to), DryRun: *dryRun, Total: len(items), Results: make([]moveResult, 0, len(items))}
+	pending := []bulkPreviewItem{}
+	byID := map[int]int{}
+	for _, wi := range items {
+		result := moveResult{ID: wi.ID, Type: workItemType(wi)}
+		result.Title, _ = wi.Fields["System.Title"].(string)
+		result.From, _ = wi.Fields["System.State"].(string)
+		plan, err := func() (movePlan, error) {
+			states, err := validator.loadStates(reqCtx, result.Type)
+			if err != nil {
+				return movePlan{}, err
+			}
+			fields, err := validator.loadType(reqCtx, result.Type)
+			if err != nil {
+				return movePlan{}, err
+			}
+			plan, err := planMove(wi, summary.Target, *reason, states, transitions[strings.ToLower(result.Type)], fields, clonePatch(extra))
+			if err != nil || plan.Patch == nil || *noValidate {
+				return plan, err
+			}
+			return plan, validator.apply(reqCtx, result.Type, plan.Patch)
+		}()
+		result.To = plan.State
+		switch {
+		case err != nil:
+			detail := output.NewErrorDetail(err)
+			result.Error = &detail
+		case plan.Patch == nil:
+			result.OK = true
+			result.Unchanged = true
+		case *dryRun:
+			result.OK = true
+		default:
+			byID[wi.ID] = len(summary.Results)
+			pending = append(pending, bulkPreviewItem{ID: wi.ID, Patch: plan.Patch})
+		}
+		summary.Results = append(summary.Results, result)
+	}
+	if len(pending) > 0 {
+		applied := applyBulkPatch(reqCtx, pending, client.BatchWorkItems, nil)
+		for _, outcome := range applied.Results {
+			result := &summary.Results[byID[outcome.ID]]
+			result.OK = outcome.OK
+			result.Rev = outcome.Rev
+			result.Error = outcome.Error
+		}
+	}
+	for _, result := range summary.Results {
+		switch {
+		case !result.OK:
+			summary.Failed++
+		case result.Unchanged:
+			summary.Unchanged++
+		default:
+			summary.Moved++
+		}
+	}
+	return renderMoveSummary(ctx, summary)
+}
+
+// stateTransitions returns the transitions out of state, matching the state
+// name without regard to case.
+func stateTransitions(transitions map[string][]api.WorkItemStateTransition, state string) []api.WorkItemStateTransition {
+	if list, ok := transitions[state]; ok {
+		return list
+	}
+	for from, list := range transitions {
+		if strings.EqualFold(from, state) {
+			return list
+		}
+	}
+	return nil
+}
+
+// planMove resolves target for wi's type and builds the transition patch.
+// target is a state name of the type or a state category; a category picks
+// the type's first state in it. Closing sets Remaining Work to 0, and the
+// type's required fields must have a value once extra is applied. The patch
+// tests the item's revision so a concurrent change is reported, not undone.
+func planMove(wi api.WorkItem, target, reason string, states []api.WorkItemTypeState, transitions map[string][]api.WorkItemStateTransition, fields map[string]api.WorkItemTypeField, extra []map[string]interface{}) (movePlan, error) {
+	typeName := workItemType(wi)
+	current, _ := wi.Fields["System.State"].(string)
+	state, category := "", ""
+	for _, candidate := range states {
+		if strings.EqualFold(candidate.Name, target) {
+			state, category = candidate.Name, candidate.Category
+			break
+		}
+	}
+	if state == "" {
+		if wanted := stateCategory(target); wanted != "" {
+			for _, candidate := range states {
+				if strings.EqualFold(candidate.Category, wanted) {
+					state, category = candidate.Name, candidate.Category
+					break
+				}
+			}
+		}
+	}
+	if state == "" {
+		names := make([]string, 0, len(states))
+		for _, candidate := range states {
+			names = append(names, candidate.Name)
+		}
+		return movePlan{}, errs.New("invalid_state", fmt.Sprintf("work item type %s has no state or state category %q", typeName, target), map[string]interface{}{"type": typeName, "states": names})
+	}
+	plan := movePlan{State: state}
+	if strings.EqualFold(state, current) {
+		return plan, nil
+	}
+	if len(transitions) > 0 {
+		allowed := []string{}
+		permitted := false
+		for _, transition := range stateTransitions(transitions, current) {
+			allowed = append(allowed, transition.To)
+			permitted = permitted || strings.EqualFold(transition.To, state)
+		}
+		if !permitted {
+			return plan, errs.New("invalid_transition", fmt.Sprintf("%s %d cannot move from %s to %s", typeName, wi.ID, current, state), map[string]interface{}{"from": current, "to": state, "allowed": allowed})
+		}
+	}
+
+	patch := []map[string]interface{}{revisionTestOp(wi.Rev), {"op": "add", "path": "/fields/System.State", "value": state}}
+	if strings.TrimSpace(reason) != "" {
+		patch = append(patch, map[string]interface{}{"op": "add", "path": "/fields/System.Reason", "value": strings.TrimSpace(reason)})
+	}
+	patched := func(ref string) bool {
+		for _, op := range patch {
+			if path, _ := op["path"].(string); strings.EqualFold(path, "/fields/"+ref) {
+				return true
+			}
+		}
+		return false
+	}
+	patch = append(patch, extra...)
+	const remainingWork = "Microsoft.VSTS.Scheduling.RemainingWork"
+	if _, onType := fields[strings.ToLower(remainingWork)]; onType && category == stateCategoryCompleted && !patched(remainingWork) {
+		if remaining := currentFieldValue(wi.Fields, remainingWork); remaining != nil && editScalar(remaining) != "0" {
+			patch = append(patch, map[string]interface{}{"op": "add", "path": "/fields/" + remainingWork, "value": 0})
+		}
+	}
+	missing := []string{}
+	for _, field := range fields {
+		if !field.AlwaysRequired || patched(field.ReferenceName) {
+			continue
+		}
+		if value := currentFieldValue(wi.Fields, field.ReferenceName); value == nil || editScalar(value) == "" {
+			missing = append(missing, field.ReferenceName)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return plan, errs.New("missing_fields", fmt.Sprintf("%s %d needs %s to move to %s; pass them with --set", typeName, wi.ID, strings.Join(missing, ", "), state), missing)
+	}
+	plan.Patch = patch
+	return plan, nil
+}
+
+func renderMoveSummary(ctx commandContext, summary moveSummary) int {
+	code := renderOutput(ctx, summary, func() output.Table {
+		table := output.Table{Headers: []string{"ID", "Type", "Title", "From", "To", "Status", "Rev", "Error"}}
+		for _, result := range summary.Results {
+			status, message := moveResultStatus(summary, result)
+			rev := ""
+			if result.Rev > 0 {
+				rev = strconv.Itoa(result.Rev)
+			}
+			table.Rows = append(table.Rows, []string{strconv.Itoa(result.ID), result.Type, result.Title, result.From, result.To, status, rev, message})
+		}
+		return table
+	}, func() {
+		if len(summary.Results) > 0 {
+			tw := tabwriter.NewWriter(ctx.stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tTYPE\tFROM\tTO\tSTATUS\tERROR")
+			for _, result := range summary.Results {
+				status, message := moveResultStatus(summary, result)
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", result.ID, result.Type, result.From, result.To, status, message)
+			}
+			_ = tw.Flush()
+		}
+		verb := "Moved"
+		if summary.DryRun {
+			verb = "Dry run: would move"
+		}
+		fmt.Fprintf(ctx.stdout, "%s %d of %d work items to %s (%d unchanged, %d failed)\n", verb, summary.Moved, summary.Total, summary.Target, summary.Unchanged, summary.Failed)
+	})
+	if code == 0 && summary.Failed > 0 {
+		return 1
+	}
+	return code
+}
+
+func moveResultStatus(summary moveSummary, result moveResult) (status, message string) {
+	switch {
+	case !result.OK:
+		return "failed", result.Error.Message
+	case result.Unchanged:
+		return "unchanged", ""
+	case summary.DryRun:
+		return "would move", ""
+	default:
+		return "moved", ""
+	}
+}
+
+// parseWorkItemIDs parses positional work item IDs, dropping repeats.
+func parseWorkItemIDs(args []string) ([]int, error) {
+	ids := make([]int, 0, len(args))
+	seen := map[int]bool{}
+	for _, arg := range args {
+		for _, part := range splitCSV(arg) {
+			id, err := strconv.Atoi(part)
+			if err != nil || id <= 0 {
+				return nil, errs.New("invalid_args", "work item id must be a positive number", part)
+			}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func moveValueFlags() map[string]bool {
+	flags := wiqlValueFlags()
+	flags["to"] = true
+	flags["reason"] = true
+	flags["set"] = true
+	flags["add-comment"] = true
+	flags["dry-run"] = false
+	flags["no-validate"] = false
+	return flags
+}
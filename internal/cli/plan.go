@@ -0,0 +1,591 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+// workItemPlan is the document accepted by `tfs create --from`. Items may nest
+// their children or point at a parent by local name or existing work item ID.
+type workItemPlan struct {
+	Defaults planDefaults `json:"defaults" yaml:"defaults"`
+	Items    []planItem   `json:"items" yaml:"items"`
+}
+
+type planDefaults struct {
+	Type       string                 `json:"type" yaml:"type"`
+	AssignedTo string                 `json:"assignedTo" yaml:"assignedTo"`
+	ParentRel  string                 `json:"parentRel" yaml:"parentRel"`
+	Set        map[string]interface{} `json:"set" yaml:"set"`
+}
+
+type planItem struct {
+	Name       string                 `json:"name" yaml:"name"`
+	Type       string                 `json:"type" yaml:"type"`
+	Title      string                 `json:"title" yaml:"title"`
+	AssignedTo string                 `json:"assignedTo" yaml:"assignedTo"`
+	Parent     interface{}            `json:"parent" yaml:"parent"`
+	Set        map[string]interface{} `json:"set" yaml:"set"`
+	Children   []planItem             `json:"children" yaml:"children"`
+}
+
+// planNode is a flattened plan item ready to be created. Exactly one of
+// ParentName (a node in the same plan) and ParentID (an existing work item)
+// may be set.
+type planNode struct {
+	Name       string
+	Type       string
+	Title      string
+	AssignedTo string
+	ParentName string
+	ParentID   int
+	Sets       []string
+}
+
+type planResult struct {
+	Name     string `json:"name"`
+	ID       int    `json:"id"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	ParentID int    `json:"parentId,omitempty"`
+	Existing bool   `json:"existing,omitempty"`
+}
+
+type planFailure struct {
+	Name  string             `json:"name"`
+	Error output.ErrorDetail `json:"error"`
+}
+
+type planOutcome struct {
+	Results        []planResult
+	Created        map[string]int
+	Failures       []planFailure
+	RolledBack     []int
+	RollbackErrors []output.ErrorDetail
+}
+
+// planState is persisted after every successful create so an interrupted run
+// can be resumed with the same --state file.
+type planState struct {
+	Created map[string]int `json:"created"`
+}
+
+// planRunner creates one wave of nodes whose parents already exist and
+// returns, per node, the new ID or the error.
+type planRunner struct {
+	create   func(ctx context.Context, nodes []planNode, parentIDs []int) ([]int, []error)
+	remove   func(ctx context.Context, id int) error
+	save     func(created map[string]int) error
+	rollback bool
+}
+
+func runCreateFromPlan(ctx commandContext, planPath, statePath string, rollback, validate bool, stdin io.Reader) int {
+	if rollback && statePath != "" {
+		output.WriteError(ctx.stderr, errs.New("invalid_args", "--rollback and --state cannot be combined", nil), ctx.jsonMode)
+		return 1
+	}
+	plan, err := loadWorkItemPlan(planPath, stdin)
+	if err != nil {
+		output.WriteError(ctx.stderr, err, ctx.jsonMode)
+		return 1
+	}
+	nodes, err := flattenPlan(plan)
+	if err != nil {
+		output.WriteError(ctx.stderr, err, ctx.jsonMode)
+		return 1
+	}
+	created := map[string]int{}
+	if statePath != "" {
+		created, err = loadPlanState(statePath)
+		if err != nil {
+			output.WriteError(ctx.stderr, err, ctx.jsonMode)
+			return 1
+		}
+	}
+
+	client, err := api.NewClient(ctx.baseURL, ctx.project, ctx.pat, ctx.insecure, ctx.verbose, ctx.stderr)
+	if err != nil {
+		output.WriteError(ctx.stderr, err, ctx.jsonMode)
+		return 1
+	}
+	var currentAssignee interface{}
+	validator := newFieldValidator(client)
+	runner := planRunner{
+		create: func(reqCtx context.Context, nodes []planNode, parentIDs []int) ([]int, []error) {
+			ids := make([]int, len(nodes))
+			failures := make([]error, len(nodes))
+			ops := []api.WorkItemBatchOperation{}
+			opIndex := []int{}
+			for index, node := range nodes {
+				var assigned interface{} = node.AssignedTo
+				if node.AssignedTo == "" {
+					if currentAssignee == nil {
+						resolved, err := resolveCurrentAssignee(reqCtx, client)
+						if err != nil {
+							failures[index] = err
+							continue
+						}
+						currentAssignee = resolved
+					}
+					assigned = currentAssignee
+				}
+				patch, err := assembleCreatePatch(client, node.Title, assigned, aliasAssignments(ctx.cfg.FieldAliases, node.Sets), parentIDs[index], plan.Defaults.ParentRel)
+				if err == nil {
+					applyValueAliases(ctx.cfg, patch)
+				}
+				if err == nil && validate {
+					err = validator.apply(reqCtx, node.Type, patch)
+				}
+				if err != nil {
+					failures[index] = err
+					continue
+				}
+				ops = append(ops, api.WorkItemBatchOperation{Type: node.Type, Patch: patch})
+				opIndex = append(opIndex, index)
+			}
+			if len(ops) == 0 {
+				return ids, failures
+			}
+			results, err := client.BatchWorkItems(reqCtx, ops)
+			for i, index := range opIndex {
+				switch {
+				case err != nil:
+					failures[index] = err
+				case results[i].Err != nil:
+					failures[index] = results[i].Err
+				default:
+					ids[index] = results[i].ID
+				}
+			}
+			return ids, failures
+		},
+		remove: func(reqCtx context.Context, id int) error {
+			_, err := client.DeleteWorkItem(reqCtx, id, false)
+			return err
+		},
+		rollback: rollback,
+	}
+	if statePath != "" {
+		runner.save = func(created map[string]int) error {
+			return savePlanState(statePath, created)
+		}
+	}
+
+	outcome := executePlan(context.Background(), nodes, created, runner)
+	if len(outcome.Failures) > 0 {
+		first := outcome.Failures[0]
+		details := map[string]interface{}{
+			"failed":  outcome.Failures,
+			"created": outcome.Created,
+		}
+		if rollback {
+			details["rolledBack"] = outcome.RolledBack
+			if len(outcome.RollbackErrors) > 0 {
+				details["rollbackErrors"] = outcome.RollbackErrors
+			}
+		}
+		if statePath != "" {
+			details["state"] = statePath
+		}
+		message := fmt.Sprintf("creating %q failed: %s", first.Name, first.Error.Message)
+		if len(outcome.Failures) > 1 {
+			message = fmt.Sprintf("%d items failed; first %q: %s", len(outcome.Failures), first.Name, first.Error.Message)
+		}
+		output.WriteError(ctx.stderr, errs.New("plan_failed", message, details), ctx.jsonMode)
+		return 1
+	}
+	return renderPlanResults(ctx, outcome)
+}
+
+func loadWorkItemPlan(path string, stdin io.Reader) (workItemPlan, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return workItemPlan{}, errs.New("read_error", "could not read plan file", err.Error())
+	}
+	return parseWorkItemPlan(data, strings.EqualFold(filepath.Ext(path), ".json"))
+}
+
+func parseWorkItemPlan(data []byte, isJSON bool) (workItemPlan, error) {
+	var plan workItemPlan
+	if isJSON {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&plan); err != nil {
+			return workItemPlan{}, errs.New("invalid_plan", "could not parse plan file", err.Error())
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&plan); err != nil && !errors.Is(err, io.EOF) {
+			return workItemPlan{}, errs.New("invalid_plan", "could not parse plan file", err.Error())
+		}
+	}
+	if len(plan.Items) == 0 {
+		return workItemPlan{}, errs.New("invalid_plan", "plan has no items", nil)
+	}
+	return plan, nil
+}
+
+// flattenPlan validates the plan and returns its items ordered so that every
+// parent precedes its children.
+func flattenPlan(plan workItemPlan) ([]planNode, error) {
+	nodes := []planNode{}
+	byName := map[string]int{}
+	var walk func(items []planItem, parentName, prefix string) error
+	walk = func(items []planItem, parentName, prefix string) error {
+		for index, item := range items {
+			name := strings.TrimSpace(item.Name)
+			if name == "" {
+				name = prefix + strconv.Itoa(index+1)
+			}
+			if _, dup := byName[name]; dup {
+				return errs.New("invalid_plan", fmt.Sprintf("duplicate item name %q", name), nil)
+			}
+			node := planNode{
+				Name:       name,
+				Type:       firstNonEmpty(item.Type, plan.Defaults.Type),
+				Title:      strings.TrimSpace(item.Title),
+				AssignedTo: firstNonEmpty(item.AssignedTo, plan.Defaults.AssignedTo),
+				ParentName: parentName,
+			}
+			if node.Title == "" {
+				return errs.New("invalid_plan", fmt.Sprintf("item %q has no title", name), nil)
+			}
+			if node.Type == "" {
+				return errs.New("invalid_plan", fmt.Sprintf("item %q has no type and no default type is set", name), nil)
+			}
+			if item.Parent != nil {
+				if parentName != "" {
+					return errs.New("invalid_plan", fmt.Sprintf("item %q is nested under %q and cannot set parent", name, parentName), nil)
+				}
+				parentName, parentID, err := planParent(name, item.Parent)
+				if err != nil {
+					return err
+				}
+				node.ParentName, node.ParentID = parentName, parentID
+			}
+			sets, assignee := planSets(plan.Defaults.Set, item.Set)
+			if assignee != "" && strings.TrimSpace(item.AssignedTo) == "" {
+				node.AssignedTo = assignee
+			}
+			node.Sets = sets
+			byName[name] = len(nodes)
+			nodes = append(nodes, node)
+			if err := walk(item.Children, name, name+"/"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(plan.Items, "", ""); err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		if node.ParentName != "" {
+			if _, ok := byName[node.ParentName]; !ok {
+				return nil, errs.New("invalid_plan", fmt.Sprintf("item %q refers to unknown parent %q", node.Name, node.ParentName), nil)
+			}
+		}
+	}
+
+	ordered := make([]planNode, 0, len(nodes))
+	state := map[string]int{}
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case 1:
+			return errs.New("invalid_plan", fmt.Sprintf("parent cycle involving %q", name), nil)
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		node := nodes[byName[name]]
+		if node.ParentName != "" {
+			if err := visit(node.ParentName); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		ordered = append(ordered, node)
+		return nil
+	}
+	for _, node := range nodes {
+		if err := visit(node.Name); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// planSets merges default and item fields into Field=Value assignments in a
+// stable order. System.AssignedTo is returned separately because the create
+// patch always sets it.
+func planSets(defaults, fields map[string]interface{}) ([]string, string) {
+	merged := map[string]interface{}{}
+	for field, value := range defaults {
+		merged[field] = value
+	}
+	for field, value := range fields {
+		merged[field] = value
+	}
+	names := make([]string, 0, len(merged))
+	for field := range merged {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	sets := make([]string, 0, len(names))
+	assignee := ""
+	for _, field := range names {
+		value := formatPlanValue(merged[field])
+		if strings.EqualFold(field, "System.AssignedTo") {
+			assignee = value
+			continue
+		}
+		sets = append(sets, field+"="+value)
+	}
+	return sets, assignee
+}
+
+// planParent reads an item's parent: "#123" or {id: 123} is an existing work
+// item, anything else the local name of another item, so numbered names such
+// as 3 never turn into IDs.
+func planParent(name string, value interface{}) (string, int, error) {
+	ref := ""
+	switch val := value.(type) {
+	case map[string]interface{}:
+		if len(val) != 1 || val["id"] == nil {
+			return "", 0, errs.New("invalid_plan", fmt.Sprintf("item %q has an invalid parent; use a local name, \"#<id>\" or {id: <id>}", name), val)
+		}
+		ref = strings.TrimSpace(formatPlanValue(val["id"]))
+	default:
+		ref = strings.TrimSpace(formatPlanValue(value))
+		if !strings.HasPrefix(ref, "#") {
+			return ref, 0, nil
+		}
+		ref = strings.TrimPrefix(ref, "#")
+	}
+	id, err := strconv.Atoi(ref)
+	if err != nil || id <= 0 {
+		return "", 0, errs.New("invalid_plan", fmt.Sprintf("item %q has an invalid parent id", name), ref)
+	}
+	return "", id, nil
+}
+
+func formatPlanValue(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(val)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// executePlan creates the ordered nodes in waves: every wave holds the nodes
+// whose parents exist, so each wave is a single batch request. Names already
+// present in created are skipped. On failure it stops after the current wave
+// and, when requested, deletes the items created by this run in reverse order.
+func executePlan(ctx context.Context, nodes []planNode, created map[string]int, runner planRunner) planOutcome {
+	outcome := planOutcome{Results: []planResult{}, Created: map[string]int{}}
+	for name, id := range created {
+		outcome.Created[name] = id
+	}
+	pending := []planNode{}
+	for _, node := range nodes {
+		if _, ok := outcome.Created[node.Name]; !ok {
+			pending = append(pending, node)
+		}
+	}
+	createdNow := []int{}
+	for len(pending) > 0 && len(outcome.Failures) == 0 {
+		wave := []planNode{}
+		parentIDs := []int{}
+		waiting := []planNode{}
+		for _, node := range pending {
+			parentID := node.ParentID
+			if node.ParentName != "" {
+				id, ok := outcome.Created[node.ParentName]
+				if !ok {
+					waiting = append(waiting, node)
+					continue
+				}
+				parentID = id
+			}
+			wave = append(wave, node)
+			parentIDs = append(parentIDs, parentID)
+		}
+		if len(wave) == 0 {
+			break
+		}
+		ids, failures := runner.create(ctx, wave, parentIDs)
+		succeeded := 0
+		for index, node := range wave {
+			if failures[index] != nil {
+				outcome.Failures = append(outcome.Failures, planFailure{
+					Name:  node.Name,
+					Error: output.NewErrorDetail(failures[index]),
+				})
+				continue
+			}
+			outcome.Created[node.Name] = ids[index]
+			createdNow = append(createdNow, ids[index])
+			succeeded++
+		}
+		if runner.save != nil && succeeded > 0 {
+			if err := runner.save(outcome.Created); err != nil {
+				outcome.Failures = append(outcome.Failures, planFailure{Name: "state", Error: output.NewErrorDetail(err)})
+			}
+		}
+		pending = waiting
+	}
+
+	if len(outcome.Failures) > 0 && runner.rollback {
+		outcome.RolledBack = []int{}
+		for i := len(createdNow) - 1; i >= 0; i-- {
+			id := createdNow[i]
+			if err := runner.remove(ctx, id); err != nil {
+				outcome.RollbackErrors = append(outcome.RollbackErrors, output.NewErrorDetail(err))
+				continue
+			}
+			outcome.RolledBack = append(outcome.RolledBack, id)
+			for name, createdID := range outcome.Created {
+				if createdID == id {
+					delete(outcome.Created, name)
+				}
+			}
+		}
+	}
+
+	for _, node := range nodes {
+		id, ok := outcome.Created[node.Name]
+		if !ok {
+			continue
+		}
+		parentID := node.ParentID
+		if node.ParentName != "" {
+			parentID = outcome.Created[node.ParentName]
+		}
+		_, existing := created[node.Name]
+		outcome.Results = append(outcome.Results, planResult{
+			Name:     node.Name,
+			ID:       id,
+			Type:     node.Type,
+			Title:    node.Title,
+			ParentID: parentID,
+			Existing: existing,
+		})
+	}
+	return outcome
+}
+
+func loadPlanState(path string) (map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]int{}, nil
+	}
+	if err != nil {
+		return nil, errs.New("read_error", "could not read state file", err.Error())
+	}
+	var state planState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, errs.New("invalid_state", "could not parse state file", err.Error())
+	}
+	if state.Created == nil {
+		state.Created = map[string]int{}
+	}
+	return state.Created, nil
+}
+
+func savePlanState(path string, created map[string]int) error {
+	data, err := json.MarshalIndent(planState{Created: created}, "", "  ")
+	if err != nil {
+		return errs.New("write_error", "could not encode state file", err.Error())
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tfs-plan-state-*")
+	if err != nil {
+		return errs.New("write_error", "could not write state file", err.Error())
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errs.New("write_error", "could not write state file", err.Error())
+	}
+	return nil
+}
+
+func renderPlanResults(ctx commandContext, outcome planOutcome) int {
+	payload := map[string]interface{}{
+		"created": outcome.Created,
+		"items":   outcome.Results,
+	}
+	return renderOutput(ctx, payload, func() output.Table {
+		table := output.Table{Headers: []string{"Name", "ID", "Existing", "Type", "ParentID", "Title"}}
+		for _, result := range outcome.Results {
+			parent := ""
+			if result.ParentID > 0 {
+				parent = strconv.Itoa(result.ParentID)
+			}
+			table.Rows = append(table.Rows, []string{result.Name, strconv.Itoa(result.ID), strconv.FormatBool(result.Existing), result.Type, parent, result.Title})
+		}
+		return table
+	}, func() {
+		tw := tabwriter.NewWriter(ctx.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tID\tTYPE\tPARENT\tTITLE")
+		for _, result := range outcome.Results {
+			id := strconv.Itoa(result.ID)
+			if result.Existing {
+				id += " (existing)"
+			}
+			parent := ""
+			if result.ParentID > 0 {
+				parent = strconv.Itoa(result.ParentID)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", result.Name, id, result.Type, parent, result.Title)
+		}
+		_ = tw.Flush()
+	})
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
@@ -0,0 +1,405 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+// maxQueryDepth is the deepest folder expansion the queries API allows.
+const maxQueryDepth = 2
+
+func runQuery(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "query subcommand is required", nil), true)
+		return 1
+	}
+	switch args[0] {
+	case "list":
+		return runQueryList(args[1:], stdout, stderr)
+	case "run":
+		return runQueryRun(args[1:], stdout, stderr)
+	case "save":
+		return runQuerySave(args[1:], stdout, stderr)
+	case "delete":
+		return runQueryDelete(args[1:], stdout, stderr)
+	default:
+		output.WriteError(stderr, errs.New("unknown_command", "unknown query subcommand", args[0]), true)
+		return 1
+	}
+}
+
+func runQueryList(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("query list", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	depth := fs.Int("depth", 1, fmt.Sprintf("Folder levels to expand (1-%d)", maxQueryDepth))
+	folderArg, rest := splitPositional(args, queryValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if *depth < 0 || *depth > maxQueryDepth {
+		output.WriteError(stderr, errs.New("invalid_args", fmt.Sprintf("depth must be between 0 and %d", maxQueryDepth), *depth), flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	var items []api.QueryHierarchyItem
+	if folderArg == "" {
+		roots, err := client.ListQueries(context.Background(), *depth)
+		if err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			return 1
+		}
+		items = roots
+	} else {
+		folder, err := client.GetQuery(context.Background(), folderArg, *depth)
+		if err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			return 1
+		}
+		items = []api.QueryHierarchyItem{folder}
+	}
+	return renderOutput(ctx, items, func() output.Table {
+		table := output.Table{Headers: []string{"Path", "Name", "Kind", "ID"}}
+		var add func(item api.QueryHierarchyItem)
+		add = func(item api.QueryHierarchyItem) {
+			table.Rows = append(table.Rows, []string{item.Path, item.Name, queryKind(item), item.ID})
+			for _, child := range item.Children {
+				add(child)
+			}
+		}
+		for _, item := range items {
+			add(item)
+		}
+		return table
+	}, func() {
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tKIND\tID")
+		for _, item := range items {
+			printQueryItem(tw, item, 0)
+		}
+		_ = tw.Flush()
+	})
+}
+
+func printQueryItem(w io.Writer, item api.QueryHierarchyItem, depth int) {
+	fmt.Fprintf(w, "%s%s\t%s\t%s\n", strings.Repeat("  ", depth), item.Name, queryKind(item), item.ID)
+	for _, child := range item.Children {
+		printQueryItem(w, child, depth+1)
+	}
+}
+
+func queryKind(item api.QueryHierarchyItem) string {
+	if item.IsFolder {
+		return "folder"
+	}
+	if item.QueryType == "" {
+		return "query"
+	}
+	return item.QueryType
+}
+
+func runQueryRun(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("query run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	var top int
+	fs.IntVar(&top, "top", 0, "Maximum number of results")
+	depth := fs.Int("depth", 0, "Maximum tree levels to show for tree and one-hop queries (0 = all)")
+	columnsCSV := fs.String("columns", "", "Comma-separated field reference names or aliases to show")
+	queryArg, rest := splitPositional(args, queryValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if queryArg == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "query path or id is required", nil), flags.json)
+		return 1
+	}
+	if *depth < 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "depth must not be negative", *depth), flags.json)
+		return 1
+	}
+	columns, err := parseColumns(*columnsCSV)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	query, err := client.GetQuery(context.Background(), queryArg, 0)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	if query.IsFolder {
+		output.WriteError(stderr, errs.New("invalid_args", "query path points to a folder", query.Path), ctx.jsonMode)
+		return 1
+	}
+	resp, err := client.RunQuery(context.Background(), query.ID, top)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	return renderQueryResult(ctx, client, resp, time.Time{}, *depth, columns)
+}
+
+func runQuerySave(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("query save", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	wiql := fs.String("wiql", "", "WIQL text of the query")
+	overwrite := fs.Bool("overwrite", false, "Replace the WIQL of an existing query")
+	pathArg, rest := splitPositional(args, queryValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	folderPath, name := splitQueryPath(pathArg)
+	if folderPath == "" || name == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "query path must include a folder, e.g. \"Shared Queries/Active bugs\"", pathArg), flags.json)
+		return 1
+	}
+	if strings.TrimSpace(*wiql) == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "--wiql is required", nil), flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	folder, err := client.GetQuery(context.Background(), folderPath, 1)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	if !folder.IsFolder {
+		output.WriteError(stderr, errs.New("invalid_args", "parent path is not a folder", folderPath), ctx.jsonMode)
+		return 1
+	}
+	var saved api.QueryHierarchyItem
+	existing, found := findQueryChild(folder, name)
+	switch {
+	case found && existing.IsFolder:
+		output.WriteError(stderr, errs.New("invalid_args", "a folder with this name already exists", existing.Path), ctx.jsonMode)
+		return 1
+	case found && !*overwrite:
+		output.WriteError(stderr, errs.New("query_exists", "query already exists; use --overwrite to replace its WIQL", existing.Path), ctx.jsonMode)
+		return 1
+	case found:
+		saved, err = client.UpdateQuery(context.Background(), existing.ID, api.QueryHierarchyItem{Name: existing.Name, Wiql: *wiql})
+	default:
+		saved, err = client.CreateQuery(context.Background(), folderPath, api.QueryHierarchyItem{Name: name, Wiql: *wiql})
+	}
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	return renderOutput(ctx, saved, func() output.Table {
+		return output.RecordTable([]string{"Path", "ID", "Wiql"}, saved.Path, saved.ID, saved.Wiql)
+	}, func() {
+		fmt.Fprintf(stdout, "Saved query %s (%s)\n", saved.Path, saved.ID)
+	})
+}
+
+func runQueryDelete(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("query delete", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	yes := fs.Bool("yes", false, "Confirm deletion")
+	pathArg, rest := splitPositional(args, queryValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if pathArg == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "query path or id is required", nil), flags.json)
+		return 1
+	}
+	if !*yes {
+		output.WriteError(stderr, errs.New("confirmation_required", "delete is destructive; use --yes to proceed", pathArg), flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	if err := client.DeleteQuery(context.Background(), pathArg); err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	return renderOutput(ctx, map[string]interface{}{"deleted": pathArg}, func() output.Table {
+		return output.RecordTable([]string{"Deleted"}, pathArg)
+	}, func() {
+		fmt.Fprintf(stdout, "Deleted %s\n", pathArg)
+	})
+}
+
+func queryClient(flags globalFlags, stdout, stderr io.Writer) (commandContext, *api.Client, bool) {
+	ctx, err := buildContext(flags, stdout, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return ctx, nil, false
+	}
+	if ctx.project == "" {
+		output.WriteError(stderr, errs.New("config_missing", "project is required", nil), flags.json)
+		return ctx, nil, false
+	}
+	client, err := api.NewClient(ctx.baseURL, ctx.project, ctx.pat, ctx.insecure, ctx.verbose, stderr)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return ctx, nil, false
+	}
+	return ctx, client, true
+}
+
+// renderQueryResult fetches the matched items and prints them as a list, or
+// as a tree when the query returned work item links. depth limits the number
+// of tree levels shown (0 = all); columns replaces the default fields. A
+// non-zero asOf shows the fields as they were then.
+func renderQueryResult(ctx commandContext, client *api.Client, resp api.WiqlResponse, asOf time.Time, depth int, columns []output.Column) int {
+	if len(resp.WorkItemLinks) == 0 {
+		items, err := fetchWorkItemsAsOf(context.Background(), client, collectIDs(resp), asOf, columnFields(columns)...)
+		if err != nil {
+			output.WriteError(ctx.stderr, err, ctx.jsonMode)
+			return 1
+		}
+		return renderColumnList(ctx, items, columns)
+	}
+	roots := buildWorkItemTree(resp.WorkItemLinks, depth)
+	items, err := fetchWorkItemsAsOf(context.Background(), client, workItemTreeIDs(roots), asOf, columnFields(columns)...)
+	if err != nil {
+		output.WriteError(ctx.stderr, err, ctx.jsonMode)
+		return 1
+	}
+	attachTreeItems(roots, items)
+	var payload interface{} = roots
+	tableColumns := output.DefaultColumns()
+	if len(columns) > 0 {
+		payload = output.TreeRows(roots, columns)
+		tableColumns = columns
+	}
+	return renderOutput(ctx, payload, func() output.Table {
+		return output.ColumnTable(output.TreeItems(roots), tableColumns)
+	}, func() {
+		output.PrintTreeColumns(ctx.stdout, roots, tableColumns)
+	})
+}
+
+// buildWorkItemTree turns the source -> target edges of a tree or one-hop
+// query into nested nodes holding only IDs. Entries without a source are the
+// roots. An edge back to an ancestor is kept as a leaf marked Cycle, and
+// nothing below depth levels is built (0 = no limit).
+func buildWorkItemTree(links []api.WorkItemLink, depth int) []output.WorkItemNode {
+	children := map[int][]api.WorkItemLink{}
+	roots := []api.WorkItemLink{}
+	for _, link := range links {
+		if link.Target.ID == 0 {
+			continue
+		}
+		if link.Source.ID == 0 {
+			roots = append(roots, link)
+			continue
+		}
+		children[link.Source.ID] = append(children[link.Source.ID], link)
+	}
+	ancestors := map[int]bool{}
+	var build func(link api.WorkItemLink, level int) output.WorkItemNode
+	build = func(link api.WorkItemLink, level int) output.WorkItemNode {
+		id := link.Target.ID
+		node := output.WorkItemNode{WorkItem: output.WorkItem{ID: id}, Rel: link.Rel, Children: []output.WorkItemNode{}}
+		if ancestors[id] {
+			node.Cycle = true
+			return node
+		}
+		if depth > 0 && level >= depth {
+			node.Truncated = len(children[id])
+			return node
+		}
+		ancestors[id] = true
+		for _, child := range children[id] {
+			node.Children = append(node.Children, build(child, level+1))
+		}
+		delete(ancestors, id)
+		return node
+	}
+	nodes := make([]output.WorkItemNode, 0, len(roots))
+	for _, root := range roots {
+		nodes = append(nodes, build(root, 1))
+	}
+	return nodes
+}
+
+func workItemTreeIDs(nodes []output.WorkItemNode) []int {
+	seen := map[int]bool{}
+	ids := []int{}
+	var walk func(nodes []output.WorkItemNode)
+	walk = func(nodes []output.WorkItemNode) {
+		for _, node := range nodes {
+			if !seen[node.ID] {
+				seen[node.ID] = true
+				ids = append(ids, node.ID)
+			}
+			walk(node.Children)
+		}
+	}
+	walk(nodes)
+	return ids
+}
+
+func attachTreeItems(nodes []output.WorkItemNode, items []output.WorkItem) {
+	byID := make(map[int]output.WorkItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+	var walk func(nodes []output.WorkItemNode)
+	walk = func(nodes []output.WorkItemNode) {
+		for i := range nodes {
+			if item, ok := byID[nodes[i].ID]; ok {
+				nodes[i].WorkItem = item
+			}
+			walk(nodes[i].Children)
+		}
+	}
+	walk(nodes)
+}
+
+func splitQueryPath(path string) (string, string) {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	index := strings.LastIndex(path, "/")
+	if index < 0 {
+		return "", path
+	}
+	return path[:index], path[index+1:]
+}
+
+func findQueryChild(folder api.QueryHierarchyItem, name string) (api.QueryHierarchyItem, bool) {
+	for _, child := range folder.Children {
+		if strings.EqualFold(child.Name, name) {
+			return child, true
+		}
+	}
+	return api.QueryHierarchyItem{}, false
+}
+
+func queryValueFlags() map[string]bool {
+	flags := wiqlValueFlags()
+	flags["depth"] = true
+	flags["wiql"] = true
+	flags["columns"] = true
+	return flags
+}
@@ -0,0 +1,442 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+const (
+	remainingWorkField = "Microsoft.VSTS.Scheduling.RemainingWork"
+	// chartWidth is the length of the longest bar in the text charts.
+	chartWidth = 40
+)
+
+// burndownPoint is the state of a sprint at the end of a working day.
+// Remaining sums the Remaining Work of the open items in the sprint then;
+// Ideal falls evenly to zero over the sprint's working days.
+type burndownPoint struct {
+	Date      string  `json:"date"`
+	Remaining float64 `json:"remaining"`
+	Ideal     float64 `json:"ideal"`
+	OpenItems int     `json:"openItems"`
+}
+
+type burndownReport struct {
+	Iteration sprintInfo      `json:"iteration"`
+	Items     int             `json:"items"`
+	Series    []burndownPoint `json:"series"`
+}
+
+// velocityPoint is the effort of the requirements of one sprint: done by
+// the sprint's finish date, done since, or still open.
+type velocityPoint struct {
+	Iteration     string  `json:"iteration"`
+	Path          string  `json:"path"`
+	FinishDate    string  `json:"finishDate,omitempty"`
+	Completed     float64 `json:"completed"`
+	CompletedLate float64 `json:"completedLate"`
+	Incomplete    float64 `json:"incomplete"`
+}
+
+type velocityReport struct {
+	Team        string          `json:"team,omitempty"`
+	EffortField string          `json:"effortField"`
+	Average     float64         `json:"average"`
+	Series      []velocityPoint `json:"series"`
+}
+
+// chartRow is one bar of a text chart. Mark, when set, is drawn as a | on
+// the same scale, such as the ideal burndown.
+type chartRow struct {
+	Label   string
+	Value   float64
+	Mark    float64
+	HasMark bool
+	Note    string
+}
+
+func runReport(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "report subcommand is required", nil), true)
+		return 1
+	}
+	switch args[0] {
+	case "burndown":
+		return runReportBurndown(args[1:], stdout, stderr)
+	case "velocity":
+		return runReportVelocity(args[1:], stdout, stderr)
+	default:
+		output.WriteError(stderr, errs.New("unknown_command", "unknown report subcommand", args[0]), true)
+		return 1
+	}
+}
+
+func runReportBurndown(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("report burndown", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	team := fs.String("team", "", "Team name (default: config team, else the project's default team)")
+	iteration := fs.String("iteration", "", "Iteration name or path, @previous or @next (default: the current one)")
+	jsonExplicit := flagProvided(args, "json")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	if !jsonExplicit && !flags.format.set {
+		ctx.jsonMode = false
+	}
+	teamName := sprintTeam(ctx, *team)
+	reqCtx := context.Background()
+	selected, err := resolveTeamIteration(reqCtx, client, teamName, *iteration)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	info, working, daysOff, err := sprintCalendar(reqCtx, client, teamName, selected)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	start, startOK := sprintDate(info.StartDate)
+	finish, finishOK := sprintDate(info.FinishDate)
+	if !startOK || !finishOK {
+		output.WriteError(stderr, errs.New("invalid_iteration", fmt.Sprintf("iteration %s has no start and finish dates", info.Path), nil), ctx.jsonMode)
+		return 1
+	}
+	revisions, err := iterationRevisions(reqCtx, client, info.Path, nil, sprintSelectionTimes(start, finish, sprintNow()))
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	categories, err := revisionStateCategories(reqCtx, newFieldValidator(client), revisions)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	days := []time.Time{}
+	for day := start; !day.After(finish); day = day.AddDate(0, 0, 1) {
+		if working[day.Weekday()] && !dayOff(day, daysOff) {
+			days = append(days, day)
+		}
+	}
+	report := burndownReport{
+		Iteration: info,
+		Items:     len(revisions),
+		Series:    computeBurndown(revisions, info.Path, days, sprintToday(), categories),
+	}
+	return renderOutput(ctx, report, func() output.Table {
+		table := output.Table{Headers: []string{"Date", "Remaining", "Ideal", "OpenItems"}}
+		for _, point := range report.Series {
+			table.Rows = append(table.Rows, []string{point.Date, formatHours(point.Remaining), formatHours(point.Ideal), strconv.Itoa(point.OpenItems)})
+		}
+		return table
+	}, func() {
+		fmt.Fprintln(stdout, sprintHeadline(info))
+		fmt.Fprintln(stdout, "Remaining work in hours (| = ideal)")
+		rows := make([]chartRow, 0, len(report.Series))
+		for _, point := range report.Series {
+			rows = append(rows, chartRow{Label: point.Date, Value: point.Remaining, Mark: point.Ideal, HasMark: true, Note: fmt.Sprintf("%d open", point.OpenItems)})
+		}
+		printBarChart(stdout, rows)
+	})
+}
+
+func runReportVelocity(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("report velocity", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	team := fs.String("team", "", "Team name (default: config team, else the project's default team)")
+	last := fs.Int("last", 6, "Number of past sprints to include")
+	jsonExplicit := flagProvided(args, "json")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if *last <= 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "--last must be positive", nil), flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	if !jsonExplicit && !flags.format.set {
+		ctx.jsonMode = false
+	}
+	teamName := sprintTeam(ctx, *team)
+	reqCtx := context.Background()
+	backlogConfig, err := client.GetBacklogConfiguration(reqCtx, teamName)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	effortField := backlogConfig.BacklogFields.TypeFields["Effort"]
+	if effortField == "" {
+		output.WriteError(stderr, errs.New("config_missing", "the backlog configuration maps no field to Effort", nil), ctx.jsonMode)
+		return 1
+	}
+	types := []string{}
+	for _, item := range backlogConfig.RequirementBacklog.WorkItemTypes {
+		types = append(types, item.Name)
+	}
+	iterations, err := client.ListTeamIterations(reqCtx, teamName, "")
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	past := []api.TeamSettingsIteration{}
+	for _, iteration := range iterations {
+		if strings.EqualFold(iteration.Attributes.TimeFrame, "past") {
+			past = append(past, iteration)
+		}
+	}
+	if len(past) > *last {
+		past = past[len(past)-*last:]
+	}
+	validator := newFieldValidator(client)
+	report := velocityReport{Team: teamName, EffortField: effortField, Series: []velocityPoint{}}
+	for _, iteration := range past {
+		start, _ := sprintDate(iteration.Attributes.StartDate)
+		finish, _ := sprintDate(iteration.Attributes.FinishDate)
+		revisions, err := iterationRevisions(reqCtx, client, iteration.Path, types, sprintSelectionTimes(start, finish, sprintNow()))
+		if err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			return 1
+		}
+		categories, err := revisionStateCategories(reqCtx, validator, revisions)
+		if err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			return 1
+		}
+		point := velocityPoint{Iteration: iteration.Name, Path: iteration.Path, FinishDate: iteration.Attributes.FinishDate}
+		if finish, ok := sprintDate(iteration.Attributes.FinishDate); ok {
+			point.Completed, point.CompletedLate, point.Incomplete = computeVelocity(revisions, iteration.Path, effortField, finish, categories)
+		}
+		report.Series = append(report.Series, point)
+		report.Average += point.Completed
+	}
+	if len(report.Series) > 0 {
+		report.Average = math.Round(report.Average/float64(len(report.Series))*100) / 100
+	}
+	return renderOutput(ctx, report, func() output.Table {
+		table := output.Table{Headers: []string{"Iteration", "Path", "FinishDate", "Completed", "CompletedLate", "Incomplete"}}
+		for _, point := range report.Series {
+			table.Rows = append(table.Rows, []string{point.Iteration, point.Path, shortDate(point.FinishDate),
+				formatHours(point.Completed), formatHours(point.CompletedLate), formatHours(point.Incomplete)})
+		}
+		return table
+	}, func() {
+		fmt.Fprintf(stdout, "Velocity (%s) over the last %d sprints (| = average)\n", effortField, len(report.Series))
+		rows := make([]chartRow, 0, len(report.Series))
+		for _, point := range report.Series {
+			rows = append(rows, chartRow{Label: point.Iteration, Value: point.Completed, Mark: report.Average, HasMark: true,
+				Note: fmt.Sprintf("late %s, open %s", formatHours(point.CompletedLate), formatHours(point.Incomplete))})
+		}
+		printBarChart(stdout, rows)
+		fmt.Fprintf(stdout, "Average: %s\n", formatHours(report.Average))
+	})
+}
+
+// iterationRevisions returns the revisions of the items under path at any
+// of the given times (the zero time meaning now), optionally of the given
+// types only. Selecting at the sprint's start and finish keeps the items
+// carried over to a later sprint; the reports then check each revision's
+// iteration path.
+func iterationRevisions(ctx context.Context, client *api.Client, path string, types []string, times []time.Time) (map[int][]api.WorkItem, error) {
+	query := fmt.Sprintf("SELECT [System.Id] FROM WorkItems WHERE [System.TeamProject] = @Project AND [System.IterationPath] UNDER '%s'", escapeWiql(path))
+	if len(types) > 0 {
+		query += fmt.Sprintf(" AND [System.WorkItemType] IN (%s)", joinWiqlValues(types))
+	}
+	revisions := map[int][]api.WorkItem{}
+	for _, asOf := range times {
+		resp, err := client.Wiql(ctx, query, 0, asOf)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range collectIDs(resp) {
+			if _, ok := revisions[id]; ok {
+				continue
+			}
+			revs, err := client.GetWorkItemRevisions(ctx, id, 0)
+			if err != nil {
+				return nil, err
+			}
+			revisions[id] = revs
+		}
+	}
+	return revisions, nil
+}
+
+// sprintSelectionTimes are the times iterationRevisions selects a sprint's
+// items at: its start, the end of its finish day and now. Times not yet
+// reached are left to now, and missing dates are skipped.
+func sprintSelectionTimes(start, finish, now time.Time) []time.Time {
+	times := []time.Time{}
+	if !start.IsZero() && start.Before(now) {
+		times = append(times, start)
+	}
+	if !finish.IsZero() && finish.AddDate(0, 0, 1).Before(now) {
+		times = append(times, finish.AddDate(0, 0, 1))
+	}
+	return append(times, time.Time{})
+}
+
+// revisionStateCategories maps "type|state", lowercased, to the state
+// category for every type in revisions.
+func revisionStateCategories(ctx context.Context, validator *fieldValidator, revisions map[int][]api.WorkItem) (map[string]string, error) {
+	categories := map[string]string{}
+	seen := map[string]bool{}
+	for _, revs := range revisions {
+		for _, rev := range revs {
+			typeName := workItemType(rev)
+			if typeName == "" || seen[strings.ToLower(typeName)] {
+				continue
+			}
+			seen[strings.ToLower(typeName)] = true
+			states, err := validator.loadStates(ctx, typeName)
+			if err != nil {
+				return nil, err
+			}
+			for _, state := range states {
+				categories[strings.ToLower(typeName+"|"+state.Name)] = state.Category
+			}
+		}
+	}
+	return categories, nil
+}
+
+func revisionCategory(categories map[string]string, rev api.WorkItem) string {
+	state, _ := rev.Fields["System.State"].(string)
+	return categories[strings.ToLower(workItemType(rev)+"|"+state)]
+}
+
+// computeBurndown replays revisions to the end of each day up to today.
+// The ideal line starts at the first day's remaining work.
+func computeBurndown(revisions map[int][]api.WorkItem, path string, days []time.Time, today time.Time, categories map[string]string) []burndownPoint {
+	series := []burndownPoint{}
+	for index, day := range days {
+		if day.After(today) {
+			break
+		}
+		point := burndownPoint{Date: day.Format("2006-01-02")}
+		for _, revs := range revisions {
+			rev, ok := revisionAt(revs, day.AddDate(0, 0, 1))
+			if !ok || !underIterationPath(rev, path) {
+				continue
+			}
+			if category := revisionCategory(categories, rev); category == stateCategoryCompleted || category == stateCategoryRemoved {
+				continue
+			}
+			point.OpenItems++
+			if remaining, ok := rev.Fields[remainingWorkField].(float64); ok {
+				point.Remaining += remaining
+			}
+		}
+		if index == 0 || len(days) == 1 {
+			point.Ideal = point.Remaining
+		} else {
+			point.Ideal = series[0].Ideal * float64(len(days)-1-index) / float64(len(days)-1)
+		}
+		point.Remaining = math.Round(point.Remaining*100) / 100
+		point.Ideal = math.Round(point.Ideal*100) / 100
+		series = append(series, point)
+	}
+	return series
+}
+
+// computeVelocity sums the effort of requirements done in the sprint by the
+// end of finish, done later while still in the sprint, and in the sprint but
+// not done at finish, wherever they are now. Removed items do not count, nor
+// do items moved out before finish.
+func computeVelocity(revisions map[int][]api.WorkItem, path, effortField string, finish time.Time, categories map[string]string) (completed, late, incomplete float64) {
+	effortOf := func(rev api.WorkItem) float64 {
+		effort, _ := rev.Fields[effortField].(float64)
+		return effort
+	}
+	for _, revs := range revisions {
+		if len(revs) == 0 {
+			continue
+		}
+		current := revs[len(revs)-1]
+		atFinish, ok := revisionAt(revs, finish.AddDate(0, 0, 1))
+		inSprint := ok && underIterationPath(atFinish, path)
+		finishCategory := revisionCategory(categories, atFinish)
+		currentCategory := revisionCategory(categories, current)
+		switch {
+		case inSprint && finishCategory == stateCategoryCompleted:
+			completed += effortOf(atFinish)
+		case inSprint && finishCategory == stateCategoryRemoved, currentCategory == stateCategoryRemoved:
+			continue
+		case currentCategory == stateCategoryCompleted && underIterationPath(current, path):
+			late += effortOf(current)
+		case inSprint:
+			incomplete += effortOf(atFinish)
+		}
+	}
+	return completed, late, incomplete
+}
+
+// revisionAt returns the last revision changed before before.
+func revisionAt(revs []api.WorkItem, before time.Time) (api.WorkItem, bool) {
+	var found api.WorkItem
+	ok := false
+	for _, rev := range revs {
+		changed, _ := rev.Fields["System.ChangedDate"].(string)
+		at, err := time.Parse(time.RFC3339Nano, changed)
+		if err != nil || !at.Before(before) {
+			continue
+		}
+		if !ok || rev.Rev > found.Rev {
+			found, ok = rev, true
+		}
+	}
+	return found, ok
+}
+
+func underIterationPath(rev api.WorkItem, path string) bool {
+	value, _ := rev.Fields["System.IterationPath"].(string)
+	return strings.EqualFold(value, path) || strings.HasPrefix(strings.ToLower(value), strings.ToLower(path)+`\`)
+}
+
+// printBarChart draws one horizontal bar per row, scaled so the largest
+// value or mark is chartWidth characters long.
+func printBarChart(w io.Writer, rows []chartRow) {
+	labelWidth, max := 0, 0.0
+	for _, row := range rows {
+		if len(row.Label) > labelWidth {
+			labelWidth = len(row.Label)
+		}
+		max = math.Max(max, math.Max(row.Value, row.Mark))
+	}
+	scale := func(value float64) int {
+		if max <= 0 {
+			return 0
+		}
+		return int(math.Round(value / max * chartWidth))
+	}
+	for _, row := range rows {
+		bar := []rune(strings.Repeat("#", scale(row.Value)) + strings.Repeat(" ", chartWidth+1-scale(row.Value)))
+		if row.HasMark {
+			bar[scale(row.Mark)] = '|'
+		}
+		line := fmt.Sprintf("%-*s  %s %s", labelWidth, row.Label, string(bar), formatHours(row.Value))
+		if row.Note != "" {
+			line += "  (" + row.Note + ")"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
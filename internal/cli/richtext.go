@@ -2,12 +2,18 @@ package cli
 
 import (
 	"bytes"
+	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
 	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 
+	"tfs-cli/internal/api"
 	"tfs-cli/internal/errs"
 )
 
@@ -42,3 +48,626 @@ func renderRichText(value string) (string, error) {
 	}
 	return strings.TrimSpace(rendered.String()), nil
 }
+
+// Rich-text display modes accepted by --rich-text.
+const (
+	richTextMarkdownMode = "markdown"
+	richTextHTMLMode     = "html"
+	richTextPlainMode    = "plain"
+)
+
+// htmlBreak marks a <br> inside inline content until the paragraph is
+// assembled.
+const htmlBreak = "\x00"
+
+var (
+	htmlBlockTags = map[string]bool{
+		"address": true, "article": true, "aside": true, "blockquote": true, "body": true, "center": true,
+		"dd": true, "div": true, "dl": true, "dt": true, "figure": true, "footer": true, "h1": true,
+		"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+		"html": true, "li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true,
+		"section": true, "table": true, "tbody": true, "td": true, "tfoot": true, "th": true,
+		"thead": true, "tr": true, "ul": true,
+	}
+	htmlSpaces          = regexp.MustCompile(`[ \t\r\n\f]+`)
+	markdownListStart   = regexp.MustCompile(`^(\d{1,9})([.)])(\s|$)`)
+	markdownEntityStart = regexp.MustCompile(`^&(#[0-9]+|#[xX][0-9a-fA-F]+|[A-Za-z][A-Za-z0-9]*);`)
+)
+
+// parseRichTextMode validates --rich-text; empty selects Markdown for text
+// output and HTML (unchanged) for JSON.
+func parseRichTextMode(value string, jsonMode bool) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		if jsonMode {
+			return richTextHTMLMode, nil
+		}
+		return richTextMarkdownMode, nil
+	case richTextMarkdownMode, "md":
+		return richTextMarkdownMode, nil
+	case richTextHTMLMode:
+		return richTextHTMLMode, nil
+	case richTextPlainMode, "text":
+		return richTextPlainMode, nil
+	}
+	return "", errs.New("invalid_args", "rich-text must be markdown, html, or plain", value)
+}
+
+// convertRichText renders a rich-text field value in mode.
+func convertRichText(value, mode string) string {
+	switch mode {
+	case richTextMarkdownMode:
+		return htmlToMarkdown(value)
+	case richTextPlainMode:
+		return htmlToPlain(value)
+	}
+	return value
+}
+
+// convertWorkItemRichText returns wi with its rich-text fields rendered in
+// mode. The original field map is left untouched.
+func convertWorkItemRichText(wi api.WorkItem, mode string) api.WorkItem {
+	if mode == richTextHTMLMode || len(wi.Fields) == 0 {
+		return wi
+	}
+	fields := make(map[string]interface{}, len(wi.Fields))
+	for name, value := range wi.Fields {
+		if text, ok := value.(string); ok {
+			if _, rich := richTextFields[strings.ToLower(name)]; rich {
+				value = convertRichText(text, mode)
+			}
+		}
+		fields[name] = value
+	}
+	wi.Fields = fields
+	return wi
+}
+
+func convertCommentsRichText(comments []api.WorkItemComment, mode string) []api.WorkItemComment {
+	if mode == richTextHTMLMode {
+		return comments
+	}
+	converted := make([]api.WorkItemComment, len(comments))
+	for index, comment := range comments {
+		comment.Text = convertRichText(comment.Text, mode)
+		converted[index] = comment
+	}
+	return converted
+}
+
+// richTextSections returns "Label:\n<text>" for each non-empty long-text
+// field worth showing in text output. System.History is left out because
+// comments are listed separately.
+func richTextSections(fields map[string]interface{}) []string {
+	sections := []string{}
+	for _, field := range []struct{ name, label string }{
+		{"System.Description", "Description"},
+		{"Microsoft.VSTS.Common.AcceptanceCriteria", "Acceptance Criteria"},
+		{"Microsoft.VSTS.TCM.ReproSteps", "Repro Steps"},
+	} {
+		if text, ok := fields[field.name].(string); ok && strings.TrimSpace(text) != "" {
+			sections = append(sections, field.label+":\n"+text)
+		}
+	}
+	return sections
+}
+
+// htmlToMarkdown converts the HTML TFS stores in rich-text fields back to
+// Markdown that renderRichText turns into the same HTML again.
+func htmlToMarkdown(value string) string {
+	return convertHTML(value, false)
+}
+
+// htmlToPlain converts rich-text HTML to readable text without Markdown
+// markup.
+func htmlToPlain(value string) string {
+	return convertHTML(value, true)
+}
+
+func convertHTML(value string, plain bool) string {
+	if !strings.ContainsAny(value, "<&") {
+		return strings.TrimSpace(value)
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(value), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	converter := htmlConverter{plain: plain}
+	return strings.Join(converter.blocks(nodes), "\n\n")
+}
+
+type htmlConverter struct {
+	plain bool
+}
+
+func childNodes(n *html.Node) []*html.Node {
+	nodes := []*html.Node{}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		nodes = append(nodes, child)
+	}
+	return nodes
+}
+
+func htmlAttr(n *html.Node, name string) string {
+	for _, attr := range n.Attr {
+		if strings.EqualFold(attr.Key, name) {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// blocks converts a run of sibling nodes, gathering inline content into
+// paragraphs between block elements.
+func (c htmlConverter) blocks(nodes []*html.Node) []string {
+	out := []string{}
+	var inline strings.Builder
+	flush := func() {
+		out = append(out, c.paragraphs(inline.String())...)
+		inline.Reset()
+	}
+	for _, n := range nodes {
+		if n.Type == html.ElementNode && htmlBlockTags[n.Data] {
+			flush()
+			out = append(out, c.block(n)...)
+			continue
+		}
+		inline.WriteString(c.inline(n))
+	}
+	flush()
+	return out
+}
+
+func (c htmlConverter) block(n *html.Node) []string {
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := c.singleLine(c.inlineChildren(n))
+		if text == "" {
+			return nil
+		}
+		if c.plain {
+			return []string{text}
+		}
+		return []string{strings.Repeat("#", int(n.Data[1]-'0')) + " " + text}
+	case "ul", "ol":
+		if list := c.list(n); list != "" {
+			return []string{list}
+		}
+		return nil
+	case "table":
+		if table := c.table(n); table != "" {
+			return []string{table}
+		}
+		return nil
+	case "pre":
+		return []string{c.pre(n)}
+	case "blockquote":
+		inner := strings.Join(c.blocks(childNodes(n)), "\n\n")
+		if inner == "" {
+			return nil
+		}
+		return []string{prefixLines(inner, "> ", ">")}
+	case "hr":
+		return []string{"---"}
+	}
+	return c.blocks(childNodes(n))
+}
+
+func (c htmlConverter) inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for _, child := range childNodes(n) {
+		b.WriteString(c.inline(child))
+	}
+	return b.String()
+}
+
+func (c htmlConverter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		text := htmlSpaces.ReplaceAllString(n.Data, " ")
+		if c.plain {
+			return text
+		}
+		return escapeMarkdownText(text)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	switch n.Data {
+	case "br":
+		return htmlBreak
+	case "script", "style", "head", "title":
+		return ""
+	case "strong", "b":
+		return c.wrapInline(c.inlineChildren(n), "**")
+	case "em", "i":
+		return c.wrapInline(c.inlineChildren(n), "*")
+	case "del", "s", "strike":
+		return c.wrapInline(c.inlineChildren(n), "~~")
+	case "code", "kbd", "tt", "samp":
+		return c.codeSpan(htmlSpaces.ReplaceAllString(textContent(n), " "))
+	case "a":
+		return c.link(n)
+	case "img":
+		alt := htmlSpaces.ReplaceAllString(htmlAttr(n, "alt"), " ")
+		src := htmlAttr(n, "src")
+		if c.plain {
+			return firstNonEmpty(alt, src)
+		}
+		return "![" + escapeMarkdownText(alt) + "](" + markdownDestination(src) + ")"
+	}
+	if htmlBlockTags[n.Data] {
+		// A block inside inline content (for example a <div> in a <span>)
+		// still starts a new line.
+		return htmlBreak + strings.Join(c.blocks(childNodes(n)), htmlBreak) + htmlBreak
+	}
+	return c.inlineChildren(n)
+}
+
+// wrapInline surrounds text with an emphasis marker, keeping the outer
+// spaces outside so the marker stays attached to the words.
+func (c htmlConverter) wrapInline(text, marker string) string {
+	trimmed := strings.Trim(text, " ")
+	if c.plain || trimmed == "" || strings.Contains(trimmed, htmlBreak) {
+		return text
+	}
+	lead := text[:len(text)-len(strings.TrimLeft(text, " "))]
+	trail := text[len(strings.TrimRight(text, " ")):]
+	return lead + marker + trimmed + marker + trail
+}
+
+func (c htmlConverter) codeSpan(code string) string {
+	if c.plain || code == "" {
+		return code
+	}
+	longest, run := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		return fence + " " + code + " " + fence
+	}
+	return fence + code + fence
+}
+
+// link renders an anchor. TFS mentions (data-vss-mention) become their
+// "@Display Name" text.
+func (c htmlConverter) link(n *html.Node) string {
+	text := c.inlineChildren(n)
+	href := strings.TrimSpace(htmlAttr(n, "href"))
+	if htmlAttr(n, "data-vss-mention") != "" || strings.Contains(htmlAttr(n, "class"), "mention") {
+		return text
+	}
+	if href == "" || href == "#" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return text
+	}
+	label := strings.TrimSpace(strings.ReplaceAll(text, htmlBreak, " "))
+	if c.plain {
+		if label == "" || label == href {
+			return href
+		}
+		return label + " (" + href + ")"
+	}
+	if label == "" || label == escapeMarkdownText(href) {
+		if strings.Contains(href, "://") || strings.HasPrefix(href, "mailto:") {
+			return "<" + href + ">"
+		}
+		label = escapeMarkdownText(href)
+	}
+	return "[" + label + "](" + markdownDestination(href) + ")"
+}
+
+// markdownDestination encodes a link target the way the Markdown renderer
+// would, so converted links survive another round trip unchanged.
+func markdownDestination(url string) string {
+	url = strings.NewReplacer(" ", "%20", "<", "%3C", ">", "%3E").Replace(url)
+	if strings.ContainsAny(url, "()") {
+		return "<" + url + ">"
+	}
+	return url
+}
+
+func (c htmlConverter) list(n *html.Node) string {
+	ordered := n.Data == "ol"
+	number := 1
+	if start, err := strconv.Atoi(htmlAttr(n, "start")); err == nil && ordered {
+		number = start
+	}
+	loose := false
+	for _, li := range childNodes(n) {
+		for _, child := range childNodes(li) {
+			if li.Data == "li" && child.Type == html.ElementNode && child.Data == "p" {
+				loose = true
+			}
+		}
+	}
+	separator := "\n"
+	if loose {
+		separator = "\n\n"
+	}
+	items := []string{}
+	widths := []int{}
+	for _, child := range childNodes(n) {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if (child.Data == "ul" || child.Data == "ol") && len(items) > 0 {
+			// The TFS editor nests lists directly inside lists to indent
+			// them; attach those to the previous item.
+			if nested := c.list(child); nested != "" {
+				last := len(items) - 1
+				items[last] += "\n" + indentLines(nested, widths[last])
+			}
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		content := strings.Join(c.blocks(childNodes(child)), separator)
+		items = append(items, strings.TrimRight(marker+indentRest(content, len(marker)), " "))
+		widths = append(widths, len(marker))
+	}
+	return strings.Join(items, separator)
+}
+
+func (c htmlConverter) table(n *html.Node) string {
+	rows := [][]string{}
+	aligns := []string{}
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for _, child := range childNodes(node) {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.Data {
+			case "thead", "tbody", "tfoot":
+				walk(child)
+			case "tr":
+				row := []string{}
+				for _, cell := range childNodes(child) {
+					if cell.Type != html.ElementNode || (cell.Data != "td" && cell.Data != "th") {
+						continue
+					}
+					row = append(row, c.tableCell(cell))
+					if len(rows) == 0 {
+						aligns = append(aligns, cellAlignment(cell))
+					}
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	walk(n)
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if columns == 0 {
+		return ""
+	}
+	lines := []string{}
+	for index, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		if c.plain {
+			lines = append(lines, strings.TrimSpace(strings.Join(row, " | ")))
+			continue
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if index == 0 {
+			separators := make([]string, columns)
+			for column := range separators {
+				align := ""
+				if column < len(aligns) {
+					align = aligns[column]
+				}
+				switch align {
+				case "left":
+					separators[column] = ":---"
+				case "right":
+					separators[column] = "---:"
+				case "center":
+					separators[column] = ":---:"
+				default:
+					separators[column] = "---"
+				}
+			}
+			lines = append(lines, "| "+strings.Join(separators, " | ")+" |")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// tableCell flattens a cell to one line; line breaks become <br>, which the
+// Markdown renderer passes through.
+func (c htmlConverter) tableCell(cell *html.Node) string {
+	paragraphs := c.blocks(childNodes(cell))
+	lineBreak := "<br>"
+	if c.plain {
+		lineBreak = " "
+	}
+	text := strings.Join(paragraphs, lineBreak)
+	text = strings.NewReplacer("\\\n", lineBreak, "\n", " ").Replace(text)
+	if c.plain {
+		return text
+	}
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+func cellAlignment(cell *html.Node) string {
+	if align := strings.ToLower(htmlAttr(cell, "align")); align != "" {
+		return align
+	}
+	style := strings.ToLower(strings.ReplaceAll(htmlAttr(cell, "style"), " ", ""))
+	for _, align := range []string{"left", "right", "center"} {
+		if strings.Contains(style, "text-align:"+align) {
+			return align
+		}
+	}
+	return ""
+}
+
+func (c htmlConverter) pre(n *html.Node) string {
+	language := ""
+	for _, child := range childNodes(n) {
+		if child.Type == html.ElementNode && child.Data == "code" {
+			for _, class := range strings.Fields(htmlAttr(child, "class")) {
+				if strings.HasPrefix(class, "language-") {
+					language = strings.TrimPrefix(class, "language-")
+				}
+			}
+		}
+	}
+	code := strings.Trim(preText(n), "\n")
+	if c.plain {
+		return code
+	}
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+func preText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	if n.Type == html.ElementNode && n.Data == "br" {
+		return "\n"
+	}
+	var b strings.Builder
+	for _, child := range childNodes(n) {
+		b.WriteString(preText(child))
+	}
+	return b.String()
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for _, child := range childNodes(n) {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+// paragraphs assembles inline content into paragraphs: single <br> become
+// hard line breaks and empty lines (<br><br>) separate paragraphs.
+func (c htmlConverter) paragraphs(inline string) []string {
+	out := []string{}
+	lines := []string{}
+	flush := func() {
+		if len(lines) == 0 {
+			return
+		}
+		lineBreak := "\\\n"
+		if c.plain {
+			lineBreak = "\n"
+		}
+		out = append(out, strings.Join(lines, lineBreak))
+		lines = nil
+	}
+	for _, line := range strings.Split(inline, htmlBreak) {
+		line = strings.TrimSpace(htmlSpaces.ReplaceAllString(line, " "))
+		if line == "" {
+			flush()
+			continue
+		}
+		if !c.plain {
+			line = escapeMarkdownLineStart(line)
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return out
+}
+
+func (c htmlConverter) singleLine(inline string) string {
+	return strings.TrimSpace(htmlSpaces.ReplaceAllString(strings.ReplaceAll(inline, htmlBreak, " "), " "))
+}
+
+// escapeMarkdownText escapes characters that would otherwise start Markdown
+// markup. Underscores inside words are left alone since they never start
+// emphasis.
+func escapeMarkdownText(text string) string {
+	var b strings.Builder
+	runes := []rune(text)
+	for index, r := range runes {
+		switch r {
+		case '\\', '`', '*', '[', ']', '<', '~':
+			b.WriteRune('\\')
+		case '_':
+			before := index > 0 && isWordRune(runes[index-1])
+			after := index+1 < len(runes) && isWordRune(runes[index+1])
+			if !before || !after {
+				b.WriteRune('\\')
+			}
+		case '&':
+			if markdownEntityStart.MatchString(string(runes[index:])) {
+				b.WriteRune('\\')
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// escapeMarkdownLineStart escapes text at the start of a line that would be
+// read as a heading, quote, list item or thematic break.
+func escapeMarkdownLineStart(line string) string {
+	if match := markdownListStart.FindStringSubmatchIndex(line); match != nil {
+		return line[:match[4]] + `\` + line[match[4]:]
+	}
+	switch line[0] {
+	case '#', '>', '-', '+', '=':
+		return `\` + line
+	}
+	return line
+}
+
+func indentRest(text string, width int) string {
+	lines := strings.Split(text, "\n")
+	pad := strings.Repeat(" ", width)
+	for index := 1; index < len(lines); index++ {
+		if lines[index] != "" {
+			lines[index] = pad + lines[index]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func indentLines(text string, width int) string {
+	return strings.Repeat(" ", width) + indentRest(text, width)
+}
+
+func prefixLines(text, prefix, emptyPrefix string) string {
+	lines := strings.Split(text, "\n")
+	for index, line := range lines {
+		if line == "" {
+			lines[index] = emptyPrefix
+		} else {
+			lines[index] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"regexp"
 	"strings"
 	"testing"
 )
@@ -75,3 +76,89 @@ func assertContainsAll(t *testing.T, value string, fragments ...string) {
 		}
 	}
 }
+
+func TestHTMLToMarkdownRoundTripsThroughRenderer(t *testing.T) {
+	inputs := []string{
+		"### План\n\nТекст с `кодом`, **жирным**, *курсивом* и ~~зачёркнутым~~.\n\n- Первый пункт\n- Второй пункт\n  - Вложенный\n\n10. ten\n11. eleven",
+		"| A | B |\n| :--- | ---: |\n| 1 | x\\|y |",
+		"```go\nfmt.Println(\"hi\")\n```\n\n> quoted\n> text\n\nLine one\\\nLine two\n\n---\n\n![logo](https://x/y.png) [site](https://example.com/a%20b) <https://example.com/a>",
+		"Use snake_case, _private, 2*3, [brackets], \\# not a heading and &amp; entities\n\n1\\. not a list",
+		"- loose\n\n- items\n\n  with two paragraphs",
+	}
+	for _, input := range inputs {
+		first, err := renderRichText(input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		markdown := htmlToMarkdown(first)
+		second, err := renderRichText(markdown)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if normalizeHTML(first) != normalizeHTML(second) {
+			t.Fatalf("round trip changed the HTML\nmarkdown:\n%s\nfirst:\n%s\nsecond:\n%s", markdown, first, second)
+		}
+		if again := htmlToMarkdown(second); again != markdown {
+			t.Fatalf("conversion is not stable:\n%s\n---\n%s", markdown, again)
+		}
+	}
+}
+
+func TestHTMLToMarkdownConvertsTFSMarkup(t *testing.T) {
+	input := `<div>Steps:<br></div><div><ol><li>Open <b>page </b>now</li><li>Click <a href="https://tfs.example/a b">link</a></li></ol></div>` +
+		`<div><a href="#" data-vss-mention="version:2.0,1f2e">@Jane Doe</a> please check<br>second line<br><br>new para</div>` +
+		`<table><tr><td>a|b</td><td>c<br>d</td></tr><tr><td>1</td><td>2</td></tr></table>` +
+		`<ul><li>x</li><ul><li>nested</li></ul><li>y</li></ul><div>- not a list &lt;b&gt;</div>`
+
+	want := strings.Join([]string{
+		"Steps:",
+		"",
+		"1. Open **page** now",
+		"2. Click [link](https://tfs.example/a%20b)",
+		"",
+		"@Jane Doe please check\\",
+		"second line",
+		"",
+		"new para",
+		"",
+		"| a\\|b | c<br>d |",
+		"| --- | --- |",
+		"| 1 | 2 |",
+		"",
+		"- x",
+		"  - nested",
+		"- y",
+		"",
+		"\\- not a list \\<b>",
+	}, "\n")
+	got := htmlToMarkdown(input)
+	if got != want {
+		t.Fatalf("unexpected Markdown:\n%s\nwant:\n%s", got, want)
+	}
+
+	rendered, err := renderRichText(got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again := htmlToMarkdown(rendered); again != got {
+		t.Fatalf("conversion is not stable:\n%s\n---\n%s", got, again)
+	}
+}
+
+func TestHTMLToPlain(t *testing.T) {
+	input := `<p>Hello <strong>team</strong>,<br>see <a href="https://tfs.example/x">the spec</a> &amp; <code>a*b</code></p><ul><li>one</li></ul>`
+	want := "Hello team,\nsee the spec (https://tfs.example/x) & a*b\n\n- one"
+	if got := htmlToPlain(input); got != want {
+		t.Fatalf("unexpected text:\n%q\nwant:\n%q", got, want)
+	}
+	if got := htmlToMarkdown("plain *markdown* text"); got != "plain *markdown* text" {
+		t.Fatalf("text without HTML should be kept as is, got %q", got)
+	}
+}
+
+// normalizeHTML ignores whitespace differences that do not change how the
+// HTML renders.
+func normalizeHTML(value string) string {
+	value = regexp.MustCompile(`\s+`).ReplaceAllString(value, " ")
+	return regexp.MustCompile(`>\s+<`).ReplaceAllString(value, "><")
+}
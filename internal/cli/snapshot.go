@@ -0,0 +1,272 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+// snapshotDefaultFields are compared when neither --columns nor the query's
+// SELECT list names any.
+var snapshotDefaultFields = []string{
+	"System.Title",
+	"System.State",
+	"System.AssignedTo",
+	"System.AreaPath",
+	"System.IterationPath",
+	"System.Tags",
+}
+
+type snapshotItem struct {
+	ID    int    `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	State string `json:"state"`
+}
+
+type snapshotChange struct {
+	snapshotItem
+	Fields []historyFieldChange `json:"fields"`
+}
+
+// snapshotDiff compares the results of a query at two times. Changed items
+// matched both times and differ in one of Fields.
+type snapshotDiff struct {
+	From      string           `json:"from"`
+	To        string           `json:"to"`
+	Fields    []string         `json:"fields"`
+	Added     []snapshotItem   `json:"added"`
+	Removed   []snapshotItem   `json:"removed"`
+	Changed   []snapshotChange `json:"changed"`
+	Unchanged int              `json:"unchanged"`
+}
+
+func runDiffSnapshot(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff-snapshot", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	wiql := fs.String("wiql", "", "WIQL query to run at both times")
+	fromArg := fs.String("from", "", "Earlier date or time (local time)")
+	toArg := fs.String("to", "", "Later date or time (default: now)")
+	columnsCSV := fs.String("columns", "", "Comma-separated fields to compare (default: the query's SELECT list)")
+	jsonExplicit := flagProvided(args, "json")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if strings.TrimSpace(*wiql) == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "--wiql is required", nil), flags.json)
+		return 1
+	}
+	if api.HasAsOfClause(*wiql) {
+		output.WriteError(stderr, errs.New("invalid_args", "the query has its own ASOF clause; remove it to compare --from and --to", nil), flags.json)
+		return 1
+	}
+	if strings.TrimSpace(*fromArg) == "" {
+		output.WriteError(stderr, errs.New("invalid_args", "--from is required", nil), flags.json)
+		return 1
+	}
+	from, err := parseAsOf("from", *fromArg)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	to, err := parseAsOf("to", *toArg)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	if !to.IsZero() && !from.Before(to) {
+		output.WriteError(stderr, errs.New("invalid_args", "--from must be before --to", nil), flags.json)
+		return 1
+	}
+	columns, err := parseColumns(*columnsCSV)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	if !jsonExplicit && !flags.format.set {
+		ctx.jsonMode = false
+	}
+
+	reqCtx := context.Background()
+	fromResp, err := client.Wiql(reqCtx, *wiql, 0, from)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	toResp, err := client.Wiql(reqCtx, *wiql, 0, to)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	fields := columnFields(columns)
+	if len(fields) == 0 {
+		for _, column := range toResp.Columns {
+			if !strings.EqualFold(column.ReferenceName, "System.Id") {
+				fields = append(fields, column.ReferenceName)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		fields = snapshotDefaultFields
+	}
+	fromIDs, toIDs := collectIDs(fromResp), collectIDs(toResp)
+	fromItems, err := fetchSnapshot(reqCtx, client, fromIDs, fields, from)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	toItems, err := fetchSnapshot(reqCtx, client, toIDs, fields, to)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	result := diffSnapshots(fromIDs, fromItems, toIDs, toItems, fields)
+	result.From = formatAsOfLabel(from)
+	result.To = formatAsOfLabel(to)
+	return renderOutput(ctx, result, func() output.Table {
+		table := output.Table{Headers: []string{"Change", "ID", "Type", "Title", "Field", "From", "To"}}
+		for _, item := range result.Added {
+			table.Rows = append(table.Rows, []string{"added", strconv.Itoa(item.ID), item.Type, item.Title, "", "", ""})
+		}
+		for _, item := range result.Removed {
+			table.Rows = append(table.Rows, []string{"removed", strconv.Itoa(item.ID), item.Type, item.Title, "", "", ""})
+		}
+		for _, item := range result.Changed {
+			for _, change := range item.Fields {
+				table.Rows = append(table.Rows, []string{"changed", strconv.Itoa(item.ID), item.Type, item.Title, change.Field,
+					output.FormatColumnValue(change.OldValue), output.FormatColumnValue(change.NewValue)})
+			}
+		}
+		return table
+	}, func() {
+		printSnapshotDiff(stdout, result)
+	})
+}
+
+// fetchSnapshot returns the given fields of ids as they were at asOf.
+func fetchSnapshot(ctx context.Context, client *api.Client, ids []int, fields []string, asOf time.Time) (map[int]api.WorkItem, error) {
+	wanted := []string{"System.WorkItemType", "System.Title", "System.State"}
+	for _, field := range fields {
+		if !containsFold(wanted, field) {
+			wanted = append(wanted, field)
+		}
+	}
+	items := make(map[int]api.WorkItem, len(ids))
+	for i := 0; i < len(ids); i += maxBatchSize {
+		end := i + maxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch, err := client.GetWorkItemsBatch(ctx, ids[i:end], wanted, asOf)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range batch {
+			items[item.ID] = item
+		}
+	}
+	return items, nil
+}
+
+// diffSnapshots compares two query results, keeping the order of the
+// later result for added and changed items and of the earlier one for
+// removed items.
+func diffSnapshots(fromIDs []int, from map[int]api.WorkItem, toIDs []int, to map[int]api.WorkItem, fields []string) snapshotDiff {
+	result := snapshotDiff{Fields: fields, Added: []snapshotItem{}, Removed: []snapshotItem{}, Changed: []snapshotChange{}}
+	before := map[int]bool{}
+	for _, id := range fromIDs {
+		before[id] = true
+	}
+	after := map[int]bool{}
+	for _, id := range toIDs {
+		after[id] = true
+		if !before[id] {
+			result.Added = append(result.Added, snapshotItemOf(id, to[id]))
+			continue
+		}
+		change := snapshotChange{snapshotItem: snapshotItemOf(id, to[id]), Fields: []historyFieldChange{}}
+		for _, field := range fields {
+			oldValue := currentFieldValue(from[id].Fields, field)
+			newValue := currentFieldValue(to[id].Fields, field)
+			if formatHistoryValue(oldValue) != formatHistoryValue(newValue) {
+				change.Fields = append(change.Fields, historyFieldChange{Field: field, OldValue: oldValue, NewValue: newValue})
+			}
+		}
+		if len(change.Fields) == 0 {
+			result.Unchanged++
+			continue
+		}
+		result.Changed = append(result.Changed, change)
+	}
+	for _, id := range fromIDs {
+		if !after[id] {
+			result.Removed = append(result.Removed, snapshotItemOf(id, from[id]))
+		}
+	}
+	return result
+}
+
+func snapshotItemOf(id int, wi api.WorkItem) snapshotItem {
+	item := snapshotItem{ID: id, Type: workItemType(wi)}
+	item.Title, _ = wi.Fields["System.Title"].(string)
+	item.State, _ = wi.Fields["System.State"].(string)
+	return item
+}
+
+func printSnapshotDiff(w io.Writer, result snapshotDiff) {
+	fmt.Fprintf(w, "%s -> %s: %d added, %d removed, %d changed, %d unchanged\n", result.From, result.To,
+		len(result.Added), len(result.Removed), len(result.Changed), result.Unchanged)
+	for _, item := range result.Added {
+		fmt.Fprintf(w, "+ %d  %s  %s (%s)\n", item.ID, item.Type, item.Title, item.State)
+	}
+	for _, item := range result.Removed {
+		fmt.Fprintf(w, "- %d  %s  %s (%s)\n", item.ID, item.Type, item.Title, item.State)
+	}
+	for _, item := range result.Changed {
+		fmt.Fprintf(w, "~ %d  %s  %s\n", item.ID, item.Type, item.Title)
+		for _, change := range item.Fields {
+			fmt.Fprintf(w, "    %s: %s -> %s\n", change.Field, formatHistoryValue(change.OldValue), formatHistoryValue(change.NewValue))
+		}
+	}
+}
+
+// parseAsOf reads the value of a date flag such as --asof. Dates without a
+// zone are local time, and a date alone means its start. An empty value
+// returns the zero time, meaning now.
+func parseAsOf(name, value string) (time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return time.Time{}, nil
+	}
+	asOf, ok := parseFieldDate(strings.TrimSpace(value))
+	if !ok {
+		return time.Time{}, errs.New("invalid_args", fmt.Sprintf("invalid --%s date, expected YYYY-MM-DD or YYYY-MM-DDTHH:MM", name), value)
+	}
+	return asOf, nil
+}
+
+func formatAsOfLabel(asOf time.Time) string {
+	if asOf.IsZero() {
+		return "now"
+	}
+	return asOf.Format("2006-01-02 15:04")
+}
+
+func wiqlAsOfValueFlags() map[string]bool {
+	flags := wiqlTreeValueFlags()
+	flags["asof"] = true
+	return flags
+}
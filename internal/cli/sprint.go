@@ -0,0 +1,504 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+// sprintNow is the clock the sprint commands count remaining days from.
+var sprintNow = time.Now
+
+// sprintInfo describes a team iteration with its working days, excluding
+// the team's days off.
+type sprintInfo struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Path            string `json:"path"`
+	StartDate       string `json:"startDate,omitempty"`
+	FinishDate      string `json:"finishDate,omitempty"`
+	TimeFrame       string `json:"timeFrame,omitempty"`
+	Team            string `json:"team,omitempty"`
+	WorkingDays     int    `json:"workingDays"`
+	WorkingDaysLeft int    `json:"workingDaysLeft"`
+}
+
+// capacityRow is one team member's capacity in hours. Capacity covers the
+// whole sprint and RemainingCapacity the working days from today;
+// AssignedWork is the Remaining Work of the sprint items assigned to them.
+type capacityRow struct {
+	Member            string                 `json:"member"`
+	UniqueName        string                 `json:"uniqueName"`
+	Activities        []api.ActivityCapacity `json:"activities"`
+	CapacityPerDay    float64                `json:"capacityPerDay"`
+	DaysOff           int                    `json:"daysOff"`
+	Capacity          float64                `json:"capacity"`
+	RemainingCapacity float64                `json:"remainingCapacity"`
+	AssignedWork      float64                `json:"assignedWork"`
+}
+
+type capacityReport struct {
+	Iteration         sprintInfo    `json:"iteration"`
+	Members           []capacityRow `json:"members"`
+	Capacity          float64       `json:"capacity"`
+	RemainingCapacity float64       `json:"remainingCapacity"`
+	AssignedWork      float64       `json:"assignedWork"`
+	UnassignedWork    float64       `json:"unassignedWork"`
+}
+
+func runSprint(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		output.WriteError(stderr, errs.New("invalid_args", "sprint subcommand is required", nil), true)
+		return 1
+	}
+	switch args[0] {
+	case "current":
+		return runSprintCurrent(args[1:], stdout, stderr)
+	case "items":
+		return runSprintItems(args[1:], stdout, stderr)
+	case "capacity":
+		return runSprintCapacity(args[1:], stdout, stderr)
+	default:
+		output.WriteError(stderr, errs.New("unknown_command", "unknown sprint subcommand", args[0]), true)
+		return 1
+	}
+}
+
+func runSprintCurrent(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("sprint current", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	team := fs.String("team", "", "Team name (default: config team, else the project's default team)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	teamName := sprintTeam(ctx, *team)
+	reqCtx := context.Background()
+	current, err := resolveTeamIteration(reqCtx, client, teamName, "")
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	info, _, _, err := sprintCalendar(reqCtx, client, teamName, current)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	return renderOutput(ctx, info, func() output.Table {
+		return output.RecordTable([]string{"Name", "Path", "StartDate", "FinishDate", "WorkingDays", "WorkingDaysLeft", "Team"},
+			info.Name, info.Path, shortDate(info.StartDate), shortDate(info.FinishDate), info.WorkingDays, info.WorkingDaysLeft, info.Team)
+	}, func() {
+		fmt.Fprintf(stdout, "%s\n", sprintHeadline(info))
+		fmt.Fprintf(stdout, "Path: %s\n", info.Path)
+	})
+}
+
+func runSprintItems(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("sprint items", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	team := fs.String("team", "", "Team name (default: config team, else the project's default team)")
+	iteration := fs.String("iteration", "", "Iteration name or path, @previous or @next (default: the current one)")
+	flat := fs.Bool("flat", false, "List the items without nesting tasks under their backlog items")
+	depth := fs.Int("depth", 0, "Maximum tree levels to show (0 = all)")
+	columnsCSV := fs.String("columns", "", "Comma-separated field reference names or aliases to show")
+	jsonExplicit := flagProvided(args, "json")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	columns, err := parseColumns(*columnsCSV)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	if !jsonExplicit && !flags.format.set {
+		ctx.jsonMode = false
+	}
+	teamName := sprintTeam(ctx, *team)
+	reqCtx := context.Background()
+	selected, err := resolveTeamIteration(reqCtx, client, teamName, *iteration)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	items, err := client.GetIterationWorkItems(reqCtx, teamName, selected.ID)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	resp := api.WiqlResponse{WorkItemLinks: items.WorkItemRelations}
+	if *flat {
+		resp = api.WiqlResponse{WorkItems: sprintItemRefs(items.WorkItemRelations)}
+	}
+	return renderQueryResult(ctx, client, resp, time.Time{}, *depth, columns)
+}
+
+func runSprintCapacity(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("sprint capacity", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	team := fs.String("team", "", "Team name (default: config team, else the project's default team)")
+	iteration := fs.String("iteration", "", "Iteration name or path, @previous or @next (default: the current one)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+	teamName := sprintTeam(ctx, *team)
+	reqCtx := context.Background()
+	selected, err := resolveTeamIteration(reqCtx, client, teamName, *iteration)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	report, err := buildCapacityReport(reqCtx, client, teamName, selected)
+	if err != nil {
+		output.WriteError(stderr, err, ctx.jsonMode)
+		return 1
+	}
+	return renderOutput(ctx, report, func() output.Table {
+		table := output.Table{Headers: []string{"Member", "UniqueName", "Activities", "CapacityPerDay", "DaysOff", "Capacity", "RemainingCapacity", "AssignedWork"}}
+		for _, row := range report.Members {
+			table.Rows = append(table.Rows, []string{row.Member, row.UniqueName, strings.Join(capacityActivityNames(row), ", "), formatHours(row.CapacityPerDay),
+				strconv.Itoa(row.DaysOff), formatHours(row.Capacity), formatHours(row.RemainingCapacity), formatHours(row.AssignedWork)})
+		}
+		return table
+	}, func() {
+		fmt.Fprintln(stdout, sprintHeadline(report.Iteration))
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "MEMBER\tACTIVITY\tPER DAY\tDAYS OFF\tCAPACITY\tLEFT\tASSIGNED")
+		for _, row := range report.Members {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", row.Member, strings.Join(capacityActivityNames(row), ", "), formatHours(row.CapacityPerDay), row.DaysOff,
+				formatHours(row.Capacity), formatHours(row.RemainingCapacity), formatHours(row.AssignedWork))
+		}
+		fmt.Fprintf(tw, "Total\t\t\t\t%s\t%s\t%s\n", formatHours(report.Capacity), formatHours(report.RemainingCapacity), formatHours(report.AssignedWork))
+		_ = tw.Flush()
+		if report.UnassignedWork > 0 {
+			fmt.Fprintf(stdout, "Unassigned remaining work: %s\n", formatHours(report.UnassignedWork))
+		}
+	})
+}
+
+func capacityActivityNames(row capacityRow) []string {
+	activities := make([]string, 0, len(row.Activities))
+	for _, activity := range row.Activities {
+		if activity.Name != "" {
+			activities = append(activities, activity.Name)
+		}
+	}
+	return activities
+}
+
+// sprintTeam returns the --team value, falling back to the configured team.
+func sprintTeam(ctx commandContext, team string) string {
+	if strings.TrimSpace(team) != "" {
+		return strings.TrimSpace(team)
+	}
+	return ctx.cfg.Team
+}
+
+// resolveTeamIteration finds the team iteration named by name: "" or
+// @current is the current sprint, @previous and @next its neighbours, and
+// anything else an iteration name or path.
+func resolveTeamIteration(ctx context.Context, client *api.Client, team, name string) (api.TeamSettingsIteration, error) {
+	name = strings.TrimSpace(name)
+	noCurrent := errs.New("iteration_not_found", "the team has no current iteration; check the iteration dates and the team's selected iterations", team)
+	if name == "" || strings.EqualFold(name, "@current") {
+		current, err := client.ListTeamIterations(ctx, team, "current")
+		if err != nil {
+			return api.TeamSettingsIteration{}, err
+		}
+		if len(current) == 0 {
+			return api.TeamSettingsIteration{}, noCurrent
+		}
+		return current[0], nil
+	}
+	all, err := client.ListTeamIterations(ctx, team, "")
+	if err != nil {
+		return api.TeamSettingsIteration{}, err
+	}
+	iteration, ok, err := findTeamIteration(all, name)
+	if err != nil {
+		return api.TeamSettingsIteration{}, err
+	}
+	if !ok {
+		if strings.HasPrefix(name, "@") {
+			return api.TeamSettingsIteration{}, noCurrent
+		}
+		names := make([]string, 0, len(all))
+		for _, candidate := range all {
+			names = append(names, candidate.Path)
+		}
+		return api.TeamSettingsIteration{}, errs.New("iteration_not_found", fmt.Sprintf("the team has no iteration %q", name), names)
+	}
+	return iteration, nil
+}
+
+// findTeamIteration picks name out of the team's iterations, which the
+// server lists in date order.
+func findTeamIteration(iterations []api.TeamSettingsIteration, name string) (api.TeamSettingsIteration, bool, error) {
+	offset := 0
+	switch strings.ToLower(name) {
+	case "@previous":
+		offset = -1
+	case "@next":
+		offset = 1
+	}
+	if offset != 0 {
+		for index, iteration := range iterations {
+			if !strings.EqualFold(iteration.Attributes.TimeFrame, "current") {
+				continue
+			}
+			if index+offset < 0 || index+offset >= len(iterations) {
+				return api.TeamSettingsIteration{}, false, errs.New("iteration_not_found", "the team has no iteration "+name, nil)
+			}
+			return iterations[index+offset], true, nil
+		}
+		return api.TeamSettingsIteration{}, false, nil
+	}
+	wanted := strings.ReplaceAll(strings.Trim(name, `/\`), "/", `\`)
+	for _, iteration := range iterations {
+		path := iteration.Path
+		relative := path[strings.Index(path, `\`)+1:]
+		if strings.EqualFold(iteration.Name, wanted) || strings.EqualFold(path, wanted) || strings.EqualFold(relative, wanted) {
+			return iteration, true, nil
+		}
+	}
+	return api.TeamSettingsIteration{}, false, nil
+}
+
+// sprintCalendar counts the working days of iteration: the team's working
+// days between its dates, less the team's days off. The working weekdays and
+// days off are returned for per-member calculations.
+func sprintCalendar(ctx context.Context, client *api.Client, team string, iteration api.TeamSettingsIteration) (sprintInfo, map[time.Weekday]bool, []api.DateRange, error) {
+	info := sprintInfo{
+		ID:         iteration.ID,
+		Name:       iteration.Name,
+		Path:       iteration.Path,
+		StartDate:  iteration.Attributes.StartDate,
+		FinishDate: iteration.Attributes.FinishDate,
+		TimeFrame:  iteration.Attributes.TimeFrame,
+		Team:       team,
+	}
+	settings, err := client.GetTeamSettings(ctx, team)
+	if err != nil {
+		return info, nil, nil, err
+	}
+	teamOff, err := client.GetIterationDaysOff(ctx, team, iteration.ID)
+	if err != nil {
+		return info, nil, nil, err
+	}
+	working := workingWeekdays(settings.WorkingDays)
+	start, startOK := sprintDate(info.StartDate)
+	finish, finishOK := sprintDate(info.FinishDate)
+	if startOK && finishOK {
+		info.WorkingDays = countWorkingDays(start, finish, working, teamOff.DaysOff)
+		info.WorkingDaysLeft = countWorkingDays(laterDate(start, sprintToday()), finish, working, teamOff.DaysOff)
+	}
+	return info, working, teamOff.DaysOff, nil
+}
+
+func buildCapacityReport(ctx context.Context, client *api.Client, team string, iteration api.TeamSettingsIteration) (capacityReport, error) {
+	info, working, teamOff, err := sprintCalendar(ctx, client, team, iteration)
+	if err != nil {
+		return capacityReport{}, err
+	}
+	capacities, err := client.GetIterationCapacities(ctx, team, iteration.ID)
+	if err != nil {
+		return capacityReport{}, err
+	}
+	assigned, unassigned, err := sprintAssignedWork(ctx, client, team, iteration.ID)
+	if err != nil {
+		return capacityReport{}, err
+	}
+	report := capacityReport{Iteration: info, Members: make([]capacityRow, 0, len(capacities)), UnassignedWork: unassigned}
+	start, startOK := sprintDate(info.StartDate)
+	finish, finishOK := sprintDate(info.FinishDate)
+	for _, member := range capacities {
+		row := capacityRow{
+			Member:       member.TeamMember.DisplayName,
+			UniqueName:   member.TeamMember.UniqueName,
+			Activities:   member.Activities,
+			AssignedWork: assigned[strings.ToLower(member.TeamMember.UniqueName)],
+		}
+		if row.Activities == nil {
+			row.Activities = []api.ActivityCapacity{}
+		}
+		for _, activity := range member.Activities {
+			row.CapacityPerDay += activity.CapacityPerDay
+		}
+		delete(assigned, strings.ToLower(member.TeamMember.UniqueName))
+		if startOK && finishOK {
+			daysOff := append(append([]api.DateRange{}, teamOff...), member.DaysOff...)
+			days := countWorkingDays(start, finish, working, daysOff)
+			row.DaysOff = countWorkingDays(start, finish, working, teamOff) - days
+			row.Capacity = row.CapacityPerDay * float64(days)
+			row.RemainingCapacity = row.CapacityPerDay * float64(countWorkingDays(laterDate(start, sprintToday()), finish, working, daysOff))
+		}
+		report.Capacity += row.Capacity
+		report.RemainingCapacity += row.RemainingCapacity
+		report.AssignedWork += row.AssignedWork
+		report.Members = append(report.Members, row)
+	}
+	// Work assigned to people without a capacity entry still counts.
+	for _, hours := range assigned {
+		report.AssignedWork += hours
+	}
+	sort.SliceStable(report.Members, func(i, j int) bool {
+		return strings.ToLower(report.Members[i].Member) < strings.ToLower(report.Members[j].Member)
+	})
+	return report, nil
+}
+
+// sprintAssignedWork sums the Remaining Work of the sprint's items per
+// assignee unique name (lower case), and of the unassigned ones.
+func sprintAssignedWork(ctx context.Context, client *api.Client, team, iterationID string) (map[string]float64, float64, error) {
+	items, err := client.GetIterationWorkItems(ctx, team, iterationID)
+	if err != nil {
+		return nil, 0, err
+	}
+	ids := collectIDs(api.WiqlResponse{WorkItems: sprintItemRefs(items.WorkItemRelations)})
+	assigned := map[string]float64{}
+	unassigned := 0.0
+	for i := 0; i < len(ids); i += maxBatchSize {
+		end := i + maxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch, err := client.GetWorkItemsBatch(ctx, ids[i:end], []string{"System.AssignedTo", "Microsoft.VSTS.Scheduling.RemainingWork"}, time.Time{})
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, wi := range batch {
+			remaining, _ := wi.Fields["Microsoft.VSTS.Scheduling.RemainingWork"].(float64)
+			if remaining == 0 {
+				continue
+			}
+			identity, _ := wi.Fields["System.AssignedTo"].(map[string]interface{})
+			uniqueName, _ := identity["uniqueName"].(string)
+			if uniqueName == "" {
+				unassigned += remaining
+				continue
+			}
+			assigned[strings.ToLower(uniqueName)] += remaining
+		}
+	}
+	return assigned, unassigned, nil
+}
+
+// sprintItemRefs lists every item of a sprint backlog once.
+func sprintItemRefs(relations []api.WorkItemLink) []api.WorkItemReference {
+	refs := []api.WorkItemReference{}
+	seen := map[int]bool{}
+	for _, relation := range relations {
+		if relation.Target.ID != 0 && !seen[relation.Target.ID] {
+			seen[relation.Target.ID] = true
+			refs = append(refs, relation.Target)
+		}
+	}
+	return refs
+}
+
+// workingWeekdays parses the team's working days; teams without any work
+// Monday to Friday.
+func workingWeekdays(days []string) map[time.Weekday]bool {
+	working := map[time.Weekday]bool{}
+	for _, day := range days {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if strings.EqualFold(weekday.String(), day) {
+				working[weekday] = true
+			}
+		}
+	}
+	if len(working) == 0 {
+		for weekday := time.Monday; weekday <= time.Friday; weekday++ {
+			working[weekday] = true
+		}
+	}
+	return working
+}
+
+// countWorkingDays counts the working days from start to finish, both
+// included, that fall in none of the daysOff ranges.
+func countWorkingDays(start, finish time.Time, working map[time.Weekday]bool, daysOff []api.DateRange) int {
+	count := 0
+	for day := start; !day.After(finish); day = day.AddDate(0, 0, 1) {
+		if !working[day.Weekday()] || dayOff(day, daysOff) {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+func dayOff(day time.Time, daysOff []api.DateRange) bool {
+	for _, off := range daysOff {
+		start, startOK := sprintDate(off.Start)
+		end, endOK := sprintDate(off.End)
+		if startOK && endOK && !day.Before(start) && !day.After(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// sprintDate reads the date part of an iteration or day-off timestamp,
+// which the server stores as midnight UTC.
+func sprintDate(value string) (time.Time, bool) {
+	if len(value) < len("2006-01-02") {
+		return time.Time{}, false
+	}
+	date, err := time.Parse("2006-01-02", value[:len("2006-01-02")])
+	return date, err == nil
+}
+
+func sprintToday() time.Time {
+	now := sprintNow()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func laterDate(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+func sprintHeadline(info sprintInfo) string {
+	headline := info.Name
+	if info.StartDate != "" {
+		headline += fmt.Sprintf(" (%s - %s)", shortDate(info.StartDate), shortDate(info.FinishDate))
+	}
+	if info.WorkingDays > 0 {
+		headline += fmt.Sprintf(", %d of %d working days left", info.WorkingDaysLeft, info.WorkingDays)
+	}
+	return headline
+}
+
+func formatHours(hours float64) string {
+	return strconv.FormatFloat(math.Round(hours*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"tfs-cli/internal/api"
+	"tfs-cli/internal/errs"
+	"tfs-cli/internal/output"
+)
+
+// Watch event kinds.
+const (
+	watchNewItem      = "new_item"
+	watchRemoved      = "removed"
+	watchFieldChanged = "field_changed"
+	watchNewComment   = "new_comment"
+)
+
+// watchEvent is one change seen by tfs watch, printed as a JSON line.
+type watchEvent struct {
+	Time      string        `json:"time"`
+	Event     string        `json:"event"`
+	ID        int           `json:"id"`
+	Rev       int           `json:"rev,omitempty"`
+	Type      string        `json:"type,omitempty"`
+	Title     string        `json:"title,omitempty"`
+	Field     string        `json:"field,omitempty"`
+	OldValue  interface{}   `json:"oldValue,omitempty"`
+	NewValue  interface{}   `json:"newValue,omitempty"`
+	ChangedBy string        `json:"changedBy,omitempty"`
+	Comment   *watchComment `json:"comment,omitempty"`
+}
+
+type watchComment struct {
+	Author string `json:"author"`
+	Text   string `json:"text"`
+	Date   string `json:"date,omitempty"`
+}
+
+// workItemWatcher keeps the last seen revision of the watched items and
+// turns differences into events. ids returns the items to watch on each
+// poll; the first poll only records them.
+type workItemWatcher struct {
+	ids      func(context.Context) ([]int, error)
+	fetch    func(context.Context, []int, []string) ([]api.WorkItem, error)
+	comments func(context.Context, int) ([]api.WorkItemComment, error)
+	fields   []string
+	now      func() time.Time
+	seen     map[int]api.WorkItem
+}
+
+// runWatchHook runs the --exec command for an event, with the event JSON on
+// stdin. Tests replace it.
+var runWatchHook = func(ctx context.Context, command string, event watchEvent, payload []byte, stderr io.Writer) error {
+	shell, flagArg := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flagArg = "cmd", "/C"
+	}
+	cmd := exec.CommandContext(ctx, shell, flagArg, command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = stderr
+	cmd.Stderr = stderr
+	cmd.Env = append(os.Environ(), "TFS_EVENT="+event.Event, "TFS_WORK_ITEM_ID="+strconv.Itoa(event.ID))
+	return cmd.Run()
+}
+
+func runWatch(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := globalFlags{}
+	addGlobalFlags(fs, &flags)
+	wiql := fs.String("wiql", "", "Watch the items matched by this WIQL query, including items that start or stop matching")
+	interval := fs.Duration("interval", time.Minute, "Time between polls, e.g. 30s or 5m")
+	fieldsCSV := fs.String("fields", "", "Comma-separated fields to report changes of (default: title, state, assignee, area, iteration, tags)")
+	hook := fs.String("exec", "", "Shell command to run for every event, with the event JSON on stdin")
+	idArgs, rest := splitPositionals(args, watchValueFlags())
+	if err := fs.Parse(rest); err != nil {
+		return 1
+	}
+	if (len(idArgs) == 0) == (strings.TrimSpace(*wiql) == "") {
+		output.WriteError(stderr, errs.New("invalid_args", "give either work item ids or --wiql", nil), flags.json)
+		return 1
+	}
+	if *interval < time.Second {
+		output.WriteError(stderr, errs.New("invalid_args", "--interval must be at least 1s", interval.String()), flags.json)
+		return 1
+	}
+	columns, err := parseColumns(*fieldsCSV)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	ids, err := parseWorkItemIDs(idArgs)
+	if err != nil {
+		output.WriteError(stderr, err, flags.json)
+		return 1
+	}
+	ctx, client, ok := queryClient(flags, stdout, stderr)
+	if !ok {
+		return 1
+	}
+
+	watcher := &workItemWatcher{
+		ids: func(context.Context) ([]int, error) { return ids, nil },
+		fetch: func(reqCtx context.Context, ids []int, fields []string) ([]api.WorkItem, error) {
+			return client.GetWorkItemsBatch(reqCtx, ids, fields, time.Time{})
+		},
+		comments: func(reqCtx context.Context, id int) ([]api.WorkItemComment, error) {
+			return client.GetWorkItemComments(reqCtx, id, 0)
+		},
+		fields: columnFields(columns),
+		now:    time.Now,
+	}
+	if len(watcher.fields) == 0 {
+		watcher.fields = snapshotDefaultFields
+	}
+	if strings.TrimSpace(*wiql) != "" {
+		watcher.ids = func(reqCtx context.Context) ([]int, error) {
+			resp, err := client.Wiql(reqCtx, *wiql, 0, time.Time{})
+			if err != nil {
+				return nil, err
+			}
+			return collectIDs(resp), nil
+		}
+	}
+
+	reqCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	encoder := json.NewEncoder(stdout)
+	for first := true; ; first = false {
+		events, err := watcher.poll(reqCtx)
+		if reqCtx.Err() != nil {
+			return 0
+		}
+		if err != nil {
+			output.WriteError(stderr, err, ctx.jsonMode)
+			if first {
+				return 1
+			}
+		}
+		for _, event := range events {
+			payload, err := json.Marshal(event)
+			if err != nil {
+				output.WriteError(stderr, err, ctx.jsonMode)
+				continue
+			}
+			if err := encoder.Encode(json.RawMessage(payload)); err != nil {
+				return 1
+			}
+			if strings.TrimSpace(*hook) != "" {
+				if err := runWatchHook(reqCtx, *hook, event, payload, stderr); err != nil && reqCtx.Err() == nil {
+					output.WriteError(stderr, errs.New("hook_failed", "--exec command failed: "+err.Error(), event.ID), ctx.jsonMode)
+				}
+			}
+		}
+		select {
+		case <-reqCtx.Done():
+			return 0
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// poll fetches the watched items and reports what changed since the last
+// poll. A changed System.Rev marks an item to compare; a higher
+// System.CommentCount fetches its new comments. When comments cannot be
+// fetched the other events are still returned with the error, and those
+// comments are tried again on the next poll.
+func (w *workItemWatcher) poll(ctx context.Context) ([]watchEvent, error) {
+	ids, err := w.ids(ctx)
+	if err != nil {
+		return nil, err
+	}
+	wanted := []string{"System.WorkItemType", "System.Title", "System.ChangedBy", "System.CommentCount"}
+	for _, field := range w.fields {
+		if !containsFold(wanted, field) {
+			wanted = append(wanted, field)
+		}
+	}
+	current := make(map[int]api.WorkItem, len(ids))
+	for i := 0; i < len(ids); i += maxBatchSize {
+		end := i + maxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch, err := w.fetch(ctx, ids[i:end], wanted)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range batch {
+			current[item.ID] = item
+		}
+	}
+	if w.seen == nil {
+		w.seen = current
+		return nil, nil
+	}
+
+	stamp := w.now().UTC().Format(time.RFC3339)
+	events := []watchEvent{}
+	var commentErr error
+	for _, id := range ids {
+		item, ok := current[id]
+		if !ok {
+			continue
+		}
+		base := watchEvent{Time: stamp, ID: id, Rev: item.Rev, Type: workItemType(item)}
+		base.Title, _ = item.Fields["System.Title"].(string)
+		previous, known := w.seen[id]
+		if !known {
+			event := base
+			event.Event = watchNewItem
+			events = append(events, event)
+			continue
+		}
+		if changedBy, ok := item.Fields["System.ChangedBy"].(map[string]interface{}); ok {
+			base.ChangedBy = identityDisplayName(changedBy)
+		}
+		if previous.Rev != item.Rev {
+			for _, field := range w.fields {
+				oldValue := currentFieldValue(previous.Fields, field)
+				newValue := currentFieldValue(item.Fields, field)
+				if formatHistoryValue(oldValue) == formatHistoryValue(newValue) {
+					continue
+				}
+				event := base
+				event.Event, event.Field, event.OldValue, event.NewValue = watchFieldChanged, field, oldValue, newValue
+				events = append(events, event)
+			}
+		}
+		added := commentCount(item) - commentCount(previous)
+		if added <= 0 {
+			continue
+		}
+		comments, err := w.comments(ctx, id)
+		if err != nil {
+			// Keep the old comment count so the next poll fetches them again.
+			current[id] = withCommentCount(item, commentCount(previous))
+			if commentErr == nil {
+				commentErr = err
+			}
+			continue
+		}
+		if len(comments) > added {
+			comments = comments[len(comments)-added:]
+		}
+		for _, comment := range comments {
+			event := base
+			event.Event = watchNewComment
+			event.Comment = &watchComment{Author: identityDisplayName(comment.RevisedBy), Text: comment.Text, Date: comment.RevisedDate}
+			events = append(events, event)
+		}
+	}
+	for _, id := range sortedWorkItemIDs(w.seen) {
+		if _, ok := current[id]; !ok {
+			previous := w.seen[id]
+			event := watchEvent{Time: stamp, Event: watchRemoved, ID: id, Rev: previous.Rev, Type: workItemType(previous)}
+			event.Title, _ = previous.Fields["System.Title"].(string)
+			events = append(events, event)
+		}
+	}
+	w.seen = current
+	return events, commentErr
+}
+
+func commentCount(wi api.WorkItem) int {
+	count, _ := wi.Fields["System.CommentCount"].(float64)
+	return int(count)
+}
+
+// withCommentCount returns a copy of wi with System.CommentCount set to count.
+func withCommentCount(wi api.WorkItem, count int) api.WorkItem {
+	fields := make(map[string]interface{}, len(wi.Fields))
+	for name, value := range wi.Fields {
+		fields[name] = value
+	}
+	fields["System.CommentCount"] = float64(count)
+	wi.Fields = fields
+	return wi
+}
+
+func sortedWorkItemIDs(items map[int]api.WorkItem) []int {
+	ids := make([]int, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func watchValueFlags() map[string]bool {
+	flags := wiqlValueFlags()
+	flags["wiql"] = true
+	flags["interval"] = true
+	flags["fields"] = true
+	flags["exec"] = true
+	return flags
+}
@@ -11,12 +11,22 @@ const (
 	envBaseURL = "TFS_BASE_URL"
 	envProject = "TFS_PROJECT"
 	envPAT     = "TFS_PAT"
+	envTeam    = "TFS_TEAM"
 )
 
 type Config struct {
 	BaseURL string `json:"baseUrl"`
 	Project string `json:"project"`
 	PAT     string `json:"pat"`
+	// Team is the default team of the sprint, backlog and board commands;
+	// empty means the project's default team.
+	Team string `json:"team,omitempty"`
+	// FieldAliases maps short names accepted by --set, --unset, and --append
+	// to field reference names, taking precedence over the built-in aliases.
+	FieldAliases map[string]string `json:"fieldAliases,omitempty"`
+	// ValueAliases maps, per field (reference name or alias), a value to the
+	// allowed value of the process, e.g. "Development" to a localized name.
+	ValueAliases map[string]map[string]string `json:"valueAliases,omitempty"`
 }
 
 func DefaultPath() (string, error) {
@@ -72,6 +82,7 @@ func FromEnv() Config {
 		BaseURL: os.Getenv(envBaseURL),
 		Project: os.Getenv(envProject),
 		PAT:     os.Getenv(envPAT),
+		Team:    os.Getenv(envTeam),
 	}
 }
 
@@ -85,6 +96,9 @@ func Merge(base Config, override Config) Config {
 	if override.PAT != "" {
 		base.PAT = override.PAT
 	}
+	if override.Team != "" {
+		base.Team = override.Team
+	}
 	return base
 }
 
@@ -92,6 +106,8 @@ func (c Config) Redacted() Config {
 	if c.PAT == "" {
 		return c
 	}
-	return Config{BaseURL: c.BaseURL, Project: c.Project, PAT: "***"}
+	redacted := c
+	redacted.PAT = "***"
+	return redacted
 }
 
@@ -0,0 +1,511 @@
+package jmes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Expression is a compiled query.
+type Expression struct {
+	text string
+	root node
+}
+
+// Compile parses expression.
+func Compile(expression string) (*Expression, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{expression: expression, tokens: tokens}
+	root, err := p.parseExpression(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.current().typ != tokEOF {
+		return nil, p.errorf(p.current(), "unexpected %s", tokenNames[p.current().typ])
+	}
+	return &Expression{text: expression, root: root}, nil
+}
+
+// CompileList parses a comma-separated list of expressions into a single
+// projection returning an object keyed by the text of each expression, the
+// way --columns picks fields: `rev, fields."System.Title"` yields
+// {"rev": ..., "fields.\"System.Title\"": ...}.
+func CompileList(list string) (*Expression, error) {
+	tokens, err := tokenize(list)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{expression: list, tokens: tokens}
+	keys := []string{}
+	values := []node{}
+	for {
+		start := p.current().pos
+		value, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, strings.TrimSpace(list[start:p.current().pos]))
+		values = append(values, value)
+		if p.current().typ == tokEOF {
+			break
+		}
+		if err := p.match(tokComma); err != nil {
+			return nil, err
+		}
+	}
+	return &Expression{text: list, root: multiSelectHashNode{keys: keys, values: values}}, nil
+}
+
+// String returns the source text of the expression.
+func (e *Expression) String() string {
+	return e.text
+}
+
+// Search evaluates the expression against the JSON form of data.
+func (e *Expression) Search(data interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(encoded, &generic); err != nil {
+		return nil, err
+	}
+	return e.root.eval(generic)
+}
+
+type node interface {
+	eval(value interface{}) (interface{}, error)
+}
+
+type currentNode struct{}
+
+func (currentNode) eval(value interface{}) (interface{}, error) {
+	return value, nil
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type fieldNode struct {
+	name string
+}
+
+func (n fieldNode) eval(value interface{}) (interface{}, error) {
+	if m, ok := value.(map[string]interface{}); ok {
+		return m[n.name], nil
+	}
+	return nil, nil
+}
+
+type subexpressionNode struct {
+	left, right node
+}
+
+func (n subexpressionNode) eval(value interface{}) (interface{}, error) {
+	left, err := n.left.eval(value)
+	if err != nil || left == nil {
+		return nil, err
+	}
+	return n.right.eval(left)
+}
+
+type indexNode struct {
+	index int
+}
+
+func (n indexNode) eval(value interface{}) (interface{}, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	index := n.index
+	if index < 0 {
+		index += len(list)
+	}
+	if index < 0 || index >= len(list) {
+		return nil, nil
+	}
+	return list[index], nil
+}
+
+type sliceNode struct {
+	start, stop, step *int
+}
+
+func (n sliceNode) eval(value interface{}) (interface{}, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	length := len(list)
+	step := 1
+	if n.step != nil {
+		step = *n.step
+	}
+	clamp := func(bound *int, fallback int) int {
+		if bound == nil {
+			return fallback
+		}
+		index := *bound
+		if index < 0 {
+			index += length
+			if index < 0 {
+				if step < 0 {
+					return -1
+				}
+				return 0
+			}
+		} else if index >= length {
+			if step < 0 {
+				return length - 1
+			}
+			return length
+		}
+		return index
+	}
+	result := []interface{}{}
+	if step > 0 {
+		for i := clamp(n.start, 0); i < clamp(n.stop, length); i += step {
+			result = append(result, list[i])
+		}
+	} else {
+		for i := clamp(n.start, length-1); i > clamp(n.stop, -1); i += step {
+			result = append(result, list[i])
+		}
+	}
+	return result, nil
+}
+
+type projectionNode struct {
+	left, right node
+}
+
+func (n projectionNode) eval(value interface{}) (interface{}, error) {
+	base, err := n.left.eval(value)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := base.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	return project(list, n.right)
+}
+
+type valueProjectionNode struct {
+	left, right node
+}
+
+func (n valueProjectionNode) eval(value interface{}) (interface{}, error) {
+	base, err := n.left.eval(value)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := base.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	return project(objectValues(m), n.right)
+}
+
+type filterNode struct {
+	left, right, condition node
+}
+
+func (n filterNode) eval(value interface{}) (interface{}, error) {
+	base, err := n.left.eval(value)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := base.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	matched := []interface{}{}
+	for _, item := range list {
+		keep, err := n.condition.eval(item)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(keep) {
+			matched = append(matched, item)
+		}
+	}
+	return project(matched, n.right)
+}
+
+func project(list []interface{}, right node) (interface{}, error) {
+	result := []interface{}{}
+	for _, item := range list {
+		value, err := right.eval(item)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			result = append(result, value)
+		}
+	}
+	return result, nil
+}
+
+type flattenNode struct {
+	child node
+}
+
+func (n flattenNode) eval(value interface{}) (interface{}, error) {
+	base, err := n.child.eval(value)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := base.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	result := []interface{}{}
+	for _, item := range list {
+		if inner, ok := item.([]interface{}); ok {
+			result = append(result, inner...)
+		} else {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+type pipeNode struct {
+	left, right node
+}
+
+func (n pipeNode) eval(value interface{}) (interface{}, error) {
+	left, err := n.left.eval(value)
+	if err != nil {
+		return nil, err
+	}
+	return n.right.eval(left)
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n orNode) eval(value interface{}) (interface{}, error) {
+	left, err := n.left.eval(value)
+	if err != nil || truthy(left) {
+		return left, err
+	}
+	return n.right.eval(value)
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n andNode) eval(value interface{}) (interface{}, error) {
+	left, err := n.left.eval(value)
+	if err != nil || !truthy(left) {
+		return left, err
+	}
+	return n.right.eval(value)
+}
+
+type notNode struct {
+	child node
+}
+
+func (n notNode) eval(value interface{}) (interface{}, error) {
+	child, err := n.child.eval(value)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(child), nil
+}
+
+type comparatorNode struct {
+	op          tokenType
+	left, right node
+}
+
+// eval compares with JMESPath semantics, except that strings are ordered
+// too so ISO dates can be filtered with < and >.
+func (n comparatorNode) eval(value interface{}) (interface{}, error) {
+	left, err := n.left.eval(value)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(value)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case tokEQ:
+		return reflect.DeepEqual(left, right), nil
+	case tokNE:
+		return !reflect.DeepEqual(left, right), nil
+	}
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, nil
+		}
+		cmp = compareFloats(l, r)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, nil
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return nil, nil
+	}
+	switch n.op {
+	case tokLT:
+		return cmp < 0, nil
+	case tokLTE:
+		return cmp <= 0, nil
+	case tokGT:
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+type multiSelectListNode struct {
+	items []node
+}
+
+func (n multiSelectListNode) eval(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	result := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		selected, err := item.eval(value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, selected)
+	}
+	return result, nil
+}
+
+type multiSelectHashNode struct {
+	keys   []string
+	values []node
+}
+
+func (n multiSelectHashNode) eval(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	result := make(map[string]interface{}, len(n.keys))
+	for index, key := range n.keys {
+		selected, err := n.values[index].eval(value)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = selected
+	}
+	return result, nil
+}
+
+type exprefNode struct {
+	child node
+}
+
+func (n exprefNode) eval(interface{}) (interface{}, error) {
+	return n, nil
+}
+
+type functionNode struct {
+	name string
+	args []node
+}
+
+func (n functionNode) eval(value interface{}) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		evaluated, err := arg.eval(value)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, evaluated)
+	}
+	fn := functions[n.name]
+	if err := fn.check(n.name, args); err != nil {
+		return nil, err
+	}
+	return fn.call(args)
+}
+
+func truthy(value interface{}) bool {
+	switch val := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case string:
+		return val != ""
+	case []interface{}:
+		return len(val) > 0
+	case map[string]interface{}:
+		return len(val) > 0
+	}
+	return true
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// objectValues returns the values of m ordered by key, so object
+// projections are deterministic.
+func objectValues(m map[string]interface{}) []interface{} {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, m[key])
+	}
+	return values
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case exprefNode:
+		return "expref"
+	}
+	return fmt.Sprintf("%T", value)
+}
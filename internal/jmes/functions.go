@@ -0,0 +1,380 @@
+package jmes
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RuntimeError reports a function called with the wrong number or types of
+// arguments.
+type RuntimeError struct {
+	Msg string
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Msg
+}
+
+type function struct {
+	// args lists the accepted types of each argument; "any" accepts
+	// everything. A variadic function repeats its last argument type.
+	args     [][]string
+	variadic bool
+	call     func(args []interface{}) (interface{}, error)
+}
+
+var functions map[string]function
+
+func init() {
+	functions = map[string]function{
+		"abs":         {args: [][]string{{"number"}}, call: numberFunc(math.Abs)},
+		"ceil":        {args: [][]string{{"number"}}, call: numberFunc(math.Ceil)},
+		"floor":       {args: [][]string{{"number"}}, call: numberFunc(math.Floor)},
+		"avg":         {args: [][]string{{"array"}}, call: fnAvg},
+		"sum":         {args: [][]string{{"array"}}, call: fnSum},
+		"contains":    {args: [][]string{{"array", "string"}, {"any"}}, call: fnContains},
+		"starts_with": {args: [][]string{{"string"}, {"string"}}, call: fnStartsWith},
+		"ends_with":   {args: [][]string{{"string"}, {"string"}}, call: fnEndsWith},
+		"join":        {args: [][]string{{"string"}, {"array"}}, call: fnJoin},
+		"keys":        {args: [][]string{{"object"}}, call: fnKeys},
+		"values":      {args: [][]string{{"object"}}, call: fnValues},
+		"length":      {args: [][]string{{"string", "array", "object"}}, call: fnLength},
+		"map":         {args: [][]string{{"expref"}, {"array"}}, call: fnMap},
+		"max":         {args: [][]string{{"array"}}, call: extremeFunc(1)},
+		"min":         {args: [][]string{{"array"}}, call: extremeFunc(-1)},
+		"max_by":      {args: [][]string{{"array"}, {"expref"}}, call: extremeByFunc(1)},
+		"min_by":      {args: [][]string{{"array"}, {"expref"}}, call: extremeByFunc(-1)},
+		"merge":       {args: [][]string{{"object"}}, variadic: true, call: fnMerge},
+		"not_null":    {args: [][]string{{"any"}}, variadic: true, call: fnNotNull},
+		"reverse":     {args: [][]string{{"string", "array"}}, call: fnReverse},
+		"sort":        {args: [][]string{{"array"}}, call: fnSort},
+		"sort_by":     {args: [][]string{{"array"}, {"expref"}}, call: fnSortBy},
+		"to_array":    {args: [][]string{{"any"}}, call: fnToArray},
+		"to_number":   {args: [][]string{{"any"}}, call: fnToNumber},
+		"to_string":   {args: [][]string{{"any"}}, call: fnToString},
+		"type":        {args: [][]string{{"any"}}, call: func(args []interface{}) (interface{}, error) { return typeName(args[0]), nil }},
+	}
+}
+
+func (f function) check(name string, args []interface{}) error {
+	if len(args) < len(f.args) || (!f.variadic && len(args) > len(f.args)) {
+		return &RuntimeError{Msg: fmt.Sprintf("%s() expects %d argument(s), got %d", name, len(f.args), len(args))}
+	}
+	for index, arg := range args {
+		allowed := f.args[len(f.args)-1]
+		if index < len(f.args) {
+			allowed = f.args[index]
+		}
+		if !typeAllowed(arg, allowed) {
+			return &RuntimeError{Msg: fmt.Sprintf("%s() argument %d must be %s, got %s", name, index+1, strings.Join(allowed, " or "), typeName(arg))}
+		}
+	}
+	return nil
+}
+
+func typeAllowed(value interface{}, allowed []string) bool {
+	actual := typeName(value)
+	for _, name := range allowed {
+		if name == "any" && actual != "expref" {
+			return true
+		}
+		if name == actual {
+			return true
+		}
+	}
+	return false
+}
+
+func numberFunc(fn func(float64) float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		return fn(args[0].(float64)), nil
+	}
+}
+
+func numbers(name string, list []interface{}) ([]float64, error) {
+	result := make([]float64, 0, len(list))
+	for _, item := range list {
+		n, ok := item.(float64)
+		if !ok {
+			return nil, &RuntimeError{Msg: fmt.Sprintf("%s() expects an array of numbers, found %s", name, typeName(item))}
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+func fnSum(args []interface{}) (interface{}, error) {
+	values, err := numbers("sum", args[0].([]interface{}))
+	if err != nil {
+		return nil, err
+	}
+	total := 0.0
+	for _, n := range values {
+		total += n
+	}
+	return total, nil
+}
+
+func fnAvg(args []interface{}) (interface{}, error) {
+	values, err := numbers("avg", args[0].([]interface{}))
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	total := 0.0
+	for _, n := range values {
+		total += n
+	}
+	return total / float64(len(values)), nil
+}
+
+func fnContains(args []interface{}) (interface{}, error) {
+	switch subject := args[0].(type) {
+	case string:
+		search, ok := args[1].(string)
+		return ok && strings.Contains(subject, search), nil
+	case []interface{}:
+		for _, item := range subject {
+			if reflect.DeepEqual(item, args[1]) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func fnStartsWith(args []interface{}) (interface{}, error) {
+	return strings.HasPrefix(args[0].(string), args[1].(string)), nil
+}
+
+func fnEndsWith(args []interface{}) (interface{}, error) {
+	return strings.HasSuffix(args[0].(string), args[1].(string)), nil
+}
+
+func fnJoin(args []interface{}) (interface{}, error) {
+	list := args[1].([]interface{})
+	parts := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, &RuntimeError{Msg: fmt.Sprintf("join() expects an array of strings, found %s", typeName(item))}
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, args[0].(string)), nil
+}
+
+func fnKeys(args []interface{}) (interface{}, error) {
+	m := args[0].(map[string]interface{})
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, key)
+	}
+	return result, nil
+}
+
+func fnValues(args []interface{}) (interface{}, error) {
+	return objectValues(args[0].(map[string]interface{})), nil
+}
+
+func fnLength(args []interface{}) (interface{}, error) {
+	switch val := args[0].(type) {
+	case string:
+		return float64(len([]rune(val))), nil
+	case []interface{}:
+		return float64(len(val)), nil
+	case map[string]interface{}:
+		return float64(len(val)), nil
+	}
+	return nil, nil
+}
+
+func fnMap(args []interface{}) (interface{}, error) {
+	expr := args[0].(exprefNode)
+	list := args[1].([]interface{})
+	result := make([]interface{}, 0, len(list))
+	for _, item := range list {
+		value, err := expr.child.eval(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// sortKeys validates that every key is a number, or every key is a string.
+func sortKeys(name string, keys []interface{}) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	first := typeName(keys[0])
+	if first != "number" && first != "string" {
+		return &RuntimeError{Msg: fmt.Sprintf("%s() can only order numbers or strings, found %s", name, first)}
+	}
+	for _, key := range keys {
+		if typeName(key) != first {
+			return &RuntimeError{Msg: fmt.Sprintf("%s() cannot order mixed %s and %s values", name, first, typeName(key))}
+		}
+	}
+	return nil
+}
+
+func compareKeys(a, b interface{}) int {
+	if x, ok := a.(float64); ok {
+		return compareFloats(x, b.(float64))
+	}
+	return strings.Compare(a.(string), b.(string))
+}
+
+func extremeFunc(sign int) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		list := args[0].([]interface{})
+		if err := sortKeys("max/min", list); err != nil || len(list) == 0 {
+			return nil, err
+		}
+		best := list[0]
+		for _, item := range list[1:] {
+			if compareKeys(item, best)*sign > 0 {
+				best = item
+			}
+		}
+		return best, nil
+	}
+}
+
+func extremeByFunc(sign int) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		list := args[0].([]interface{})
+		keys, err := evalKeys(args[1].(exprefNode), list)
+		if err != nil {
+			return nil, err
+		}
+		if err := sortKeys("max_by/min_by", keys); err != nil || len(list) == 0 {
+			return nil, err
+		}
+		best := 0
+		for index := 1; index < len(list); index++ {
+			if compareKeys(keys[index], keys[best])*sign > 0 {
+				best = index
+			}
+		}
+		return list[best], nil
+	}
+}
+
+func evalKeys(expr exprefNode, list []interface{}) ([]interface{}, error) {
+	keys := make([]interface{}, 0, len(list))
+	for _, item := range list {
+		key, err := expr.child.eval(item)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func fnMerge(args []interface{}) (interface{}, error) {
+	result := map[string]interface{}{}
+	for _, arg := range args {
+		for key, value := range arg.(map[string]interface{}) {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+func fnNotNull(args []interface{}) (interface{}, error) {
+	for _, arg := range args {
+		if arg != nil {
+			return arg, nil
+		}
+	}
+	return nil, nil
+}
+
+func fnReverse(args []interface{}) (interface{}, error) {
+	if s, ok := args[0].(string); ok {
+		runes := []rune(s)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes), nil
+	}
+	list := args[0].([]interface{})
+	result := make([]interface{}, len(list))
+	for index, item := range list {
+		result[len(list)-1-index] = item
+	}
+	return result, nil
+}
+
+func fnSort(args []interface{}) (interface{}, error) {
+	list := append([]interface{}{}, args[0].([]interface{})...)
+	if err := sortKeys("sort", list); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return compareKeys(list[i], list[j]) < 0 })
+	return list, nil
+}
+
+func fnSortBy(args []interface{}) (interface{}, error) {
+	list := args[0].([]interface{})
+	keys, err := evalKeys(args[1].(exprefNode), list)
+	if err != nil {
+		return nil, err
+	}
+	if err := sortKeys("sort_by", keys); err != nil {
+		return nil, err
+	}
+	order := make([]int, len(list))
+	for index := range order {
+		order[index] = index
+	}
+	sort.SliceStable(order, func(i, j int) bool { return compareKeys(keys[order[i]], keys[order[j]]) < 0 })
+	result := make([]interface{}, 0, len(list))
+	for _, index := range order {
+		result = append(result, list[index])
+	}
+	return result, nil
+}
+
+func fnToArray(args []interface{}) (interface{}, error) {
+	if list, ok := args[0].([]interface{}); ok {
+		return list, nil
+	}
+	return []interface{}{args[0]}, nil
+}
+
+func fnToNumber(args []interface{}) (interface{}, error) {
+	switch val := args[0].(type) {
+	case float64:
+		return val, nil
+	case string:
+		if n, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+			return n, nil
+		}
+	}
+	return nil, nil
+}
+
+func fnToString(args []interface{}) (interface{}, error) {
+	if s, ok := args[0].(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,117 @@
+package jmes
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const sample = `{
+  "count": 3,
+  "items": [
+    {"id": 1, "rev": 4, "fields": {"System.Title": "Login page", "System.State": "Active", "System.Tags": "ui; web"}, "relations": [{"rel": "Parent"}, {"rel": "Related"}]},
+    {"id": 2, "rev": 1, "fields": {"System.Title": "API", "System.State": "New"}},
+    {"id": 3, "rev": 9, "fields": {"System.Title": "Docs", "System.State": "Active", "System.ChangedDate": "2024-05-01T10:00:00Z"}, "relations": [{"rel": "Child"}]}
+  ]
+}`
+
+func search(t *testing.T, expression string) interface{} {
+	t.Helper()
+	var data interface{}
+	if err := json.Unmarshal([]byte(sample), &data); err != nil {
+		t.Fatal(err)
+	}
+	expr, err := Compile(expression)
+	if err != nil {
+		t.Fatalf("compile %q: %v", expression, err)
+	}
+	result, err := expr.Search(data)
+	if err != nil {
+		t.Fatalf("search %q: %v", expression, err)
+	}
+	return result
+}
+
+func TestSearch(t *testing.T) {
+	cases := []struct {
+		expression string
+		want       string
+	}{
+		{`count`, `3`},
+		{`items[0].fields."System.Title"`, `"Login page"`},
+		{`items[-1].id`, `3`},
+		{`items[*].id`, `[1,2,3]`},
+		{`items[:2].id`, `[1,2]`},
+		{`items[::-1].id`, `[3,2,1]`},
+		{`items[?fields."System.State" == 'Active'].id`, `[1,3]`},
+		{`items[?rev > ` + "`3`" + `].id`, `[1,3]`},
+		{`items[?rev >= 4 && !contains(fields."System.Title", 'Docs')].id`, `[1]`},
+		{`items[?fields."System.ChangedDate" > '2024-01-01'].id`, `[3]`},
+		{`items[].relations[].rel`, `["Parent","Related","Child"]`},
+		{`items[*].relations[*].rel`, `[["Parent","Related"],["Child"]]`},
+		{`items[*].{id: id, title: fields."System.Title"}`, `[{"id":1,"title":"Login page"},{"id":2,"title":"API"},{"id":3,"title":"Docs"}]`},
+		{`items[*].[id, rev]`, `[[1,4],[2,1],[3,9]]`},
+		{`items[0].fields.*`, `["Active","ui; web","Login page"]`},
+		{`items | length(@)`, `3`},
+		{`sort_by(items, &rev)[*].id`, `[2,1,3]`},
+		{`max_by(items, &rev).id`, `3`},
+		{`sum(items[*].rev)`, `14`},
+		{`join(', ', items[*].fields."System.State")`, `"Active, New, Active"`},
+		{`items[?starts_with(fields."System.Title", 'Lo')] | [0].id`, `1`},
+		{`missing || 'fallback'`, `"fallback"`},
+		{`items[5].id`, `null`},
+		{`keys(items[1].fields)`, `["System.State","System.Title"]`},
+	}
+	for _, tc := range cases {
+		got, err := json.Marshal(search(t, tc.expression))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("%s = %s, want %s", tc.expression, got, tc.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, expression := range []string{`items[`, `items[?id ==]`, `"a"(1)`, `nosuch(@)`, `{id}`, `a.`, `'open`, "`{bad`"} {
+		if _, err := Compile(expression); err == nil {
+			t.Errorf("expected syntax error for %q", expression)
+		} else if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("expected *SyntaxError for %q, got %T", expression, err)
+		}
+	}
+}
+
+func TestSearchReportsFunctionTypeErrors(t *testing.T) {
+	expr, err := Compile(`length(count)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := expr.Search(map[string]interface{}{"count": 3}); err == nil {
+		t.Fatal("expected a type error")
+	}
+}
+
+func TestCompileList(t *testing.T) {
+	expr, err := CompileList(`rev, fields."System.Title", relations[].rel`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := expr.Search(map[string]interface{}{
+		"rev":       2,
+		"fields":    map[string]interface{}{"System.Title": "T"},
+		"relations": []interface{}{map[string]interface{}{"rel": "Parent"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"rev":                   2.0,
+		`fields."System.Title"`: "T",
+		"relations[].rel":       []interface{}{"Parent"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected projection: %#v", got)
+	}
+}
@@ -0,0 +1,593 @@
+// Package jmes implements the subset of JMESPath used by --query: field and
+// quoted-field access, indexes and slices, list/object/filter projections,
+// flattening, pipes, multi-select lists and hashes, comparisons, boolean
+// operators and the common built-in functions.
+package jmes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokIdentifier
+	tokQuotedIdentifier
+	tokRawString
+	tokLiteral
+	tokNumber
+	tokDot
+	tokStar
+	tokFlatten
+	tokFilter
+	tokLBracket
+	tokRBracket
+	tokLBrace
+	tokRBrace
+	tokLParen
+	tokRParen
+	tokComma
+	tokColon
+	tokPipe
+	tokOr
+	tokAnd
+	tokNot
+	tokCurrent
+	tokExpref
+	tokEQ
+	tokNE
+	tokLT
+	tokLTE
+	tokGT
+	tokGTE
+)
+
+var tokenNames = map[tokenType]string{
+	tokEOF: "end of expression", tokIdentifier: "identifier", tokQuotedIdentifier: "quoted identifier",
+	tokRawString: "raw string", tokLiteral: "literal", tokNumber: "number", tokDot: "'.'", tokStar: "'*'",
+	tokFlatten: "'[]'", tokFilter: "'[?'", tokLBracket: "'['", tokRBracket: "']'", tokLBrace: "'{'",
+	tokRBrace: "'}'", tokLParen: "'('", tokRParen: "')'", tokComma: "','", tokColon: "':'", tokPipe: "'|'",
+	tokOr: "'||'", tokAnd: "'&&'", tokNot: "'!'", tokCurrent: "'@'", tokExpref: "'&'", tokEQ: "'=='",
+	tokNE: "'!='", tokLT: "'<'", tokLTE: "'<='", tokGT: "'>'", tokGTE: "'>='",
+}
+
+// bindingPower drives the Pratt parser; tokens below projectionStop end the
+// right-hand side of a projection.
+var bindingPower = map[tokenType]int{
+	tokPipe: 1, tokOr: 2, tokAnd: 3,
+	tokEQ: 5, tokNE: 5, tokLT: 5, tokLTE: 5, tokGT: 5, tokGTE: 5,
+	tokFlatten: 9, tokStar: 20, tokFilter: 21, tokDot: 40, tokNot: 45,
+	tokLBrace: 50, tokLBracket: 55, tokLParen: 60,
+}
+
+const projectionStop = 10
+
+type token struct {
+	typ   tokenType
+	text  string
+	value interface{}
+	pos   int
+}
+
+// SyntaxError reports an invalid expression and the byte offset of the
+// problem.
+type SyntaxError struct {
+	Expression string
+	Offset     int
+	Msg        string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Offset)
+}
+
+func tokenize(expression string) ([]token, error) {
+	tokens := []token{}
+	syntaxErr := func(pos int, msg string) error {
+		return &SyntaxError{Expression: expression, Offset: pos, Msg: msg}
+	}
+	for pos := 0; pos < len(expression); {
+		ch := expression[pos]
+		start := pos
+		emit := func(typ tokenType, width int) {
+			tokens = append(tokens, token{typ: typ, text: expression[start : start+width], pos: start})
+			pos += width
+		}
+		next := byte(0)
+		if pos+1 < len(expression) {
+			next = expression[pos+1]
+		}
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			pos++
+		case isIdentStart(ch):
+			end := pos + 1
+			for end < len(expression) && isIdentPart(expression[end]) {
+				end++
+			}
+			tokens = append(tokens, token{typ: tokIdentifier, text: expression[pos:end], value: expression[pos:end], pos: pos})
+			pos = end
+		case ch == '-' || (ch >= '0' && ch <= '9'):
+			end := pos + 1
+			for end < len(expression) && expression[end] >= '0' && expression[end] <= '9' {
+				end++
+			}
+			if ch == '-' && end == pos+1 {
+				return nil, syntaxErr(pos, "expected a digit after '-'")
+			}
+			n, err := strconv.Atoi(expression[pos:end])
+			if err != nil {
+				return nil, syntaxErr(pos, "invalid number")
+			}
+			tokens = append(tokens, token{typ: tokNumber, text: expression[pos:end], value: n, pos: pos})
+			pos = end
+		case ch == '"':
+			end, err := scanDelimited(expression, pos, '"')
+			if err != nil {
+				return nil, syntaxErr(pos, err.Error())
+			}
+			var name string
+			if err := json.Unmarshal([]byte(expression[pos:end]), &name); err != nil {
+				return nil, syntaxErr(pos, "invalid quoted identifier")
+			}
+			tokens = append(tokens, token{typ: tokQuotedIdentifier, text: expression[pos:end], value: name, pos: pos})
+			pos = end
+		case ch == '\'':
+			end, err := scanDelimited(expression, pos, '\'')
+			if err != nil {
+				return nil, syntaxErr(pos, err.Error())
+			}
+			raw := strings.ReplaceAll(expression[pos+1:end-1], `\'`, `'`)
+			tokens = append(tokens, token{typ: tokRawString, text: expression[pos:end], value: raw, pos: pos})
+			pos = end
+		case ch == '`':
+			end, err := scanDelimited(expression, pos, '`')
+			if err != nil {
+				return nil, syntaxErr(pos, err.Error())
+			}
+			body := strings.ReplaceAll(expression[pos+1:end-1], "\\`", "`")
+			var value interface{}
+			if err := json.Unmarshal([]byte(body), &value); err != nil {
+				return nil, syntaxErr(pos, "invalid JSON literal")
+			}
+			tokens = append(tokens, token{typ: tokLiteral, text: expression[pos:end], value: value, pos: pos})
+			pos = end
+		case ch == '[' && next == '?':
+			emit(tokFilter, 2)
+		case ch == '[' && next == ']':
+			emit(tokFlatten, 2)
+		case ch == '[':
+			emit(tokLBracket, 1)
+		case ch == ']':
+			emit(tokRBracket, 1)
+		case ch == '{':
+			emit(tokLBrace, 1)
+		case ch == '}':
+			emit(tokRBrace, 1)
+		case ch == '(':
+			emit(tokLParen, 1)
+		case ch == ')':
+			emit(tokRParen, 1)
+		case ch == '.':
+			emit(tokDot, 1)
+		case ch == '*':
+			emit(tokStar, 1)
+		case ch == ',':
+			emit(tokComma, 1)
+		case ch == ':':
+			emit(tokColon, 1)
+		case ch == '@':
+			emit(tokCurrent, 1)
+		case ch == '|' && next == '|':
+			emit(tokOr, 2)
+		case ch == '|':
+			emit(tokPipe, 1)
+		case ch == '&' && next == '&':
+			emit(tokAnd, 2)
+		case ch == '&':
+			emit(tokExpref, 1)
+		case ch == '!' && next == '=':
+			emit(tokNE, 2)
+		case ch == '!':
+			emit(tokNot, 1)
+		case ch == '=' && next == '=':
+			emit(tokEQ, 2)
+		case ch == '<' && next == '=':
+			emit(tokLTE, 2)
+		case ch == '<':
+			emit(tokLT, 1)
+		case ch == '>' && next == '=':
+			emit(tokGTE, 2)
+		case ch == '>':
+			emit(tokGT, 1)
+		default:
+			return nil, syntaxErr(pos, fmt.Sprintf("unexpected character %q", ch))
+		}
+	}
+	tokens = append(tokens, token{typ: tokEOF, pos: len(expression)})
+	return tokens, nil
+}
+
+// scanDelimited returns the offset just past the closing delimiter of the
+// string starting at pos, honouring backslash escapes.
+func scanDelimited(expression string, pos int, delim byte) (int, error) {
+	for i := pos + 1; i < len(expression); i++ {
+		switch expression[i] {
+		case '\\':
+			i++
+		case delim:
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated %c", delim)
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isIdentPart(ch byte) bool {
+	return isIdentStart(ch) || (ch >= '0' && ch <= '9')
+}
+
+type parser struct {
+	expression string
+	tokens     []token
+	index      int
+}
+
+func (p *parser) current() token {
+	return p.tokens[p.index]
+}
+
+func (p *parser) lookahead(n int) token {
+	if p.index+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.index+n]
+}
+
+func (p *parser) advance() {
+	if p.index < len(p.tokens)-1 {
+		p.index++
+	}
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return &SyntaxError{Expression: p.expression, Offset: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) match(typ tokenType) error {
+	if p.current().typ != typ {
+		return p.errorf(p.current(), "expected %s, found %s", tokenNames[typ], tokenNames[p.current().typ])
+	}
+	p.advance()
+	return nil
+}
+
+func (p *parser) parseExpression(bp int) (node, error) {
+	tok := p.current()
+	p.advance()
+	left, err := p.nud(tok)
+	if err != nil {
+		return nil, err
+	}
+	for bp < bindingPower[p.current().typ] {
+		tok := p.current()
+		p.advance()
+		left, err = p.led(tok, left)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) nud(tok token) (node, error) {
+	switch tok.typ {
+	case tokLiteral, tokRawString:
+		return literalNode{value: tok.value}, nil
+	case tokNumber:
+		// Bare numbers are accepted as literals so filters can be written
+		// as [?rev > `3`] or [?rev > 3].
+		return literalNode{value: float64(tok.value.(int))}, nil
+	case tokIdentifier:
+		return fieldNode{name: tok.value.(string)}, nil
+	case tokQuotedIdentifier:
+		if p.current().typ == tokLParen {
+			return nil, p.errorf(p.current(), "quoted identifiers cannot be used as function names")
+		}
+		return fieldNode{name: tok.value.(string)}, nil
+	case tokStar:
+		right, err := p.parseProjectionRHS(bindingPower[tokStar])
+		if err != nil {
+			return nil, err
+		}
+		return valueProjectionNode{left: currentNode{}, right: right}, nil
+	case tokFilter:
+		return p.led(tok, currentNode{})
+	case tokLBrace:
+		return p.parseMultiSelectHash()
+	case tokLParen:
+		inner, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.match(tokRParen)
+	case tokFlatten:
+		right, err := p.parseProjectionRHS(bindingPower[tokFlatten])
+		if err != nil {
+			return nil, err
+		}
+		return projectionNode{left: flattenNode{child: currentNode{}}, right: right}, nil
+	case tokNot:
+		child, err := p.parseExpression(bindingPower[tokNot])
+		if err != nil {
+			return nil, err
+		}
+		return notNode{child: child}, nil
+	case tokLBracket:
+		switch {
+		case p.current().typ == tokNumber || p.current().typ == tokColon:
+			right, err := p.parseIndexExpression()
+			if err != nil {
+				return nil, err
+			}
+			return p.projectIfSlice(currentNode{}, right)
+		case p.current().typ == tokStar && p.lookahead(1).typ == tokRBracket:
+			p.advance()
+			p.advance()
+			right, err := p.parseProjectionRHS(bindingPower[tokStar])
+			if err != nil {
+				return nil, err
+			}
+			return projectionNode{left: currentNode{}, right: right}, nil
+		}
+		return p.parseMultiSelectList()
+	case tokCurrent:
+		return currentNode{}, nil
+	case tokExpref:
+		child, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		return exprefNode{child: child}, nil
+	}
+	return nil, p.errorf(tok, "unexpected %s", tokenNames[tok.typ])
+}
+
+func (p *parser) led(tok token, left node) (node, error) {
+	switch tok.typ {
+	case tokDot:
+		if p.current().typ == tokStar {
+			p.advance()
+			right, err := p.parseProjectionRHS(bindingPower[tokDot])
+			if err != nil {
+				return nil, err
+			}
+			return valueProjectionNode{left: left, right: right}, nil
+		}
+		right, err := p.parseDotRHS(bindingPower[tokDot])
+		if err != nil {
+			return nil, err
+		}
+		return subexpressionNode{left: left, right: right}, nil
+	case tokPipe:
+		right, err := p.parseExpression(bindingPower[tokPipe])
+		if err != nil {
+			return nil, err
+		}
+		return pipeNode{left: left, right: right}, nil
+	case tokOr:
+		right, err := p.parseExpression(bindingPower[tokOr])
+		if err != nil {
+			return nil, err
+		}
+		return orNode{left: left, right: right}, nil
+	case tokAnd:
+		right, err := p.parseExpression(bindingPower[tokAnd])
+		if err != nil {
+			return nil, err
+		}
+		return andNode{left: left, right: right}, nil
+	case tokLParen:
+		field, ok := left.(fieldNode)
+		if !ok {
+			return nil, p.errorf(tok, "function name must be an identifier")
+		}
+		args := []node{}
+		for p.current().typ != tokRParen {
+			arg, err := p.parseExpression(0)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.current().typ == tokComma {
+				p.advance()
+			} else if p.current().typ != tokRParen {
+				return nil, p.errorf(p.current(), "expected ',' or ')' in arguments of %s", field.name)
+			}
+		}
+		p.advance()
+		if _, ok := functions[field.name]; !ok {
+			return nil, p.errorf(tok, "unknown function %s()", field.name)
+		}
+		return functionNode{name: field.name, args: args}, nil
+	case tokFilter:
+		condition, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.match(tokRBracket); err != nil {
+			return nil, err
+		}
+		var right node = currentNode{}
+		if p.current().typ != tokFlatten {
+			right, err = p.parseProjectionRHS(bindingPower[tokFilter])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return filterNode{left: left, right: right, condition: condition}, nil
+	case tokEQ, tokNE, tokLT, tokLTE, tokGT, tokGTE:
+		right, err := p.parseExpression(bindingPower[tok.typ])
+		if err != nil {
+			return nil, err
+		}
+		return comparatorNode{op: tok.typ, left: left, right: right}, nil
+	case tokFlatten:
+		right, err := p.parseProjectionRHS(bindingPower[tokFlatten])
+		if err != nil {
+			return nil, err
+		}
+		return projectionNode{left: flattenNode{child: left}, right: right}, nil
+	case tokLBracket:
+		if p.current().typ == tokNumber || p.current().typ == tokColon {
+			right, err := p.parseIndexExpression()
+			if err != nil {
+				return nil, err
+			}
+			return p.projectIfSlice(left, right)
+		}
+		if err := p.match(tokStar); err != nil {
+			return nil, err
+		}
+		if err := p.match(tokRBracket); err != nil {
+			return nil, err
+		}
+		right, err := p.parseProjectionRHS(bindingPower[tokStar])
+		if err != nil {
+			return nil, err
+		}
+		return projectionNode{left: left, right: right}, nil
+	}
+	return nil, p.errorf(tok, "unexpected %s", tokenNames[tok.typ])
+}
+
+func (p *parser) parseIndexExpression() (node, error) {
+	if p.current().typ == tokColon || p.lookahead(1).typ == tokColon {
+		return p.parseSliceExpression()
+	}
+	tok := p.current()
+	if err := p.match(tokNumber); err != nil {
+		return nil, err
+	}
+	if err := p.match(tokRBracket); err != nil {
+		return nil, err
+	}
+	return indexNode{index: tok.value.(int)}, nil
+}
+
+func (p *parser) parseSliceExpression() (node, error) {
+	var parts [3]*int
+	part := 0
+	for p.current().typ != tokRBracket {
+		switch p.current().typ {
+		case tokColon:
+			part++
+			if part > 2 {
+				return nil, p.errorf(p.current(), "too many colons in slice")
+			}
+		case tokNumber:
+			value := p.current().value.(int)
+			parts[part] = &value
+		default:
+			return nil, p.errorf(p.current(), "unexpected %s in slice", tokenNames[p.current().typ])
+		}
+		p.advance()
+	}
+	p.advance()
+	if parts[2] != nil && *parts[2] == 0 {
+		return nil, p.errorf(p.current(), "slice step cannot be 0")
+	}
+	return sliceNode{start: parts[0], stop: parts[1], step: parts[2]}, nil
+}
+
+func (p *parser) projectIfSlice(left, right node) (node, error) {
+	indexed := subexpressionNode{left: left, right: right}
+	if _, ok := right.(sliceNode); ok {
+		rest, err := p.parseProjectionRHS(bindingPower[tokStar])
+		if err != nil {
+			return nil, err
+		}
+		return projectionNode{left: indexed, right: rest}, nil
+	}
+	return indexed, nil
+}
+
+func (p *parser) parseProjectionRHS(bp int) (node, error) {
+	switch {
+	case bindingPower[p.current().typ] < projectionStop:
+		return currentNode{}, nil
+	case p.current().typ == tokLBracket, p.current().typ == tokFilter:
+		return p.parseExpression(bp)
+	case p.current().typ == tokDot:
+		p.advance()
+		return p.parseDotRHS(bp)
+	}
+	return nil, p.errorf(p.current(), "unexpected %s after projection", tokenNames[p.current().typ])
+}
+
+func (p *parser) parseDotRHS(bp int) (node, error) {
+	switch p.current().typ {
+	case tokIdentifier, tokQuotedIdentifier, tokStar:
+		return p.parseExpression(bp)
+	case tokLBracket:
+		p.advance()
+		return p.parseMultiSelectList()
+	case tokLBrace:
+		p.advance()
+		return p.parseMultiSelectHash()
+	}
+	return nil, p.errorf(p.current(), "expected identifier, '[' or '{' after '.', found %s", tokenNames[p.current().typ])
+}
+
+func (p *parser) parseMultiSelectList() (node, error) {
+	items := []node{}
+	for {
+		item, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.current().typ == tokRBracket {
+			p.advance()
+			return multiSelectListNode{items: items}, nil
+		}
+		if err := p.match(tokComma); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseMultiSelectHash() (node, error) {
+	keys := []string{}
+	values := []node{}
+	for {
+		key := p.current()
+		if key.typ != tokIdentifier && key.typ != tokQuotedIdentifier {
+			return nil, p.errorf(key, "expected a key name, found %s", tokenNames[key.typ])
+		}
+		p.advance()
+		if err := p.match(tokColon); err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key.value.(string))
+		values = append(values, value)
+		if p.current().typ == tokRBrace {
+			p.advance()
+			return multiSelectHashNode{keys: keys, values: values}, nil
+		}
+		if err := p.match(tokComma); err != nil {
+			return nil, err
+		}
+	}
+}
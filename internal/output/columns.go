@@ -0,0 +1,164 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const columnDateLayout = "2006-01-02 15:04"
+
+// Column is one field of a list. Key names the value in JSON rows and
+// Header labels the table column.
+type Column struct {
+	Key    string
+	Header string
+	Field  string
+}
+
+// DefaultColumns are the columns PrintTable shows.
+func DefaultColumns() []Column {
+	return []Column{
+		{Key: "id", Header: "ID", Field: "System.Id"},
+		{Key: "type", Header: "TYPE", Field: "System.WorkItemType"},
+		{Key: "state", Header: "STATE", Field: "System.State"},
+		{Key: "title", Header: "TITLE", Field: "System.Title"},
+		{Key: "assignedTo", Header: "ASSIGNED", Field: "System.AssignedTo"},
+	}
+}
+
+// PrintColumns prints items as a table of the given columns.
+func PrintColumns(w io.Writer, items []WorkItem, columns []Column) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	printColumnHeader(tw, columns)
+	for _, item := range items {
+		printColumnRow(tw, item, columns, "", "")
+	}
+	_ = tw.Flush()
+}
+
+// ColumnRows returns one JSON object per item holding "id" and the value of
+// every column under its key.
+func ColumnRows(items []WorkItem, columns []Column) []map[string]interface{} {
+	rows := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, ColumnRow(item, columns))
+	}
+	return rows
+}
+
+// ColumnRow returns the JSON object ColumnRows builds for one item.
+func ColumnRow(item WorkItem, columns []Column) map[string]interface{} {
+	row := map[string]interface{}{"id": item.ID}
+	for _, column := range columns {
+		row[column.Key] = columnJSONValue(ColumnValue(item, column.Field))
+	}
+	return row
+}
+
+// ColumnValue returns the raw value of field for item.
+func ColumnValue(item WorkItem, field string) interface{} {
+	if strings.EqualFold(field, "System.Id") {
+		return item.ID
+	}
+	if value, ok := item.Fields[field]; ok {
+		return value
+	}
+	for name, value := range item.Fields {
+		if strings.EqualFold(name, field) {
+			return value
+		}
+	}
+	return nil
+}
+
+// FormatColumnValue renders a field value for a table cell: identities by
+// name, dates without seconds, and numbers without trailing zeros.
+func FormatColumnValue(value interface{}) string {
+	return strings.ReplaceAll(formatCell(value), "\n", " ")
+}
+
+// formatCell is FormatColumnValue without flattening line breaks.
+func formatCell(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return ""
+	case string:
+		if parsed, ok := parseColumnDate(val); ok {
+			return parsed.Format(columnDateLayout)
+		}
+		return val
+	case *string:
+		if val == nil {
+			return ""
+		}
+		return *val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case bool:
+		return strconv.FormatBool(val)
+	case map[string]interface{}:
+		if identity := identityString(val); identity != nil {
+			return *identity
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// columnJSONValue keeps numbers, booleans and dates as they are and reduces
+// identity objects to the same string the table shows.
+func columnJSONValue(value interface{}) interface{} {
+	if m, ok := value.(map[string]interface{}); ok {
+		if identity := identityString(m); identity != nil {
+			return *identity
+		}
+	}
+	return value
+}
+
+func parseColumnDate(value string) (time.Time, bool) {
+	if len(value) < len("2006-01-02T15:04") || value[4] != '-' || value[10] != 'T' {
+		return time.Time{}, false
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parsed, true
+}
+
+func printColumnHeader(w io.Writer, columns []Column) {
+	headers := make([]string, 0, len(columns))
+	for _, column := range columns {
+		headers = append(headers, column.Header)
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+}
+
+// printColumnRow prints one row; indent and suffix wrap the title column, or
+// the first column when there is no title.
+func printColumnRow(w io.Writer, item WorkItem, columns []Column, indent, suffix string) {
+	titleIndex := 0
+	for index, column := range columns {
+		if strings.EqualFold(column.Field, "System.Title") {
+			titleIndex = index
+			break
+		}
+	}
+	cells := make([]string, 0, len(columns))
+	for index, column := range columns {
+		cell := FormatColumnValue(ColumnValue(item, column.Field))
+		if index == titleIndex {
+			cell = indent + cell + suffix
+		}
+		cells = append(cells, cell)
+	}
+	fmt.Fprintln(w, strings.Join(cells, "\t"))
+}
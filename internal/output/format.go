@@ -0,0 +1,324 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"tfs-cli/internal/errs"
+)
+
+// Output formats accepted by --format.
+const (
+	FormatJSON     = "json"
+	FormatTable    = "table"
+	FormatCSV      = "csv"
+	FormatTSV      = "tsv"
+	FormatYAML     = "yaml"
+	FormatMarkdown = "markdown"
+	FormatTemplate = "template"
+)
+
+// Format is a parsed --format value. Template is set for FormatTemplate.
+type Format struct {
+	Kind     string
+	Template *template.Template
+}
+
+// Table is the tabular form of a payload, used by the csv, tsv and markdown
+// formats.
+type Table struct {
+	Headers []string
+	Rows    [][]string
+}
+
+// ParseFormat parses json|table|csv|tsv|yaml|markdown|template=<go-template>.
+func ParseFormat(value string) (Format, error) {
+	kind := strings.ToLower(strings.TrimSpace(value))
+	switch kind {
+	case FormatJSON, FormatTable, FormatCSV, FormatTSV, FormatYAML, FormatMarkdown:
+		return Format{Kind: kind}, nil
+	case "md":
+		return Format{Kind: FormatMarkdown}, nil
+	}
+	if strings.HasPrefix(strings.TrimSpace(value), FormatTemplate+"=") {
+		text := strings.TrimPrefix(strings.TrimSpace(value), FormatTemplate+"=")
+		tpl, err := template.New("format").Funcs(templateFuncs()).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return Format{}, errs.New("invalid_args", "invalid output template", err.Error())
+		}
+		return Format{Kind: FormatTemplate, Template: tpl}, nil
+	}
+	return Format{}, errs.New("invalid_args", "unknown output format "+value, []string{FormatJSON, FormatTable, FormatCSV, FormatTSV, FormatYAML, FormatMarkdown, FormatTemplate + "=<go-template>"})
+}
+
+// PrintFormatted prints payload in a machine-readable format. JSON, YAML and
+// templates use payload; csv, tsv and markdown use the rows returned by table.
+// FormatTable is rendered by the caller and is not handled here.
+func PrintFormatted(w io.Writer, format Format, payload interface{}, table func() Table) error {
+	switch format.Kind {
+	case FormatJSON, "":
+		return PrintJSON(w, payload)
+	case FormatYAML:
+		return PrintYAML(w, payload)
+	case FormatTemplate:
+		return PrintTemplate(w, format.Template, payload)
+	case FormatCSV:
+		return PrintCSV(w, table())
+	case FormatTSV:
+		return PrintTSV(w, table())
+	case FormatMarkdown:
+		return PrintMarkdown(w, table())
+	}
+	return fmt.Errorf("format %s must be rendered by the caller", format.Kind)
+}
+
+// PrintYAML prints v as YAML using the same keys as its JSON form.
+func PrintYAML(w io.Writer, v interface{}) error {
+	generic, err := GenericValue(v)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// PrintTemplate executes tpl against the JSON form of v. A list runs the
+// template once per element; every run is followed by a newline.
+func PrintTemplate(w io.Writer, tpl *template.Template, v interface{}) error {
+	generic, err := GenericValue(v)
+	if err != nil {
+		return err
+	}
+	values := []interface{}{generic}
+	if list, ok := generic.([]interface{}); ok {
+		values = list
+	}
+	for _, value := range values {
+		if err := tpl.Execute(w, value); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrintCSV prints an RFC 4180 table; cells holding commas, quotes or
+// newlines are quoted.
+func PrintCSV(w io.Writer, table Table) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(table.Headers); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(table.Rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// PrintTSV prints a tab-separated table. TSV has no quoting, so tabs and
+// line breaks inside cells become spaces.
+func PrintTSV(w io.Writer, table Table) error {
+	clean := strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+	lines := make([]string, 0, len(table.Rows)+1)
+	for _, row := range append([][]string{table.Headers}, table.Rows...) {
+		cells := make([]string, len(row))
+		for index, cell := range row {
+			cells[index] = clean.Replace(cell)
+		}
+		lines = append(lines, strings.Join(cells, "\t"))
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// PrintMarkdown prints a GitHub-flavored Markdown table. Pipes are escaped
+// and line breaks become <br>.
+func PrintMarkdown(w io.Writer, table Table) error {
+	clean := strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+	var buf bytes.Buffer
+	writeRow := func(cells []string) {
+		escaped := make([]string, len(cells))
+		for index, cell := range cells {
+			escaped[index] = clean.Replace(cell)
+		}
+		buf.WriteString("| " + strings.Join(escaped, " | ") + " |\n")
+	}
+	writeRow(table.Headers)
+	separators := make([]string, len(table.Headers))
+	for index := range separators {
+		separators[index] = "---"
+	}
+	writeRow(separators)
+	for _, row := range table.Rows {
+		writeRow(row)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ColumnTable returns the items as rows of the given columns. Unlike the
+// text table, cells keep their line breaks.
+func ColumnTable(items []WorkItem, columns []Column) Table {
+	table := Table{Headers: make([]string, 0, len(columns))}
+	for _, column := range columns {
+		table.Headers = append(table.Headers, column.Header)
+	}
+	for _, item := range items {
+		row := make([]string, 0, len(columns))
+		for _, column := range columns {
+			row = append(row, formatCell(ColumnValue(item, column.Field)))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// RecordTable returns a one-row table for a detail view.
+func RecordTable(headers []string, values ...interface{}) Table {
+	row := make([]string, 0, len(values))
+	for _, value := range values {
+		row = append(row, formatCell(value))
+	}
+	return Table{Headers: headers, Rows: [][]string{row}}
+}
+
+// TreeItems lists the items of a tree depth-first, parents before children.
+func TreeItems(roots []WorkItemNode) []WorkItem {
+	items := []WorkItem{}
+	for _, root := range roots {
+		items = append(items, root.WorkItem)
+		items = append(items, TreeItems(root.Children)...)
+	}
+	return items
+}
+
+// GenericValue converts v to the maps, slices and scalars of its JSON form.
+// Whole numbers become int64 so they are not printed in exponent notation.
+func GenericValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return convertNumbers(generic), nil
+}
+
+func convertNumbers(value interface{}) interface{} {
+	switch val := value.(type) {
+	case map[string]interface{}:
+		for key, item := range val {
+			val[key] = convertNumbers(item)
+		}
+		return val
+	case []interface{}:
+		for index, item := range val {
+			val[index] = convertNumbers(item)
+		}
+		return val
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+		return val.String()
+	}
+	return value
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"join": func(sep string, values []interface{}) string {
+			parts := make([]string, 0, len(values))
+			for _, value := range values {
+				parts = append(parts, fmt.Sprint(value))
+			}
+			return strings.Join(parts, sep)
+		},
+	}
+}
+
+// ValueTable tabulates an arbitrary JSON value such as a --query result: a
+// list of objects gives one row per object with the sorted union of their
+// keys as columns, an object gives a single row, and anything else a single
+// "value" column.
+func ValueTable(v interface{}) Table {
+	generic, err := GenericValue(v)
+	if err != nil {
+		return Table{Headers: []string{"value"}, Rows: [][]string{{fmt.Sprint(v)}}}
+	}
+	list, isList := generic.([]interface{})
+	if !isList {
+		list = []interface{}{generic}
+	}
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, item := range list {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			keys = nil
+			break
+		}
+		for key := range object {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	if keys == nil {
+		table := Table{Headers: []string{"value"}}
+		for _, item := range list {
+			table.Rows = append(table.Rows, []string{valueCell(item)})
+		}
+		return table
+	}
+	sort.Strings(keys)
+	table := Table{Headers: keys}
+	for _, item := range list {
+		object := item.(map[string]interface{})
+		row := make([]string, 0, len(keys))
+		for _, key := range keys {
+			row = append(row, valueCell(object[key]))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// valueCell formats scalars like formatCell and nested values as JSON.
+func valueCell(value interface{}) string {
+	switch value.(type) {
+	case []interface{}, map[string]interface{}:
+		if identity := identityString(value); identity != nil {
+			return *identity
+		}
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+	return formatCell(value)
+}
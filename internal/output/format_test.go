@@ -0,0 +1,91 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintCSVQuotesCommasAndNewlines(t *testing.T) {
+	title := "Fix export, import"
+	items := []WorkItem{{
+		ID:    12,
+		Title: &title,
+		Fields: map[string]interface{}{
+			"System.Title":       title,
+			"System.Description": "first line\nsecond \"quoted\" line",
+		},
+	}}
+	columns := []Column{
+		{Key: "id", Header: "ID", Field: "System.Id"},
+		{Key: "title", Header: "TITLE", Field: "System.Title"},
+		{Key: "description", Header: "DESCRIPTION", Field: "System.Description"},
+	}
+	var buf bytes.Buffer
+	if err := PrintCSV(&buf, ColumnTable(items, columns)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "ID,TITLE,DESCRIPTION\n12,\"Fix export, import\",\"first line\nsecond \"\"quoted\"\" line\"\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected CSV:\n%q\nwant\n%q", buf.String(), want)
+	}
+}
+
+func TestPrintTSVAndMarkdownFlattenCells(t *testing.T) {
+	table := Table{Headers: []string{"ID", "TITLE"}, Rows: [][]string{{"1", "a|b\nc\td"}}}
+
+	var tsv bytes.Buffer
+	if err := PrintTSV(&tsv, table); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tsv.String() != "ID\tTITLE\n1\ta|b c d\n" {
+		t.Fatalf("unexpected TSV: %q", tsv.String())
+	}
+
+	var md bytes.Buffer
+	if err := PrintMarkdown(&md, table); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if md.String() != "| ID | TITLE |\n| --- | --- |\n| 1 | a\\|b<br>c\td |\n" {
+		t.Fatalf("unexpected Markdown: %q", md.String())
+	}
+}
+
+func TestPrintFormattedYAMLAndTemplate(t *testing.T) {
+	payload := []map[string]interface{}{
+		{"id": 1234567, "title": "First", "remaining": 2.5},
+		{"id": 8, "title": "Second"},
+	}
+
+	var yamlOut bytes.Buffer
+	if err := PrintFormatted(&yamlOut, Format{Kind: FormatYAML}, payload, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(yamlOut.String(), "- id: 1234567\n  remaining: 2.5\n  title: First\n") {
+		t.Fatalf("unexpected YAML:\n%s", yamlOut.String())
+	}
+
+	format, err := ParseFormat("template={{.id}}: {{.title}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var tplOut bytes.Buffer
+	if err := PrintFormatted(&tplOut, format, payload, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tplOut.String() != "1234567: First\n8: Second\n" {
+		t.Fatalf("unexpected template output: %q", tplOut.String())
+	}
+}
+
+func TestParseFormatRejectsUnknown(t *testing.T) {
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+	if _, err := ParseFormat("template={{.id"); err == nil {
+		t.Fatal("expected an error for an invalid template")
+	}
+	if format, err := ParseFormat("Markdown"); err != nil || format.Kind != FormatMarkdown {
+		t.Fatalf("unexpected format: %v, %v", format, err)
+	}
+}
@@ -22,12 +22,7 @@ type ErrorEnvelope struct {
 
 func WriteError(w io.Writer, err error, jsonMode bool) {
 	if jsonMode {
-		env := ErrorEnvelope{Error: ErrorDetail{Code: "internal_error", Message: err.Error()}}
-		if appErr, ok := err.(errs.AppError); ok {
-			env.Error.Code = appErr.Code
-			env.Error.Message = appErr.Message
-			env.Error.Details = appErr.Details
-		}
+		env := ErrorEnvelope{Error: NewErrorDetail(err)}
 		data, _ := json.Marshal(env)
 		fmt.Fprintln(w, string(data))
 		return
@@ -35,6 +30,16 @@ func WriteError(w io.Writer, err error, jsonMode bool) {
 	fmt.Fprintln(w, err.Error())
 }
 
+func NewErrorDetail(err error) ErrorDetail {
+	detail := ErrorDetail{Code: "internal_error", Message: err.Error()}
+	if appErr, ok := err.(errs.AppError); ok {
+		detail.Code = appErr.Code
+		detail.Message = appErr.Message
+		detail.Details = appErr.Details
+	}
+	return detail
+}
+
 func PrintJSON(w io.Writer, v interface{}) error {
 	data, err := json.Marshal(v)
 	if err != nil {
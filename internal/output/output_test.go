@@ -1,7 +1,9 @@
 package output
 
 import (
+	"bytes"
 	"reflect"
+	"strings"
 	"testing"
 
 	"tfs-cli/internal/api"
@@ -50,3 +52,41 @@ func TestNormalizeWorkItemAssignedString(t *testing.T) {
 	}
 }
 
+
+func TestColumnRowsFormatValues(t *testing.T) {
+	item := WorkItem{
+		ID: 7,
+		Fields: map[string]interface{}{
+			"System.ChangedDate":                      "2024-03-05T09:41:12.34Z",
+			"Microsoft.VSTS.Scheduling.RemainingWork": 4.5,
+			"Microsoft.VSTS.Common.Priority":          2.0,
+			"System.ChangedBy": map[string]interface{}{
+				"displayName": "Pat Owner",
+				"uniqueName":  "pat@example.com",
+			},
+		},
+	}
+	columns := []Column{
+		{Key: "id", Header: "ID", Field: "System.Id"},
+		{Key: "changed", Header: "CHANGED", Field: "System.ChangedDate"},
+		{Key: "remaining", Header: "REMAINING", Field: "microsoft.vsts.scheduling.remainingwork"},
+		{Key: "priority", Header: "PRIORITY", Field: "Microsoft.VSTS.Common.Priority"},
+		{Key: "changedby", Header: "CHANGEDBY", Field: "System.ChangedBy"},
+		{Key: "missing", Header: "MISSING", Field: "Custom.Missing"},
+	}
+
+	var buf bytes.Buffer
+	PrintColumns(&buf, []WorkItem{item}, columns)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected table: %q", buf.String())
+	}
+	if got := strings.Fields(lines[1]); !reflect.DeepEqual(got, []string{"7", "2024-03-05", "09:41", "4.5", "2", "Pat", "Owner<pat@example.com>"}) {
+		t.Fatalf("unexpected row: %q", lines[1])
+	}
+
+	rows := ColumnRows([]WorkItem{item}, columns)
+	if rows[0]["changedby"] != "Pat Owner<pat@example.com>" || rows[0]["remaining"] != 4.5 || rows[0]["missing"] != nil {
+		t.Fatalf("unexpected row: %v", rows[0])
+	}
+}
@@ -0,0 +1,68 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// WorkItemNode is a work item together with the items a tree or one-hop
+// query linked below it. Cycle marks a link back to an ancestor, whose
+// children are not repeated; Truncated counts children hidden by a depth limit.
+type WorkItemNode struct {
+	WorkItem
+	Rel       string         `json:"rel,omitempty"`
+	Cycle     bool           `json:"cycle,omitempty"`
+	Truncated int            `json:"truncated,omitempty"`
+	Children  []WorkItemNode `json:"children"`
+}
+
+// PrintTree prints the same columns as PrintTable, indenting titles by depth.
+func PrintTree(w io.Writer, roots []WorkItemNode) {
+	PrintTreeColumns(w, roots, DefaultColumns())
+}
+
+// PrintTreeColumns prints the tree as a table of the given columns.
+func PrintTreeColumns(w io.Writer, roots []WorkItemNode, columns []Column) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	printColumnHeader(tw, columns)
+	for _, root := range roots {
+		printTreeNode(tw, root, columns, 0)
+	}
+	_ = tw.Flush()
+}
+
+// TreeRows converts the tree to nested column rows with "children" (and
+// "rel", "cycle", "truncated" where set) for JSON output.
+func TreeRows(nodes []WorkItemNode, columns []Column) []map[string]interface{} {
+	rows := make([]map[string]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		row := ColumnRow(node.WorkItem, columns)
+		if node.Rel != "" {
+			row["rel"] = node.Rel
+		}
+		if node.Cycle {
+			row["cycle"] = true
+		}
+		if node.Truncated > 0 {
+			row["truncated"] = node.Truncated
+		}
+		row["children"] = TreeRows(node.Children, columns)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func printTreeNode(w io.Writer, node WorkItemNode, columns []Column, depth int) {
+	suffix := ""
+	if node.Cycle {
+		suffix = " (cycle)"
+	} else if node.Truncated > 0 {
+		suffix = fmt.Sprintf(" (+%d more)", node.Truncated)
+	}
+	printColumnRow(w, node.WorkItem, columns, strings.Repeat("  ", depth), suffix)
+	for _, child := range node.Children {
+		printTreeNode(w, child, columns, depth+1)
+	}
+}